@@ -15,9 +15,185 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/driver/api/v1/import": {
+        "/driver/api/v1/claim": {
             "post": {
-                "description": "Upload driver locations from a predefined CSV file",
+                "description": "Atomically finds the nearest available driver and reserves them. The reservation expires unless it is confirmed. The returned reservation_id is needed to confirm or release the reservation.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reservation"
+                ],
+                "summary": "Claim Nearest Driver",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Latitude",
+                        "name": "latitude",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude",
+                        "name": "longitude",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Search radius in meters",
+                        "name": "radius",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.ClaimResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No drivers found",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to claim driver",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/demand/unmet": {
+            "get": {
+                "description": "Aggregates sampled searches that found no driver by geohash cell and hour of the day, largest first. Counts are estimates scaled by the sample rate.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Demand"
+                ],
+                "summary": "Unmet Demand Hotspots",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the period in RFC 3339, defaults to 24 hours before to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the period in RFC 3339, defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Geohash precision of the cells, defaults to 6",
+                        "name": "precision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone of the hours, defaults to UTC",
+                        "name": "timezone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of hotspots between 1 and 1000, defaults to 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/bitaksi-go-driver_internal_models.DemandHotspot"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to aggregate demand",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/drivers/{id}/confirm": {
+            "post": {
+                "description": "Confirms an unexpired reservation so it no longer auto-releases",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reservation"
+                ],
+                "summary": "Confirm Reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Driver ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reservation ID returned by the claim",
+                        "name": "reservation_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reservation confirmed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid driver ID or missing reservation ID",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Reservation not found or expired",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/drivers/{id}/location": {
+            "put": {
+                "description": "Moves a driver to the given position",
                 "consumes": [
                     "application/json"
                 ],
@@ -27,49 +203,144 @@ const docTemplate = `{
                 "tags": [
                     "Driver"
                 ],
-                "summary": "Import Driver Locations",
+                "summary": "Update Driver Location",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Driver ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New position",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.UpdateLocationRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "Locations imported successfully",
+                        "description": "Location updated successfully",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Driver not found",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
                     "500": {
-                        "description": "Failed to import locations",
+                        "description": "Failed to update location",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/drivers/{id}/release": {
+            "post": {
+                "description": "Releases a reserved driver so they can be found and claimed again",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reservation"
+                ],
+                "summary": "Release Driver",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Driver ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reservation ID returned by the claim or the accepted ride request",
+                        "name": "reservation_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Driver released",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "400": {
+                        "description": "Invalid driver ID or missing reservation ID",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Reservation not found or expired",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
                     }
                 }
             }
         },
-        "/driver/api/v1/search": {
+        "/driver/api/v1/heatmap": {
             "get": {
-                "description": "Finds drivers near a given location within the specified radius",
+                "description": "Counts drivers per geohash cell within a bounding box. A min_lon greater than max_lon crosses the antimeridian. The box may span at most 10000 cells of the precision.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Driver"
+                    "Heatmap"
                 ],
-                "summary": "Search Nearby Drivers",
+                "summary": "Driver Heatmap",
                 "parameters": [
                     {
                         "type": "number",
-                        "description": "Latitude",
-                        "name": "latitude",
+                        "description": "Southern edge",
+                        "name": "min_lat",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "number",
-                        "description": "Longitude",
-                        "name": "longitude",
+                        "description": "Western edge",
+                        "name": "min_lon",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Northern edge",
+                        "name": "max_lat",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Eastern edge",
+                        "name": "max_lon",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "integer",
-                        "description": "Search radius in meters",
-                        "name": "radius",
+                        "description": "Geohash precision between 1 and 9",
+                        "name": "precision",
                         "in": "query",
                         "required": true
                     }
@@ -78,30 +349,56 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/bitaksi-go-driver_internal_models.DriverWithDistance"
-                            }
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_models.Heatmap"
                         }
                     },
                     "400": {
                         "description": "Invalid input",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
                         }
                     },
                     "500": {
-                        "description": "Failed to search drivers",
+                        "description": "Failed to build heatmap",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/import": {
+            "post": {
+                "description": "Upload driver locations from a predefined CSV file",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Driver"
+                ],
+                "summary": "Import Driver Locations",
+                "responses": {
+                    "200": {
+                        "description": "Locations imported successfully",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "500": {
+                        "description": "Failed to import locations",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
                     }
                 }
             }
         },
-        "/health": {
-            "get": {
-                "description": "Returns the health status of the Driver Service",
+        "/driver/api/v1/match": {
+            "post": {
+                "description": "Assigns available drivers to a batch of pickups minimizing the total pickup distance",
                 "consumes": [
                     "application/json"
                 ],
@@ -109,33 +406,696 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Health"
+                    "Matching"
+                ],
+                "summary": "Batch Match Riders",
+                "parameters": [
+                    {
+                        "description": "Pickups and maximum pickup distance in meters",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.MatchRequest"
+                        }
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_api_handler.HealthResponse"
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_models.MatchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to match riders",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "bitaksi-go-driver_internal_models.DriverWithDistance": {
-            "type": "object",
+        },
+        "/driver/api/v1/rides": {
+            "post": {
+                "description": "Creates a ride request at the pickup point and offers it to nearby available drivers one at a time",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dispatch"
+                ],
+                "summary": "Create Ride Request",
+                "parameters": [
+                    {
+                        "description": "Pickup point and search radius in meters",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.CreateRideRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_models.RideRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No drivers found",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create ride request",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/rides/{id}": {
+            "get": {
+                "description": "Returns the current state and offer history of a ride request",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dispatch"
+                ],
+                "summary": "Get Ride Request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ride request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_models.RideRequest"
+                        }
+                    },
+                    "404": {
+                        "description": "Ride request not found",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get ride request",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/rides/{id}/accept": {
+            "post": {
+                "description": "Accepts the pending offer for the driver and atomically reserves them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dispatch"
+                ],
+                "summary": "Accept Ride Offer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ride request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Driver responding to the offer",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.OfferResponseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_models.RideRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ride request not found",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "No pending offer or driver unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/rides/{id}/decline": {
+            "post": {
+                "description": "Declines the pending offer for the driver and offers the ride to the next driver",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dispatch"
+                ],
+                "summary": "Decline Ride Offer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ride request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Driver responding to the offer",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.OfferResponseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_models.RideRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ride request not found",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "No pending offer",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/search": {
+            "get": {
+                "description": "Finds drivers near a given location within the specified radius",
+                "tags": [
+                    "Driver"
+                ],
+                "summary": "Search Nearby Drivers",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Latitude",
+                        "name": "latitude",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude",
+                        "name": "longitude",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Search radius in meters",
+                        "name": "radius",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/bitaksi-go-driver_internal_models.DriverWithDistance"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to search drivers",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/surge": {
+            "get": {
+                "description": "Returns available drivers, recent searches and the resulting surge multiplier of the cell containing the location",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Surge"
+                ],
+                "summary": "Surge At Location",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Latitude",
+                        "name": "latitude",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude",
+                        "name": "longitude",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_models.SurgeCell"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to compute surge",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/driver/api/v1/surge/cells": {
+            "get": {
+                "description": "Returns the surge multiplier of every cell within the bounding box that has available drivers or recent searches",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Surge"
+                ],
+                "summary": "Surge Map",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Southern edge",
+                        "name": "min_lat",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Western edge",
+                        "name": "min_lon",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Northern edge",
+                        "name": "max_lat",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Eastern edge",
+                        "name": "max_lon",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_models.SurgeMap"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to compute surge",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Returns the health status of the Driver Service",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/livez": {
+            "get": {
+                "description": "Returns 200 while the process serves HTTP, whatever the state of its dependencies",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks the storage backend and its geospatial index. Returns 503 while a check fails or the server shuts down.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/bitaksi-go-driver_internal_health.Report"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "bitaksi-go-driver_internal_apierror.Code": {
+            "type": "string",
+            "enum": [
+                "invalid_body",
+                "invalid_parameter",
+                "unauthorized",
+                "insufficient_scope",
+                "route_not_found",
+                "method_not_allowed",
+                "no_drivers_found",
+                "driver_not_found",
+                "driver_unavailable",
+                "reservation_not_found",
+                "ride_not_found",
+                "no_pending_offer",
+                "internal_error"
+            ],
+            "x-enum-varnames": [
+                "CodeInvalidBody",
+                "CodeInvalidParameter",
+                "CodeUnauthorized",
+                "CodeInsufficientScope",
+                "CodeRouteNotFound",
+                "CodeMethodNotAllowed",
+                "CodeNoDriversFound",
+                "CodeDriverNotFound",
+                "CodeDriverUnavailable",
+                "CodeReservationNotFound",
+                "CodeRideNotFound",
+                "CodeNoPendingOffer",
+                "CodeInternal"
+            ]
+        },
+        "bitaksi-go-driver_internal_apierror.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_apierror.Code"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_geo.Bounds": {
+            "type": "object",
+            "properties": {
+                "max_lat": {
+                    "type": "number"
+                },
+                "max_lon": {
+                    "type": "number"
+                },
+                "min_lat": {
+                    "type": "number"
+                },
+                "min_lon": {
+                    "type": "number"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_health.CheckResult": {
+            "type": "object",
+            "properties": {
+                "latency_ms": {
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/bitaksi-go-driver_internal_health.CheckResult"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.Coordinates": {
+            "type": "object",
+            "properties": {
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.DemandHotspot": {
+            "type": "object",
+            "properties": {
+                "bounds": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_geo.Bounds"
+                },
+                "center": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_models.Coordinates"
+                },
+                "geohash": {
+                    "type": "string"
+                },
+                "hour": {
+                    "description": "Hour of the day in the requested time zone",
+                    "type": "integer"
+                },
+                "searches": {
+                    "description": "Estimated number of searches",
+                    "type": "number"
+                },
+                "unmet": {
+                    "description": "Estimated number of searches that found no driver",
+                    "type": "number"
+                },
+                "unmet_ratio": {
+                    "type": "number"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.DriverWithDistance": {
+            "type": "object",
             "properties": {
                 "distance": {
-                    "description": "Distance from the \"near\" point",
+                    "description": "Distance from the \"near\" point, not stored",
+                    "type": "number"
+                },
+                "eta_seconds": {
+                    "description": "Estimated travel time to the \"near\" point",
                     "type": "number"
                 },
                 "id": {
                     "type": "string"
                 },
+                "last_seen": {
+                    "description": "Time of the last location update",
+                    "type": "string"
+                },
                 "location": {
                     "$ref": "#/definitions/bitaksi-go-driver_internal_models.Location"
+                },
+                "reserved_until": {
+                    "description": "Unconfirmed reservations expire at this time",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.Heatmap": {
+            "type": "object",
+            "properties": {
+                "bounds": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_geo.Bounds"
+                },
+                "cells": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bitaksi-go-driver_internal_models.HeatmapCell"
+                    }
+                },
+                "precision": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.HeatmapCell": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "description": "Drivers that can currently be offered a ride",
+                    "type": "integer"
+                },
+                "bounds": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_geo.Bounds"
+                },
+                "center": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_models.Coordinates"
+                },
+                "count": {
+                    "description": "All drivers in the cell",
+                    "type": "integer"
+                },
+                "geohash": {
+                    "type": "string"
                 }
             }
         },
@@ -153,6 +1113,202 @@ const docTemplate = `{
                 }
             }
         },
+        "bitaksi-go-driver_internal_models.MatchPair": {
+            "type": "object",
+            "properties": {
+                "driver": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_models.DriverWithDistance"
+                },
+                "pickup_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.MatchResult": {
+            "type": "object",
+            "properties": {
+                "pairs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bitaksi-go-driver_internal_models.MatchPair"
+                    }
+                },
+                "total_distance": {
+                    "description": "Sum of pickup distances of all pairs in meters",
+                    "type": "number"
+                },
+                "unmatched": {
+                    "description": "IDs of pickups without a driver within the maximum distance",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.Offer": {
+            "type": "object",
+            "properties": {
+                "distance": {
+                    "type": "number"
+                },
+                "driver_id": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "offered_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.Pickup": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.RideRequest": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "driver_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "offers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bitaksi-go-driver_internal_models.Offer"
+                    }
+                },
+                "pickup": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_models.Coordinates"
+                },
+                "radius": {
+                    "type": "integer"
+                },
+                "reservation_id": {
+                    "description": "Releases the accepted driver once the ride is over",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.SurgeCell": {
+            "type": "object",
+            "properties": {
+                "bounds": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_geo.Bounds"
+                },
+                "center": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_models.Coordinates"
+                },
+                "demand": {
+                    "description": "Searches from the cell within the sliding window",
+                    "type": "integer"
+                },
+                "geohash": {
+                    "type": "string"
+                },
+                "multiplier": {
+                    "type": "number"
+                },
+                "ratio": {
+                    "description": "Demand per available driver",
+                    "type": "number"
+                },
+                "supply": {
+                    "description": "Available drivers in the cell",
+                    "type": "integer"
+                }
+            }
+        },
+        "bitaksi-go-driver_internal_models.SurgeMap": {
+            "type": "object",
+            "properties": {
+                "cells": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bitaksi-go-driver_internal_models.SurgeCell"
+                    }
+                },
+                "precision": {
+                    "type": "integer"
+                },
+                "window": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_api_handler.ClaimResponse": {
+            "type": "object",
+            "properties": {
+                "distance": {
+                    "description": "Distance from the \"near\" point, not stored",
+                    "type": "number"
+                },
+                "eta_seconds": {
+                    "description": "Estimated travel time to the \"near\" point",
+                    "type": "number"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_seen": {
+                    "description": "Time of the last location update",
+                    "type": "string"
+                },
+                "location": {
+                    "$ref": "#/definitions/bitaksi-go-driver_internal_models.Location"
+                },
+                "reservation_id": {
+                    "type": "string"
+                },
+                "reserved_until": {
+                    "description": "Unconfirmed reservations expire at this time",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_api_handler.CreateRideRequest": {
+            "type": "object",
+            "properties": {
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                },
+                "radius": {
+                    "type": "integer"
+                }
+            }
+        },
         "internal_api_handler.HealthResponse": {
             "type": "object",
             "properties": {
@@ -160,6 +1316,40 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "internal_api_handler.MatchRequest": {
+            "type": "object",
+            "properties": {
+                "max_distance": {
+                    "description": "Maximum pickup distance in meters",
+                    "type": "integer"
+                },
+                "pickups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bitaksi-go-driver_internal_models.Pickup"
+                    }
+                }
+            }
+        },
+        "internal_api_handler.OfferResponseRequest": {
+            "type": "object",
+            "properties": {
+                "driver_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_api_handler.UpdateLocationRequest": {
+            "type": "object",
+            "properties": {
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bitaksi-go-driver/internal/apierror"
+	"bitaksi-go-driver/internal/models"
+)
+
+const (
+	defaultDemandPrecision = 6
+	defaultDemandLimit     = 100
+	maxDemandLimit         = 1000
+	defaultDemandPeriod    = 24 * time.Hour
+)
+
+type DemandHandler interface {
+	UnmetDemand(w http.ResponseWriter, r *http.Request)
+}
+
+type DemandService interface {
+	UnmetDemand(ctx context.Context, query models.DemandQuery) ([]models.DemandHotspot, error)
+}
+
+type demandHandler struct {
+	service DemandService
+	now     func() time.Time
+}
+
+func NewDemandHandler(service DemandService) DemandHandler {
+	return &demandHandler{service: service, now: time.Now}
+}
+
+// UnmetDemand returns the areas and hours with the most searches that found no driver
+// @Summary Unmet Demand Hotspots
+// @Description Aggregates sampled searches that found no driver by geohash cell and hour of the day, largest first. Counts are estimates scaled by the sample rate.
+// @Tags Demand
+// @Produce json
+// @Param from query string false "Start of the period in RFC 3339, defaults to 24 hours before to"
+// @Param to query string false "End of the period in RFC 3339, defaults to now"
+// @Param precision query int false "Geohash precision of the cells, defaults to 6"
+// @Param timezone query string false "IANA time zone of the hours, defaults to UTC"
+// @Param limit query int false "Maximum number of hotspots between 1 and 1000, defaults to 100"
+// @Success 200 {array} models.DemandHotspot
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 500 {object} apierror.Problem "Failed to aggregate demand"
+// @Router /driver/api/v1/demand/unmet [get]
+func (h *demandHandler) UnmetDemand(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := models.DemandQuery{
+		To:        h.now(),
+		Precision: defaultDemandPrecision,
+		Location:  time.UTC,
+		Limit:     defaultDemandLimit,
+	}
+	values := r.URL.Query()
+
+	if value := values.Get("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidParameter("Invalid to: must be an RFC 3339 timestamp"))
+			return
+		}
+		query.To = to
+	}
+	query.From = query.To.Add(-defaultDemandPeriod)
+	if value := values.Get("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidParameter("Invalid from: must be an RFC 3339 timestamp"))
+			return
+		}
+		query.From = from
+	}
+
+	if value := values.Get("precision"); value != "" {
+		precision, err := strconv.Atoi(value)
+		if err != nil || precision < 1 {
+			apierror.Write(w, r, apierror.InvalidParameter("Invalid precision: must be a positive integer"))
+			return
+		}
+		query.Precision = precision
+	}
+
+	if value := values.Get("timezone"); value != "" {
+		location, err := time.LoadLocation(value)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidParameter("Invalid timezone"))
+			return
+		}
+		query.Location = location
+	}
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxDemandLimit {
+			apierror.Write(w, r, apierror.InvalidParameter("Invalid limit: must be between 1 and %d", maxDemandLimit))
+			return
+		}
+		query.Limit = limit
+	}
+
+	hotspots, err := h.service.UnmetDemand(r.Context(), query)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to aggregate demand"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(hotspots)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/service"
+)
+
+type MockDemandService struct {
+	UnmetDemandFn func(ctx context.Context, query models.DemandQuery) ([]models.DemandHotspot, error)
+}
+
+func (m *MockDemandService) UnmetDemand(ctx context.Context, query models.DemandQuery) ([]models.DemandHotspot, error) {
+	return m.UnmetDemandFn(ctx, query)
+}
+
+func TestUnmetDemand(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		serviceErr     error
+		expectedQuery  string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Defaults",
+			query:          "",
+			expectedQuery:  "2024-01-01T12:00:00Z 2024-01-02T12:00:00Z 6 UTC 100",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"geohash":"sxk973","hour":8,"searches":40,"unmet":30,"unmet_ratio":0.75,"center":{"latitude":0,"longitude":0},"bounds":{"min_lat":0,"min_lon":0,"max_lat":0,"max_lon":0}}]`,
+		},
+		{
+			name:           "Explicit Query",
+			query:          "from=2024-01-01T00:00:00Z&to=2024-01-01T06:00:00Z&precision=5&timezone=Europe/Istanbul&limit=10",
+			expectedQuery:  "2024-01-01T00:00:00Z 2024-01-01T06:00:00Z 5 Europe/Istanbul 10",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"geohash":"sxk973","hour":8,"searches":40,"unmet":30,"unmet_ratio":0.75,"center":{"latitude":0,"longitude":0},"bounds":{"min_lat":0,"min_lon":0,"max_lat":0,"max_lon":0}}]`,
+		},
+		{
+			name:           "Invalid From",
+			query:          "from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid from: must be an RFC 3339 timestamp","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Invalid Timezone",
+			query:          "timezone=Mars/Olympus",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid timezone","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Invalid Limit",
+			query:          "limit=0",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid limit: must be between 1 and 1000","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Rejected Query",
+			query:          "precision=8",
+			serviceErr:     fmt.Errorf("%w: precision must be at most 7", service.ErrInvalidDemandQuery),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid demand query: precision must be at most 7","code":"invalid_parameter"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDemandService{
+				UnmetDemandFn: func(ctx context.Context, query models.DemandQuery) ([]models.DemandHotspot, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					got := fmt.Sprintf("%s %s %d %s %d", query.From.Format(time.RFC3339), query.To.Format(time.RFC3339), query.Precision, query.Location, query.Limit)
+					if got != tt.expectedQuery {
+						t.Errorf("expected query %q, got %q", tt.expectedQuery, got)
+					}
+					return []models.DemandHotspot{{Geohash: "sxk973", Hour: 8, Searches: 40, Unmet: 30, UnmetRatio: 0.75}}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/demand/unmet?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			handler := &demandHandler{service: mockService, now: func() time.Time { return now }}
+			handler.UnmetDemand(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody+"\n" {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
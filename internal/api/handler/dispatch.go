@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/apierror"
+	"bitaksi-go-driver/internal/models"
+)
+
+type DispatchHandler interface {
+	CreateRide(w http.ResponseWriter, r *http.Request)
+	GetRide(w http.ResponseWriter, r *http.Request)
+	AcceptOffer(w http.ResponseWriter, r *http.Request)
+	DeclineOffer(w http.ResponseWriter, r *http.Request)
+}
+
+type DispatchService interface {
+	CreateRide(ctx context.Context, latitude, longitude float64, radius int) (*models.RideRequest, error)
+	GetRide(ctx context.Context, id string) (*models.RideRequest, error)
+	AcceptOffer(ctx context.Context, id string, driverID primitive.ObjectID) (*models.RideRequest, error)
+	DeclineOffer(ctx context.Context, id string, driverID primitive.ObjectID) (*models.RideRequest, error)
+}
+
+// CreateRideRequest is the body of a ride request creation
+type CreateRideRequest struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Radius    int     `json:"radius"`
+}
+
+// OfferResponseRequest is the body of an accept or decline call
+type OfferResponseRequest struct {
+	DriverID string `json:"driver_id"`
+}
+
+type dispatchHandler struct {
+	service DispatchService
+}
+
+func NewDispatchHandler(service DispatchService) DispatchHandler {
+	return &dispatchHandler{service: service}
+}
+
+// CreateRide creates a ride request and offers it to the nearest available driver
+// @Summary Create Ride Request
+// @Description Creates a ride request at the pickup point and offers it to nearby available drivers one at a time
+// @Tags Dispatch
+// @Accept json
+// @Produce json
+// @Param request body CreateRideRequest true "Pickup point and search radius in meters"
+// @Success 201 {object} models.RideRequest
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 404 {object} apierror.Problem "No drivers found"
+// @Failure 500 {object} apierror.Problem "Failed to create ride request"
+// @Router /driver/api/v1/rides [post]
+func (h *dispatchHandler) CreateRide(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var body CreateRideRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Write(w, r, apierror.InvalidBody())
+		return
+	}
+
+	if body.Latitude < -90 || body.Latitude > 90 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid latitude: must be between -90 and 90"))
+		return
+	}
+	if body.Longitude < -180 || body.Longitude > 180 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid longitude: must be between -180 and 180"))
+		return
+	}
+	if body.Radius <= 0 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid radius: must be a positive integer"))
+		return
+	}
+
+	ride, err := h.service.CreateRide(r.Context(), body.Latitude, body.Longitude, body.Radius)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to create ride request"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ride)
+}
+
+// GetRide returns the state of a ride request
+// @Summary Get Ride Request
+// @Description Returns the current state and offer history of a ride request
+// @Tags Dispatch
+// @Produce json
+// @Param id path string true "Ride request ID"
+// @Success 200 {object} models.RideRequest
+// @Failure 404 {object} apierror.Problem "Ride request not found"
+// @Failure 500 {object} apierror.Problem "Failed to get ride request"
+// @Router /driver/api/v1/rides/{id} [get]
+func (h *dispatchHandler) GetRide(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ride, err := h.service.GetRide(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to get ride request"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ride)
+}
+
+// AcceptOffer accepts the pending offer of a ride request and reserves the driver
+// @Summary Accept Ride Offer
+// @Description Accepts the pending offer for the driver and atomically reserves them
+// @Tags Dispatch
+// @Accept json
+// @Produce json
+// @Param id path string true "Ride request ID"
+// @Param request body OfferResponseRequest true "Driver responding to the offer"
+// @Success 200 {object} models.RideRequest
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 404 {object} apierror.Problem "Ride request not found"
+// @Failure 409 {object} apierror.Problem "No pending offer or driver unavailable"
+// @Router /driver/api/v1/rides/{id}/accept [post]
+func (h *dispatchHandler) AcceptOffer(w http.ResponseWriter, r *http.Request) {
+	h.respondToOffer(w, r, h.service.AcceptOffer)
+}
+
+// DeclineOffer declines the pending offer of a ride request
+// @Summary Decline Ride Offer
+// @Description Declines the pending offer for the driver and offers the ride to the next driver
+// @Tags Dispatch
+// @Accept json
+// @Produce json
+// @Param id path string true "Ride request ID"
+// @Param request body OfferResponseRequest true "Driver responding to the offer"
+// @Success 200 {object} models.RideRequest
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 404 {object} apierror.Problem "Ride request not found"
+// @Failure 409 {object} apierror.Problem "No pending offer"
+// @Router /driver/api/v1/rides/{id}/decline [post]
+func (h *dispatchHandler) DeclineOffer(w http.ResponseWriter, r *http.Request) {
+	h.respondToOffer(w, r, h.service.DeclineOffer)
+}
+
+func (h *dispatchHandler) respondToOffer(w http.ResponseWriter, r *http.Request, respond func(ctx context.Context, id string, driverID primitive.ObjectID) (*models.RideRequest, error)) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var body OfferResponseRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Write(w, r, apierror.InvalidBody())
+		return
+	}
+
+	driverID, err := primitive.ObjectIDFromHex(body.DriverID)
+	if err != nil {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid driver_id"))
+		return
+	}
+
+	ride, err := respond(r.Context(), mux.Vars(r)["id"], driverID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to update ride request"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ride)
+}
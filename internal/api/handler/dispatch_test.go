@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+	"bitaksi-go-driver/internal/service"
+)
+
+type MockDispatchService struct {
+	CreateRideFn   func(ctx context.Context, latitude, longitude float64, radius int) (*models.RideRequest, error)
+	GetRideFn      func(ctx context.Context, id string) (*models.RideRequest, error)
+	AcceptOfferFn  func(ctx context.Context, id string, driverID primitive.ObjectID) (*models.RideRequest, error)
+	DeclineOfferFn func(ctx context.Context, id string, driverID primitive.ObjectID) (*models.RideRequest, error)
+}
+
+func (m *MockDispatchService) CreateRide(ctx context.Context, latitude, longitude float64, radius int) (*models.RideRequest, error) {
+	return m.CreateRideFn(ctx, latitude, longitude, radius)
+}
+
+func (m *MockDispatchService) GetRide(ctx context.Context, id string) (*models.RideRequest, error) {
+	return m.GetRideFn(ctx, id)
+}
+
+func (m *MockDispatchService) AcceptOffer(ctx context.Context, id string, driverID primitive.ObjectID) (*models.RideRequest, error) {
+	return m.AcceptOfferFn(ctx, id, driverID)
+}
+
+func (m *MockDispatchService) DeclineOffer(ctx context.Context, id string, driverID primitive.ObjectID) (*models.RideRequest, error) {
+	return m.DeclineOfferFn(ctx, id, driverID)
+}
+
+func TestCreateRide(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Valid Request",
+			body:           `{"latitude":41.0,"longitude":29.0,"radius":5000}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "No Drivers Found",
+			body:           `{"latitude":41.0,"longitude":29.0,"radius":5000}`,
+			mockError:      repository.ErrDriverNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"No drivers found","code":"no_drivers_found"}`,
+		},
+		{
+			name:           "Invalid Body",
+			body:           `not json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid request body","code":"invalid_body"}`,
+		},
+		{
+			name:           "Invalid Radius",
+			body:           `{"latitude":41.0,"longitude":29.0,"radius":0}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid radius: must be a positive integer","code":"invalid_parameter"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDispatchService{
+				CreateRideFn: func(ctx context.Context, latitude, longitude float64, radius int) (*models.RideRequest, error) {
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &models.RideRequest{ID: "ride-1", Status: models.RideStatusOffering}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/rides", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			NewDispatchHandler(mockService).CreateRide(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody+"\n" {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAcceptOffer(t *testing.T) {
+	driverID := primitive.NewObjectID()
+
+	tests := []struct {
+		name           string
+		body           string
+		mockError      error
+		expectedStatus int
+	}{
+		{name: "Accepted", body: `{"driver_id":"` + driverID.Hex() + `"}`, expectedStatus: http.StatusOK},
+		{name: "Invalid Driver ID", body: `{"driver_id":"nope"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Ride Not Found", body: `{"driver_id":"` + driverID.Hex() + `"}`, mockError: service.ErrRideNotFound, expectedStatus: http.StatusNotFound},
+		{name: "No Pending Offer", body: `{"driver_id":"` + driverID.Hex() + `"}`, mockError: service.ErrNoActiveOffer, expectedStatus: http.StatusConflict},
+		{name: "Driver Unavailable", body: `{"driver_id":"` + driverID.Hex() + `"}`, mockError: repository.ErrDriverUnavailable, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDispatchService{
+				AcceptOfferFn: func(ctx context.Context, id string, acceptingID primitive.ObjectID) (*models.RideRequest, error) {
+					if id != "ride-1" || acceptingID != driverID {
+						t.Errorf("unexpected ride %s or driver %s", id, acceptingID.Hex())
+					}
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &models.RideRequest{ID: id, Status: models.RideStatusAccepted, DriverID: &acceptingID}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/rides/ride-1/accept", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "ride-1"})
+			rec := httptest.NewRecorder()
+
+			NewDispatchHandler(mockService).AcceptOffer(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
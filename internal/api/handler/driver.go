@@ -3,23 +3,33 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/apierror"
 	"bitaksi-go-driver/internal/models"
-	"bitaksi-go-driver/internal/repository"
 )
 
 type DriverHandler interface {
 	ImportLocations(w http.ResponseWriter, r *http.Request)
 	FindNearestDriver(w http.ResponseWriter, r *http.Request)
+	UpdateLocation(w http.ResponseWriter, r *http.Request)
 }
 
 type DriverService interface {
 	ImportLocations(ctx context.Context) error
 	FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error)
+	UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error
+}
+
+// UpdateLocationRequest is the body of a driver location update. Both coordinates are required, a
+// missing one must not move the driver to the equator or the prime meridian.
+type UpdateLocationRequest struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
 
 type driverHandler struct {
@@ -37,14 +47,14 @@ func NewDriverHandler(service DriverService) DriverHandler {
 // @Accept json
 // @Produce json
 // @Success 200 {string} string "Locations imported successfully"
-// @Failure 500 {string} string "Failed to import locations"
+// @Failure 500 {object} apierror.Problem "Failed to import locations"
 // @Router /driver/api/v1/import [post]
 func (h *driverHandler) ImportLocations(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	err := h.service.ImportLocations(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": "Failed to import locations: %v"}`, err), http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to import locations"))
 		return
 	}
 
@@ -60,44 +70,95 @@ func (h *driverHandler) ImportLocations(w http.ResponseWriter, r *http.Request)
 // @Param longitude query float64 true "Longitude"
 // @Param radius query int true "Search radius in meters"
 // @Success 200 {array} models.DriverWithDistance
-// @Failure 400 {string} string "Invalid input"
-// @Failure 500 {string} string "Failed to search drivers"
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 500 {object} apierror.Problem "Failed to search drivers"
 // @Router /driver/api/v1/search [get]
 func (h *driverHandler) FindNearestDriver(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Parse query parameters
-	latitude, err := strconv.ParseFloat(r.URL.Query().Get("latitude"), 64)
-	if err != nil || latitude < -90 || latitude > 90 {
-		http.Error(w, `{"error": "Invalid latitude: must be between -90 and 90"}`, http.StatusBadRequest)
+	latitude, longitude, radius, ok := parseLocationQuery(w, r)
+	if !ok {
 		return
 	}
 
-	longitude, err := strconv.ParseFloat(r.URL.Query().Get("longitude"), 64)
-	if err != nil || longitude < -180 || longitude > 180 {
-		http.Error(w, `{"error": "Invalid longitude: must be between -180 and 180"}`, http.StatusBadRequest)
+	// Call the service
+	results, err := h.service.FindNearestDriver(r.Context(), latitude, longitude, radius)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to search drivers"))
 		return
 	}
 
-	radius, err := strconv.Atoi(r.URL.Query().Get("radius"))
-	if err != nil || radius <= 0 {
-		http.Error(w, `{"error": "Invalid radius: must be a positive integer"}`, http.StatusBadRequest)
+	// Send response
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+// UpdateLocation moves a driver to a new position
+// @Summary Update Driver Location
+// @Description Moves a driver to the given position
+// @Tags Driver
+// @Accept json
+// @Produce json
+// @Param id path string true "Driver ID"
+// @Param request body UpdateLocationRequest true "New position"
+// @Success 200 {string} string "Location updated successfully"
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 404 {object} apierror.Problem "Driver not found"
+// @Failure 500 {object} apierror.Problem "Failed to update location"
+// @Router /driver/api/v1/drivers/{id}/location [put]
+func (h *driverHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid driver ID"))
 		return
 	}
 
-	// Call the service
-	results, err := h.service.FindNearestDriver(r.Context(), latitude, longitude, radius)
-	if err != nil {
-		if errors.Is(err, repository.ErrDriverNotFound) {
-			http.Error(w, `{"error": "No drivers found"}`, http.StatusNotFound)
-			return
-		}
+	var body UpdateLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Write(w, r, apierror.InvalidBody())
+		return
+	}
 
-		http.Error(w, fmt.Sprintf(`{"error": "Failed to search drivers: %v"}`, err), http.StatusInternalServerError)
+	if body.Latitude == nil || *body.Latitude < -90 || *body.Latitude > 90 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid latitude: must be between -90 and 90"))
+		return
+	}
+	if body.Longitude == nil || *body.Longitude < -180 || *body.Longitude > 180 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid longitude: must be between -180 and 180"))
+		return
+	}
+
+	if err := h.service.UpdateDriverLocation(r.Context(), id, *body.Latitude, *body.Longitude); err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to update location"))
 		return
 	}
 
-	// Send response
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(results)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Location updated successfully"})
+}
+
+// parseLocationQuery parses and validates the latitude, longitude and radius query parameters,
+// writing a 400 response if any of them is invalid
+func parseLocationQuery(w http.ResponseWriter, r *http.Request) (latitude, longitude float64, radius int, ok bool) {
+	latitude, err := strconv.ParseFloat(r.URL.Query().Get("latitude"), 64)
+	if err != nil || latitude < -90 || latitude > 90 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid latitude: must be between -90 and 90"))
+		return 0, 0, 0, false
+	}
+
+	longitude, err = strconv.ParseFloat(r.URL.Query().Get("longitude"), 64)
+	if err != nil || longitude < -180 || longitude > 180 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid longitude: must be between -180 and 180"))
+		return 0, 0, 0, false
+	}
+
+	radius, err = strconv.Atoi(r.URL.Query().Get("radius"))
+	if err != nil || radius <= 0 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid radius: must be a positive integer"))
+		return 0, 0, 0, false
+	}
+
+	return latitude, longitude, radius, true
 }
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"bitaksi-go-driver/internal/models"
@@ -16,6 +18,7 @@ import (
 type MockDriverService struct {
 	ImportLocationsFn   func(ctx context.Context) error
 	FindNearestDriverFn func(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error)
+	UpdateLocationFn    func(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error
 }
 
 func (m *MockDriverService) ImportLocations(ctx context.Context) error {
@@ -26,6 +29,10 @@ func (m *MockDriverService) FindNearestDriver(ctx context.Context, latitude, lon
 	return m.FindNearestDriverFn(ctx, latitude, longitude, radius)
 }
 
+func (m *MockDriverService) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	return m.UpdateLocationFn(ctx, id, latitude, longitude)
+}
+
 func TestImportLocations(t *testing.T) {
 	mockService := &MockDriverService{
 		ImportLocationsFn: func(ctx context.Context) error {
@@ -82,7 +89,7 @@ func TestFindNearestDriver_TableDriven(t *testing.T) {
 			mockResponse:   nil,
 			mockError:      repository.ErrDriverNotFound,
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   `{"error": "No drivers found"}`,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"No drivers found","code":"no_drivers_found"}`,
 		},
 		{
 			name:           "Invalid Latitude",
@@ -92,7 +99,7 @@ func TestFindNearestDriver_TableDriven(t *testing.T) {
 			mockResponse:   nil,
 			mockError:      nil,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error": "Invalid latitude: must be between -90 and 90"}`,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid latitude: must be between -90 and 90","code":"invalid_parameter"}`,
 		},
 		{
 			name:           "Invalid Longitude",
@@ -102,7 +109,7 @@ func TestFindNearestDriver_TableDriven(t *testing.T) {
 			mockResponse:   nil,
 			mockError:      nil,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error": "Invalid longitude: must be between -180 and 180"}`,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid longitude: must be between -180 and 180","code":"invalid_parameter"}`,
 		},
 		{
 			name:           "Invalid Radius",
@@ -112,7 +119,7 @@ func TestFindNearestDriver_TableDriven(t *testing.T) {
 			mockResponse:   nil,
 			mockError:      nil,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error": "Invalid radius: must be a positive integer"}`,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid radius: must be a positive integer","code":"invalid_parameter"}`,
 		},
 	}
 
@@ -147,3 +154,84 @@ func TestFindNearestDriver_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateLocation(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Valid Request",
+			id:             "6775be842e9ffeeae6b1de93",
+			body:           `{"latitude": 41.01, "longitude": 29.02}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Location updated successfully"}`,
+		},
+		{
+			name:           "Invalid Driver ID",
+			id:             "not-an-id",
+			body:           `{"latitude": 41.01, "longitude": 29.02}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid driver ID","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Invalid Latitude",
+			id:             "6775be842e9ffeeae6b1de93",
+			body:           `{"latitude": 91, "longitude": 29.02}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid latitude: must be between -90 and 90","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Missing Latitude",
+			id:             "6775be842e9ffeeae6b1de93",
+			body:           `{"longitude": 29.02}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid latitude: must be between -90 and 90","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Missing Longitude",
+			id:             "6775be842e9ffeeae6b1de93",
+			body:           `{"latitude": 41.01}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid longitude: must be between -180 and 180","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Unknown Driver",
+			id:             "6775be842e9ffeeae6b1de93",
+			body:           `{"latitude": 41.01, "longitude": 29.02}`,
+			mockError:      repository.ErrUnknownDriver,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"Driver not found","code":"driver_not_found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDriverService{
+				UpdateLocationFn: func(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+					if id.Hex() != tt.id || latitude != 41.01 || longitude != 29.02 {
+						t.Errorf("unexpected update of %s to (%v, %v)", id.Hex(), latitude, longitude)
+					}
+					return tt.mockError
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/drivers/"+tt.id+"/location", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			rec := httptest.NewRecorder()
+
+			NewDriverHandler(mockService).UpdateLocation(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody+"\n" {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
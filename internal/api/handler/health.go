@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+
+	"bitaksi-go-driver/internal/health"
 )
 
 // HealthResponse represents the health check response
@@ -10,7 +13,8 @@ type HealthResponse struct {
 	Status string `json:"status"`
 }
 
-// HealthCheckHandler handles the health check request
+// HealthCheckHandler handles the health check request. It predates the liveness and readiness
+// probes and is kept for the monitors still using it.
 // @Summary Health check
 // @Description Returns the health status of the Driver Service
 // @Tags Health
@@ -23,3 +27,49 @@ func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// ReadinessChecker checks the dependencies of the service
+type ReadinessChecker interface {
+	Ready(ctx context.Context) health.Report
+}
+
+// LivenessHandler reports that the process is up and serving HTTP, without checking dependencies,
+// so that the orchestrator does not restart it while the database is down
+// @Summary Liveness probe
+// @Description Returns 200 while the process serves HTTP, whatever the state of its dependencies
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /livez [get]
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthResponse{Status: health.StatusOK})
+}
+
+type readinessHandler struct {
+	checker ReadinessChecker
+}
+
+// NewReadinessHandler creates the readiness probe of the checker
+func NewReadinessHandler(checker ReadinessChecker) http.Handler {
+	return &readinessHandler{checker: checker}
+}
+
+// ServeHTTP reports the status and latency of every dependency check
+// @Summary Readiness probe
+// @Description Checks the storage backend and its geospatial index. Returns 503 while a check fails or the server shuts down.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *readinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Ready(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !report.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bitaksi-go-driver/internal/apierror"
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// maxHeatmapPrecision bounds the cell resolution, precision 9 cells are about 5m x 5m
+const maxHeatmapPrecision = 9
+
+// maxHeatmapCells bounds the cells a heatmap may span, so that a large box at a high precision
+// cannot make the response unbounded
+const maxHeatmapCells = 10000
+
+type HeatmapHandler interface {
+	DriverHeatmap(w http.ResponseWriter, r *http.Request)
+}
+
+type HeatmapService interface {
+	DriverHeatmap(ctx context.Context, bounds geo.Bounds, precision int) (*models.Heatmap, error)
+}
+
+type heatmapHandler struct {
+	service HeatmapService
+}
+
+func NewHeatmapHandler(service HeatmapService) HeatmapHandler {
+	return &heatmapHandler{service: service}
+}
+
+// DriverHeatmap returns driver counts per geohash cell
+// @Summary Driver Heatmap
+// @Description Counts drivers per geohash cell within a bounding box. A min_lon greater than max_lon crosses the antimeridian. The box may span at most 10000 cells of the precision.
+// @Tags Heatmap
+// @Produce json
+// @Param min_lat query float64 true "Southern edge"
+// @Param min_lon query float64 true "Western edge"
+// @Param max_lat query float64 true "Northern edge"
+// @Param max_lon query float64 true "Eastern edge"
+// @Param precision query int true "Geohash precision between 1 and 9"
+// @Success 200 {object} models.Heatmap
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 500 {object} apierror.Problem "Failed to build heatmap"
+// @Router /driver/api/v1/heatmap [get]
+func (h *heatmapHandler) DriverHeatmap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bounds, ok := parseBoundsQuery(w, r)
+	if !ok {
+		return
+	}
+
+	precision, err := strconv.Atoi(r.URL.Query().Get("precision"))
+	if err != nil || precision < 1 || precision > maxHeatmapPrecision {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid precision: must be between 1 and %d", maxHeatmapPrecision))
+		return
+	}
+	if cells := geo.GeohashCellCount(bounds, precision); cells > maxHeatmapCells {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid precision: the bounds span %d cells at precision %d, at most %d are allowed", cells, precision, maxHeatmapCells))
+		return
+	}
+
+	heatmap, err := h.service.DriverHeatmap(r.Context(), bounds, precision)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to build heatmap"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(heatmap)
+}
+
+// parseBoundsQuery parses and validates the min_lat, min_lon, max_lat and max_lon query parameters,
+// writing a 400 response if any of them is invalid
+func parseBoundsQuery(w http.ResponseWriter, r *http.Request) (geo.Bounds, bool) {
+	var values [4]float64
+	for i, name := range []string{"min_lat", "min_lon", "max_lat", "max_lon"} {
+		value, err := strconv.ParseFloat(r.URL.Query().Get(name), 64)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidParameter("Invalid %s: must be a number", name))
+			return geo.Bounds{}, false
+		}
+		values[i] = value
+	}
+
+	bounds := geo.Bounds{MinLat: values[0], MinLon: values[1], MaxLat: values[2], MaxLon: values[3]}
+	if bounds.MinLat < -90 || bounds.MaxLat > 90 || bounds.MinLat > bounds.MaxLat {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid latitude range: must be between -90 and 90 with min_lat <= max_lat"))
+		return geo.Bounds{}, false
+	}
+	if bounds.MinLon < -180 || bounds.MinLon > 180 || bounds.MaxLon < -180 || bounds.MaxLon > 180 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid longitude range: must be between -180 and 180"))
+		return geo.Bounds{}, false
+	}
+
+	return bounds, true
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+type MockHeatmapService struct {
+	DriverHeatmapFn func(ctx context.Context, bounds geo.Bounds, precision int) (*models.Heatmap, error)
+}
+
+func (m *MockHeatmapService) DriverHeatmap(ctx context.Context, bounds geo.Bounds, precision int) (*models.Heatmap, error) {
+	return m.DriverHeatmapFn(ctx, bounds, precision)
+}
+
+func TestDriverHeatmap(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Valid Request",
+			query:          "min_lat=40&min_lon=28&max_lat=42&max_lon=30&precision=2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"precision":2,"bounds":{"min_lat":40,"min_lon":28,"max_lat":42,"max_lon":30},"total":0,"cells":[]}`,
+		},
+		{
+			name:           "Crossing The Antimeridian",
+			query:          "min_lat=-10&min_lon=170&max_lat=10&max_lon=-170&precision=2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"precision":2,"bounds":{"min_lat":-10,"min_lon":170,"max_lat":10,"max_lon":-170},"total":0,"cells":[]}`,
+		},
+		{
+			name:           "Missing Bound",
+			query:          "min_lat=40&min_lon=28&max_lat=42&precision=2",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid max_lon: must be a number","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Inverted Latitudes",
+			query:          "min_lat=42&min_lon=28&max_lat=40&max_lon=30&precision=2",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid latitude range: must be between -90 and 90 with min_lat <= max_lat","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Precision Too High",
+			query:          "min_lat=40&min_lon=28&max_lat=42&max_lon=30&precision=10",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid precision: must be between 1 and 9","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Too Many Cells",
+			query:          "min_lat=40&min_lon=28&max_lat=42&max_lon=30&precision=6",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid precision: the bounds span 66795 cells at precision 6, at most 10000 are allowed","code":"invalid_parameter"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockHeatmapService{
+				DriverHeatmapFn: func(ctx context.Context, bounds geo.Bounds, precision int) (*models.Heatmap, error) {
+					return &models.Heatmap{Precision: precision, Bounds: bounds, Cells: []models.HeatmapCell{}}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/heatmap?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			NewHeatmapHandler(mockService).DriverHeatmap(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody+"\n" {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
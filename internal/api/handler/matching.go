@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"bitaksi-go-driver/internal/apierror"
+	"bitaksi-go-driver/internal/models"
+)
+
+type MatchingHandler interface {
+	MatchRiders(w http.ResponseWriter, r *http.Request)
+}
+
+type MatchingService interface {
+	MatchRiders(ctx context.Context, pickups []models.Pickup, maxDistance int) (*models.MatchResult, error)
+}
+
+// MatchRequest is a batch of pickups to be matched with drivers
+type MatchRequest struct {
+	Pickups     []models.Pickup `json:"pickups"`
+	MaxDistance int             `json:"max_distance"` // Maximum pickup distance in meters
+}
+
+type matchingHandler struct {
+	service      MatchingService
+	maxBatchSize int
+}
+
+func NewMatchingHandler(service MatchingService, maxBatchSize int) MatchingHandler {
+	return &matchingHandler{service: service, maxBatchSize: maxBatchSize}
+}
+
+// MatchRiders matches a batch of pickups with drivers
+// @Summary Batch Match Riders
+// @Description Assigns available drivers to a batch of pickups minimizing the total pickup distance
+// @Tags Matching
+// @Accept json
+// @Produce json
+// @Param request body MatchRequest true "Pickups and maximum pickup distance in meters"
+// @Success 200 {object} models.MatchResult
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 500 {object} apierror.Problem "Failed to match riders"
+// @Router /driver/api/v1/match [post]
+func (h *matchingHandler) MatchRiders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var body MatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Write(w, r, apierror.InvalidBody())
+		return
+	}
+
+	if len(body.Pickups) == 0 || len(body.Pickups) > h.maxBatchSize {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid pickups: must contain between 1 and %d pickups", h.maxBatchSize))
+		return
+	}
+	for _, pickup := range body.Pickups {
+		if pickup.Latitude < -90 || pickup.Latitude > 90 || pickup.Longitude < -180 || pickup.Longitude > 180 {
+			apierror.Write(w, r, apierror.InvalidParameter("Invalid pickup coordinates"))
+			return
+		}
+	}
+	if body.MaxDistance <= 0 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid max_distance: must be a positive integer"))
+		return
+	}
+
+	result, err := h.service.MatchRiders(r.Context(), body.Pickups, body.MaxDistance)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to match riders"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/service"
+)
+
+type MockMatchingService struct {
+	MatchRidersFn func(ctx context.Context, pickups []models.Pickup, maxDistance int) (*models.MatchResult, error)
+}
+
+func (m *MockMatchingService) MatchRiders(ctx context.Context, pickups []models.Pickup, maxDistance int) (*models.MatchResult, error) {
+	return m.MatchRidersFn(ctx, pickups, maxDistance)
+}
+
+func TestMatchRiders(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Valid Request",
+			body:           `{"pickups":[{"id":"a","latitude":41.0,"longitude":29.0}],"max_distance":3000}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"pairs":[],"unmatched":["a"],"total_distance":0}`,
+		},
+		{
+			name:           "Too Many Pickups",
+			body:           `{"pickups":[{"id":"a"},{"id":"b"},{"id":"c"}],"max_distance":3000}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid pickups: must contain between 1 and 2 pickups","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Invalid Coordinates",
+			body:           `{"pickups":[{"id":"a","latitude":95.0,"longitude":29.0}],"max_distance":3000}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid pickup coordinates","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Invalid Max Distance",
+			body:           `{"pickups":[{"id":"a","latitude":41.0,"longitude":29.0}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid max_distance: must be a positive integer","code":"invalid_parameter"}`,
+		},
+		{
+			name:           "Duplicate Pickups",
+			body:           `{"pickups":[{"id":"a","latitude":41.0,"longitude":29.0},{"id":"a","latitude":41.1,"longitude":29.1}],"max_distance":3000}`,
+			mockError:      fmt.Errorf("%w: a", service.ErrDuplicatePickup),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"duplicate pickup ID: a","code":"invalid_parameter"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockMatchingService{
+				MatchRidersFn: func(ctx context.Context, pickups []models.Pickup, maxDistance int) (*models.MatchResult, error) {
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &models.MatchResult{Pairs: []models.MatchPair{}, Unmatched: []string{pickups[0].ID}}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/match", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			NewMatchingHandler(mockService, 2).MatchRiders(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody+"\n" {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
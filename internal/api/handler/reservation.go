@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/apierror"
+	"bitaksi-go-driver/internal/models"
+)
+
+type ReservationHandler interface {
+	ClaimNearestDriver(w http.ResponseWriter, r *http.Request)
+	ConfirmReservation(w http.ResponseWriter, r *http.Request)
+	ReleaseDriver(w http.ResponseWriter, r *http.Request)
+}
+
+type ReservationService interface {
+	ClaimNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error)
+	ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) error
+	ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error
+}
+
+// ClaimResponse is a claimed driver together with the reservation ID, which only the claimant gets
+type ClaimResponse struct {
+	models.DriverWithDistance
+	ReservationID string `json:"reservation_id"`
+}
+
+type reservationHandler struct {
+	service ReservationService
+}
+
+func NewReservationHandler(service ReservationService) ReservationHandler {
+	return &reservationHandler{service: service}
+}
+
+// ClaimNearestDriver reserves the nearest available driver
+// @Summary Claim Nearest Driver
+// @Description Atomically finds the nearest available driver and reserves them. The reservation expires unless it is confirmed. The returned reservation_id is needed to confirm or release the reservation.
+// @Tags Reservation
+// @Produce json
+// @Param latitude query float64 true "Latitude"
+// @Param longitude query float64 true "Longitude"
+// @Param radius query int true "Search radius in meters"
+// @Success 200 {object} ClaimResponse
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 404 {object} apierror.Problem "No drivers found"
+// @Failure 500 {object} apierror.Problem "Failed to claim driver"
+// @Router /driver/api/v1/claim [post]
+func (h *reservationHandler) ClaimNearestDriver(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	latitude, longitude, radius, ok := parseLocationQuery(w, r)
+	if !ok {
+		return
+	}
+
+	driver, err := h.service.ClaimNearestDriver(r.Context(), latitude, longitude, radius)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to claim driver"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ClaimResponse{DriverWithDistance: *driver, ReservationID: driver.ReservationID})
+}
+
+// ConfirmReservation makes a claimed driver's reservation permanent
+// @Summary Confirm Reservation
+// @Description Confirms an unexpired reservation so it no longer auto-releases
+// @Tags Reservation
+// @Produce json
+// @Param id path string true "Driver ID"
+// @Param reservation_id query string true "Reservation ID returned by the claim"
+// @Success 200 {string} string "Reservation confirmed"
+// @Failure 400 {object} apierror.Problem "Invalid driver ID or missing reservation ID"
+// @Failure 409 {object} apierror.Problem "Reservation not found or expired"
+// @Router /driver/api/v1/drivers/{id}/confirm [post]
+func (h *reservationHandler) ConfirmReservation(w http.ResponseWriter, r *http.Request) {
+	h.updateReservation(w, r, h.service.ConfirmReservation, "Reservation confirmed")
+}
+
+// ReleaseDriver makes a reserved driver available again
+// @Summary Release Driver
+// @Description Releases a reserved driver so they can be found and claimed again
+// @Tags Reservation
+// @Produce json
+// @Param id path string true "Driver ID"
+// @Param reservation_id query string true "Reservation ID returned by the claim or the accepted ride request"
+// @Success 200 {string} string "Driver released"
+// @Failure 400 {object} apierror.Problem "Invalid driver ID or missing reservation ID"
+// @Failure 409 {object} apierror.Problem "Reservation not found or expired"
+// @Router /driver/api/v1/drivers/{id}/release [post]
+func (h *reservationHandler) ReleaseDriver(w http.ResponseWriter, r *http.Request) {
+	h.updateReservation(w, r, h.service.ReleaseDriver, "Driver released")
+}
+
+func (h *reservationHandler) updateReservation(w http.ResponseWriter, r *http.Request, update func(ctx context.Context, id primitive.ObjectID, reservationID string) error, message string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid driver ID"))
+		return
+	}
+
+	reservationID := r.URL.Query().Get("reservation_id")
+	if reservationID == "" {
+		apierror.Write(w, r, apierror.InvalidParameter("Missing reservation_id"))
+		return
+	}
+
+	if err := update(r.Context(), id, reservationID); err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to update reservation"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
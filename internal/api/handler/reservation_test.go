@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+type MockReservationService struct {
+	ClaimNearestDriverFn func(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error)
+	ConfirmReservationFn func(ctx context.Context, id primitive.ObjectID, reservationID string) error
+	ReleaseDriverFn      func(ctx context.Context, id primitive.ObjectID, reservationID string) error
+}
+
+func (m *MockReservationService) ClaimNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
+	return m.ClaimNearestDriverFn(ctx, latitude, longitude, radius)
+}
+
+func (m *MockReservationService) ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	return m.ConfirmReservationFn(ctx, id, reservationID)
+}
+
+func (m *MockReservationService) ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	return m.ReleaseDriverFn(ctx, id, reservationID)
+}
+
+func TestClaimNearestDriver(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Successful Claim",
+			query:          "latitude=41.0&longitude=29.0&radius=5000",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":"6775be842e9ffeeae6b1de93","location":{"type":"","coordinates":null},"status":"reserved","distance":120,"reservation_id":"r1"}`,
+		},
+		{
+			name:           "No Drivers Found",
+			query:          "latitude=41.0&longitude=29.0&radius=5000",
+			mockError:      repository.ErrDriverNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"No drivers found","code":"no_drivers_found"}`,
+		},
+		{
+			name:           "Invalid Latitude",
+			query:          "latitude=91&longitude=29.0&radius=5000",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid latitude: must be between -90 and 90","code":"invalid_parameter"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockReservationService{
+				ClaimNearestDriverFn: func(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					id, _ := primitive.ObjectIDFromHex("6775be842e9ffeeae6b1de93")
+					return &models.DriverWithDistance{ID: id, Status: models.DriverStatusReserved, ReservationID: "r1", Distance: 120}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/claim?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			NewReservationHandler(mockService).ClaimNearestDriver(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody+"\n" {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestConfirmReservation(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		reservationID  string
+		mockError      error
+		expectedStatus int
+	}{
+		{name: "Confirmed", id: primitive.NewObjectID().Hex(), reservationID: "r1", expectedStatus: http.StatusOK},
+		{name: "Expired", id: primitive.NewObjectID().Hex(), reservationID: "r1", mockError: repository.ErrReservationNotFound, expectedStatus: http.StatusConflict},
+		{name: "Invalid ID", id: "nope", reservationID: "r1", expectedStatus: http.StatusBadRequest},
+		{name: "Missing reservation ID", id: primitive.NewObjectID().Hex(), expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockReservationService{
+				ConfirmReservationFn: func(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+					if reservationID != tt.reservationID {
+						t.Errorf("expected reservation ID %q, got %q", tt.reservationID, reservationID)
+					}
+					return tt.mockError
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/drivers/"+tt.id+"/confirm?reservation_id="+tt.reservationID, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			rec := httptest.NewRecorder()
+
+			NewReservationHandler(mockService).ConfirmReservation(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestReleaseDriver(t *testing.T) {
+	id := primitive.NewObjectID()
+	mockService := &MockReservationService{
+		ReleaseDriverFn: func(ctx context.Context, driverID primitive.ObjectID, reservationID string) error {
+			if driverID != id || reservationID != "r1" {
+				return repository.ErrReservationNotFound
+			}
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name           string
+		reservationID  string
+		expectedStatus int
+	}{
+		{name: "Released", reservationID: "r1", expectedStatus: http.StatusOK},
+		{name: "Held by another client", reservationID: "r2", expectedStatus: http.StatusConflict},
+		{name: "Missing reservation ID", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/drivers/"+id.Hex()+"/release?reservation_id="+tt.reservationID, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": id.Hex()})
+			rec := httptest.NewRecorder()
+
+			NewReservationHandler(mockService).ReleaseDriver(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bitaksi-go-driver/internal/apierror"
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+type SurgeHandler interface {
+	SurgeAt(w http.ResponseWriter, r *http.Request)
+	SurgeMap(w http.ResponseWriter, r *http.Request)
+}
+
+type SurgeService interface {
+	SurgeAt(ctx context.Context, latitude, longitude float64) (*models.SurgeCell, error)
+	SurgeMap(ctx context.Context, bounds geo.Bounds) (*models.SurgeMap, error)
+}
+
+type surgeHandler struct {
+	service SurgeService
+}
+
+func NewSurgeHandler(service SurgeService) SurgeHandler {
+	return &surgeHandler{service: service}
+}
+
+// SurgeAt returns the surge multiplier at a location
+// @Summary Surge At Location
+// @Description Returns available drivers, recent searches and the resulting surge multiplier of the cell containing the location
+// @Tags Surge
+// @Produce json
+// @Param latitude query float64 true "Latitude"
+// @Param longitude query float64 true "Longitude"
+// @Success 200 {object} models.SurgeCell
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 500 {object} apierror.Problem "Failed to compute surge"
+// @Router /driver/api/v1/surge [get]
+func (h *surgeHandler) SurgeAt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	latitude, err := strconv.ParseFloat(r.URL.Query().Get("latitude"), 64)
+	if err != nil || latitude < -90 || latitude > 90 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid latitude: must be between -90 and 90"))
+		return
+	}
+
+	longitude, err := strconv.ParseFloat(r.URL.Query().Get("longitude"), 64)
+	if err != nil || longitude < -180 || longitude > 180 {
+		apierror.Write(w, r, apierror.InvalidParameter("Invalid longitude: must be between -180 and 180"))
+		return
+	}
+
+	cell, err := h.service.SurgeAt(r.Context(), latitude, longitude)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to compute surge"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(cell)
+}
+
+// SurgeMap returns the surge multipliers of all cells within a bounding box
+// @Summary Surge Map
+// @Description Returns the surge multiplier of every cell within the bounding box that has available drivers or recent searches
+// @Tags Surge
+// @Produce json
+// @Param min_lat query float64 true "Southern edge"
+// @Param min_lon query float64 true "Western edge"
+// @Param max_lat query float64 true "Northern edge"
+// @Param max_lon query float64 true "Eastern edge"
+// @Success 200 {object} models.SurgeMap
+// @Failure 400 {object} apierror.Problem "Invalid input"
+// @Failure 500 {object} apierror.Problem "Failed to compute surge"
+// @Router /driver/api/v1/surge/cells [get]
+func (h *surgeHandler) SurgeMap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bounds, ok := parseBoundsQuery(w, r)
+	if !ok {
+		return
+	}
+
+	surgeMap, err := h.service.SurgeMap(r.Context(), bounds)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(err, "Failed to compute surge"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(surgeMap)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+type MockSurgeService struct {
+	SurgeAtFn  func(ctx context.Context, latitude, longitude float64) (*models.SurgeCell, error)
+	SurgeMapFn func(ctx context.Context, bounds geo.Bounds) (*models.SurgeMap, error)
+}
+
+func (m *MockSurgeService) SurgeAt(ctx context.Context, latitude, longitude float64) (*models.SurgeCell, error) {
+	return m.SurgeAtFn(ctx, latitude, longitude)
+}
+
+func (m *MockSurgeService) SurgeMap(ctx context.Context, bounds geo.Bounds) (*models.SurgeMap, error) {
+	return m.SurgeMapFn(ctx, bounds)
+}
+
+func TestSurgeAt(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Valid Request",
+			query:          "latitude=41&longitude=29",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"geohash":"sxk3","supply":2,"demand":4,"ratio":2,"multiplier":1.5,"center":{"latitude":0,"longitude":0},"bounds":{"min_lat":0,"min_lon":0,"max_lat":0,"max_lon":0}}`,
+		},
+		{
+			name:           "Invalid Longitude",
+			query:          "latitude=41&longitude=181",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid longitude: must be between -180 and 180","code":"invalid_parameter"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSurgeService{
+				SurgeAtFn: func(ctx context.Context, latitude, longitude float64) (*models.SurgeCell, error) {
+					return &models.SurgeCell{Geohash: "sxk3", Supply: 2, Demand: 4, Ratio: 2, Multiplier: 1.5}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/surge?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			NewSurgeHandler(mockService).SurgeAt(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody+"\n" {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
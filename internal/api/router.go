@@ -1,32 +1,189 @@
 package api
 
 import (
+	"expvar"
+	"log/slog"
 	"net/http"
+	"net/netip"
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 
 	"bitaksi-go-driver/internal/api/handler"
+	"bitaksi-go-driver/internal/apierror"
+	"bitaksi-go-driver/internal/auth"
 	"bitaksi-go-driver/internal/config"
+	"bitaksi-go-driver/internal/metrics"
 	"bitaksi-go-driver/internal/middleware"
 )
 
+// searchRoute is the template of the nearest driver search route, as middleware sees it
+const searchRoute = "/driver/api/v1/search"
+
+// Routes gives router options access to the public and the authenticated driver routers
+type Routes struct {
+	Public *mux.Router
+	Driver *mux.Router
+	// Authenticator checks the credentials of requests to the driver routes, the key of
+	// server.api_key unless an option sets another
+	Authenticator auth.Authenticator
+}
+
+// RouterOption registers optional endpoints
+type RouterOption func(routes *Routes)
+
+// scoped lets only callers granted the scope through to the handler
+func scoped(scope string, handler http.HandlerFunc) http.Handler {
+	return middleware.RequireScope(scope)(handler)
+}
+
+// WithAuthenticator authenticates requests to the driver routes with the authenticator, e.g. a
+// key store of many keys with their own scopes
+func WithAuthenticator(authenticator auth.Authenticator) RouterOption {
+	return func(routes *Routes) {
+		routes.Authenticator = authenticator
+	}
+}
+
+// WithDispatch registers the ride request dispatch endpoints
+func WithDispatch(dispatchService handler.DispatchService) RouterOption {
+	return func(routes *Routes) {
+		dispatchHandler := handler.NewDispatchHandler(dispatchService)
+
+		routes.Driver.Handle("/rides", scoped(auth.ScopeRidesWrite, dispatchHandler.CreateRide)).Methods(http.MethodPost)
+		routes.Driver.Handle("/rides/{id}", scoped(auth.ScopeRidesRead, dispatchHandler.GetRide)).Methods(http.MethodGet)
+		routes.Driver.Handle("/rides/{id}/accept", scoped(auth.ScopeRidesWrite, dispatchHandler.AcceptOffer)).Methods(http.MethodPost)
+		routes.Driver.Handle("/rides/{id}/decline", scoped(auth.ScopeRidesWrite, dispatchHandler.DeclineOffer)).Methods(http.MethodPost)
+	}
+}
+
+// WithReservations registers the find-and-claim reservation endpoints
+func WithReservations(reservationService handler.ReservationService) RouterOption {
+	return func(routes *Routes) {
+		reservationHandler := handler.NewReservationHandler(reservationService)
+
+		routes.Driver.Handle("/claim", scoped(auth.ScopeReservationsWrite, reservationHandler.ClaimNearestDriver)).Methods(http.MethodPost)
+		routes.Driver.Handle("/drivers/{id}/confirm", scoped(auth.ScopeReservationsWrite, reservationHandler.ConfirmReservation)).Methods(http.MethodPost)
+		routes.Driver.Handle("/drivers/{id}/release", scoped(auth.ScopeReservationsWrite, reservationHandler.ReleaseDriver)).Methods(http.MethodPost)
+	}
+}
+
+// WithMatching registers the batch matching endpoint
+func WithMatching(matchingService handler.MatchingService, maxBatchSize int) RouterOption {
+	return func(routes *Routes) {
+		matchingHandler := handler.NewMatchingHandler(matchingService, maxBatchSize)
+
+		routes.Driver.Handle("/match", scoped(auth.ScopeSearchRead, matchingHandler.MatchRiders)).Methods(http.MethodPost)
+	}
+}
+
+// WithHeatmap registers the driver heatmap endpoint
+func WithHeatmap(heatmapService handler.HeatmapService) RouterOption {
+	return func(routes *Routes) {
+		heatmapHandler := handler.NewHeatmapHandler(heatmapService)
+
+		routes.Driver.Handle("/heatmap", scoped(auth.ScopeAnalyticsRead, heatmapHandler.DriverHeatmap)).Methods(http.MethodGet)
+	}
+}
+
+// WithSurge registers the surge multiplier endpoints
+func WithSurge(surgeService handler.SurgeService) RouterOption {
+	return func(routes *Routes) {
+		surgeHandler := handler.NewSurgeHandler(surgeService)
+
+		routes.Driver.Handle("/surge", scoped(auth.ScopeAnalyticsRead, surgeHandler.SurgeAt)).Methods(http.MethodGet)
+		routes.Driver.Handle("/surge/cells", scoped(auth.ScopeAnalyticsRead, surgeHandler.SurgeMap)).Methods(http.MethodGet)
+	}
+}
+
+// WithDemand registers the search demand endpoints
+func WithDemand(demandService handler.DemandService) RouterOption {
+	return func(routes *Routes) {
+		demandHandler := handler.NewDemandHandler(demandService)
+
+		routes.Driver.Handle("/demand/unmet", scoped(auth.ScopeAnalyticsRead, demandHandler.UnmetDemand)).Methods(http.MethodGet)
+	}
+}
+
+// WithDebugVars serves the expvar variables, such as the search cache counters, to callers granted
+// the debug scope. They include memory statistics and the command line, so they are not public.
+func WithDebugVars() RouterOption {
+	return func(routes *Routes) {
+		routes.Driver.Handle("/debug/vars", scoped(auth.ScopeDebugRead, expvar.Handler().ServeHTTP)).Methods(http.MethodGet)
+	}
+}
+
+// WithMetrics records the requests to every route and serves the metrics next to the health check
+func WithMetrics(m *metrics.Metrics) RouterOption {
+	return func(routes *Routes) {
+		routes.Public.Use(middleware.MetricsMiddleware(m))
+		routes.Public.Handle("/metrics", m.Handler()).Methods(http.MethodGet)
+	}
+}
+
+// WithAccessLog logs every request with the address of the client, taken from the forwarding
+// headers of the trusted proxies, and the identity of its API key. Successful searches, by far the
+// most frequent requests, are sampled at the given rate.
+func WithAccessLog(trustedProxies []netip.Prefix, searchSampleRate float64) RouterOption {
+	return func(routes *Routes) {
+		routes.Public.Use(middleware.AccessLogMiddleware(slog.Default(), middleware.AccessLogOptions{
+			TrustedProxies: trustedProxies,
+			SampleRates:    map[string]float64{searchRoute: searchSampleRate},
+		}))
+	}
+}
+
+// WithReadiness serves the readiness probe of the checker next to the liveness probe
+func WithReadiness(checker handler.ReadinessChecker) RouterOption {
+	return func(routes *Routes) {
+		routes.Public.Handle("/readyz", handler.NewReadinessHandler(checker)).Methods(http.MethodGet)
+	}
+}
+
+// WithTracing starts a server span named after the route template for every request, continuing
+// the trace of the caller given in the W3C traceparent header
+func WithTracing(serviceName string) RouterOption {
+	return func(routes *Routes) {
+		routes.Public.Use(otelmux.Middleware(serviceName))
+	}
+}
+
 // SetupRouter initializes the application router with all endpoints and middleware
-func SetupRouter(driverService handler.DriverService, cfg *config.Config) *mux.Router {
+func SetupRouter(driverService handler.DriverService, cfg *config.Config, opts ...RouterOption) *mux.Router {
 	router := mux.NewRouter()
+	router.NotFoundHandler = apierror.Handler(apierror.New(http.StatusNotFound, apierror.CodeRouteNotFound, "No route matches the path"))
+	router.MethodNotAllowedHandler = apierror.Handler(apierror.New(http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "The route does not accept the method"))
+
+	// Request IDs first, so that everything logged for a request carries its ID
+	router.Use(middleware.RequestIDMiddleware)
 
 	// Initialize handlers
 	driverHandler := handler.NewDriverHandler(driverService)
 
 	// Public routes (e.g., health check)
 	router.HandleFunc("/health", handler.HealthCheckHandler).Methods("GET")
+	router.HandleFunc("/livez", handler.LivenessHandler).Methods(http.MethodGet)
 
 	// Driver-related routes with API key middleware
 	driverRouter := router.PathPrefix("/driver/api/v1").Subrouter()
-	driverRouter.Use(middleware.APIKeyMiddleware(cfg))
 
 	// Register driver endpoints
-	driverRouter.HandleFunc("/import", driverHandler.ImportLocations).Methods(http.MethodPost)
-	driverRouter.HandleFunc("/search", driverHandler.FindNearestDriver).Methods(http.MethodGet)
+	driverRouter.Handle("/import", scoped(auth.ScopeImportWrite, driverHandler.ImportLocations)).Methods(http.MethodPost)
+	driverRouter.Handle("/search", scoped(auth.ScopeSearchRead, driverHandler.FindNearestDriver)).Methods(http.MethodGet)
+	driverRouter.Handle("/drivers/{id}/location", scoped(auth.ScopeDriversWrite, driverHandler.UpdateLocation)).Methods(http.MethodPut)
+
+	// Register optional endpoints
+	routes := &Routes{Public: router, Driver: driverRouter}
+	for _, opt := range opts {
+		opt(routes)
+	}
+
+	// After the options, which may replace the authenticator
+	if routes.Authenticator == nil {
+		// A single key, valid whatever its value, so the error can be ignored
+		routes.Authenticator, _ = auth.NewKeyStore(auth.LegacyKey(cfg.Server.APIKey))
+	}
+	driverRouter.Use(middleware.AuthMiddleware(routes.Authenticator))
 
 	return router
 }
@@ -1,12 +1,20 @@
 package api
 
 import (
+	"bitaksi-go-driver/internal/auth"
 	"bitaksi-go-driver/internal/config"
+	"bitaksi-go-driver/internal/health"
+	"bitaksi-go-driver/internal/metrics"
 	"bitaksi-go-driver/internal/models"
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // MockDriverService is a mock implementation of the DriverService interface
@@ -20,6 +28,10 @@ func (m *MockDriverService) FindNearestDriver(ctx context.Context, latitude, lon
 	return nil, nil
 }
 
+func (m *MockDriverService) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	return nil
+}
+
 func TestSetupRouter(t *testing.T) {
 	cfg := &config.Config{
 		Server: struct {
@@ -63,6 +75,20 @@ func TestSetupRouter(t *testing.T) {
 			headers:        nil,
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:           "Liveness",
+			method:         http.MethodGet,
+			endpoint:       "/livez",
+			headers:        nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unknown Route",
+			method:         http.MethodGet,
+			endpoint:       "/driver/api/v2/search",
+			headers:        nil,
+			expectedStatus: http.StatusNotFound,
+		},
 		{
 			name:           "Unauthorized Import",
 			method:         http.MethodPost,
@@ -111,3 +137,182 @@ func TestSetupRouter(t *testing.T) {
 		})
 	}
 }
+
+func TestSetupRouter_Metrics(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.APIKey = "test-api-key"
+	router := SetupRouter(&MockDriverService{}, cfg, WithMetrics(metrics.New()))
+
+	requests := []struct {
+		method   string
+		endpoint string
+		apiKey   string
+	}{
+		{method: http.MethodGet, endpoint: "/health"},
+		{method: http.MethodGet, endpoint: "/driver/api/v1/search?latitude=40.0&longitude=-73.0&radius=5000"},
+		{method: http.MethodPut, endpoint: "/driver/api/v1/drivers/507f1f77bcf86cd799439011/location", apiKey: "test-api-key"},
+		{method: http.MethodPut, endpoint: "/driver/api/v1/drivers/507f191e810c19729de860ea/location", apiKey: "test-api-key"},
+	}
+	for _, request := range requests {
+		req := httptest.NewRequest(request.method, request.endpoint, strings.NewReader(`{"latitude": 41, "longitude": 29}`))
+		req.Header.Set("Authorization", request.apiKey)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	for _, expected := range []string{
+		`http_requests_total{method="GET",route="/health",status="200"} 1`,
+		`http_requests_total{method="GET",route="/driver/api/v1/search",status="401"} 1`,
+		`http_requests_total{method="PUT",route="/driver/api/v1/drivers/{id}/location",status="200"} 2`,
+		`http_request_duration_seconds_count{method="PUT",route="/driver/api/v1/drivers/{id}/location",status="200"} 2`,
+	} {
+		if !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("expected metrics to contain %q", expected)
+		}
+	}
+}
+
+func TestSetupRouter_RequestID(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.APIKey = "test-api-key"
+	router := SetupRouter(&MockDriverService{}, cfg)
+
+	tests := []struct {
+		name        string
+		requestID   string
+		keepsID     bool
+		endpoint    string
+		expectedErr bool
+	}{
+		{name: "Generated", endpoint: "/health"},
+		{name: "Accepted", requestID: "abc-123", keepsID: true, endpoint: "/health"},
+		{name: "Replaced When Unsafe", requestID: "abc\" 123", endpoint: "/health"},
+		{name: "In Error Body", requestID: "abc-123", keepsID: true, endpoint: "/driver/api/v1/search?latitude=abc&longitude=29&radius=100", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.endpoint, nil)
+			req.Header.Set("Authorization", "test-api-key")
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			id := rec.Header().Get("X-Request-ID")
+			if id == "" {
+				t.Fatal("expected an X-Request-ID response header")
+			}
+			if tt.keepsID != (id == tt.requestID) {
+				t.Errorf("unexpected request ID %q for incoming %q", id, tt.requestID)
+			}
+			if tt.expectedErr && !strings.Contains(rec.Body.String(), `"request_id":"`+id+`"`) {
+				t.Errorf("expected the error body to contain the request ID, got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSetupRouter_Readiness(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.APIKey = "test-api-key"
+
+	healthy := health.Check{Name: "mongodb", Check: func(ctx context.Context) error { return nil }}
+	missingIndex := health.Check{Name: "geo_index", Check: func(ctx context.Context) error { return errors.New("index is missing") }}
+
+	tests := []struct {
+		name           string
+		checks         []health.Check
+		shutDown       bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Ready", checks: []health.Check{healthy}, expectedStatus: http.StatusOK, expectedBody: `"status":"ready"`},
+		{name: "Failing Check", checks: []health.Check{healthy, missingIndex}, expectedStatus: http.StatusServiceUnavailable, expectedBody: `"geo_index":{"status":"failed"`},
+		{name: "Shutting Down", checks: []health.Check{healthy}, shutDown: true, expectedStatus: http.StatusServiceUnavailable, expectedBody: `"status":"shutting_down"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := health.NewChecker(time.Second, tt.checks...)
+			if tt.shutDown {
+				checker.ShutDown()
+			}
+			router := SetupRouter(&MockDriverService{}, cfg, WithReadiness(checker))
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("expected body to contain %s, got %s", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+// authenticatorFunc adapts a function to auth.Authenticator
+type authenticatorFunc func(ctx context.Context, credentials string) (auth.Principal, error)
+
+func (f authenticatorFunc) Authenticate(ctx context.Context, credentials string) (auth.Principal, error) {
+	return f(ctx, credentials)
+}
+
+func TestSetupRouter_Scopes(t *testing.T) {
+	keys, err := auth.NewKeyStore(auth.Key{Name: "rider-app", Hash: auth.HashKey("rider-key"), Scopes: []string{auth.ScopeSearchRead}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bearer := authenticatorFunc(func(ctx context.Context, token string) (auth.Principal, error) {
+		if token != "gateway-token" {
+			return auth.Principal{}, auth.ErrInvalidCredentials
+		}
+		return auth.Principal{Name: "gateway-user", Scopes: []string{auth.ScopeImportWrite}}, nil
+	})
+	cfg := &config.Config{}
+	cfg.Server.APIKey = "test-api-key"
+	router := SetupRouter(&MockDriverService{}, cfg, WithAuthenticator(auth.BearerOrKey{Keys: keys, Bearer: bearer}), WithDebugVars())
+
+	tests := []struct {
+		name           string
+		method         string
+		endpoint       string
+		apiKey         string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "Granted Scope", method: http.MethodGet, endpoint: "/driver/api/v1/search?latitude=40&longitude=29&radius=100", apiKey: "rider-key", expectedStatus: http.StatusOK},
+		{name: "Missing Scope", method: http.MethodPost, endpoint: "/driver/api/v1/import", apiKey: "rider-key", expectedStatus: http.StatusForbidden, expectedCode: `"code":"insufficient_scope"`},
+		{name: "Bearer Token", method: http.MethodPost, endpoint: "/driver/api/v1/import", apiKey: "Bearer gateway-token", expectedStatus: http.StatusOK},
+		{name: "Bearer Token Missing Scope", method: http.MethodGet, endpoint: "/driver/api/v1/search?latitude=40&longitude=29&radius=100", apiKey: "Bearer gateway-token", expectedStatus: http.StatusForbidden, expectedCode: `"code":"insufficient_scope"`},
+		{name: "Invalid Bearer Token", method: http.MethodPost, endpoint: "/driver/api/v1/import", apiKey: "Bearer rider-key", expectedStatus: http.StatusUnauthorized, expectedCode: `"code":"unauthorized"`},
+		{name: "Debug Vars Without Credentials", method: http.MethodGet, endpoint: "/driver/api/v1/debug/vars", expectedStatus: http.StatusUnauthorized, expectedCode: `"code":"unauthorized"`},
+		{name: "Debug Vars Missing Scope", method: http.MethodGet, endpoint: "/driver/api/v1/debug/vars", apiKey: "rider-key", expectedStatus: http.StatusForbidden, expectedCode: `"code":"insufficient_scope"`},
+		{name: "Debug Vars Not Public", method: http.MethodGet, endpoint: "/debug/vars", expectedStatus: http.StatusNotFound, expectedCode: `"code":"route_not_found"`},
+		{name: "Replaced Legacy Key", method: http.MethodPost, endpoint: "/driver/api/v1/import", apiKey: "test-api-key", expectedStatus: http.StatusUnauthorized, expectedCode: `"code":"unauthorized"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.endpoint, nil)
+			req.Header.Set("Authorization", tt.apiKey)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.expectedCode) {
+				t.Errorf("expected body to contain %s, got %s", tt.expectedCode, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Package apierror defines the errors of the HTTP API and writes them as RFC 7807 problem details,
+// with a stable code for clients to branch on instead of the human-readable detail.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"bitaksi-go-driver/internal/logging"
+	"bitaksi-go-driver/internal/repository"
+	"bitaksi-go-driver/internal/service"
+)
+
+// ContentType is the media type of problem details
+const ContentType = "application/problem+json"
+
+// Code identifies the kind of an error. Codes are part of the API and must not change.
+type Code string
+
+// Error codes of the API
+const (
+	CodeInvalidBody         Code = "invalid_body"
+	CodeInvalidParameter    Code = "invalid_parameter"
+	CodeUnauthorized        Code = "unauthorized"
+	CodeInsufficientScope   Code = "insufficient_scope"
+	CodeRouteNotFound       Code = "route_not_found"
+	CodeMethodNotAllowed    Code = "method_not_allowed"
+	CodeNoDriversFound      Code = "no_drivers_found"
+	CodeDriverNotFound      Code = "driver_not_found"
+	CodeDriverUnavailable   Code = "driver_unavailable"
+	CodeReservationNotFound Code = "reservation_not_found"
+	CodeRideNotFound        Code = "ride_not_found"
+	CodeNoPendingOffer      Code = "no_pending_offer"
+	CodeInternal            Code = "internal_error"
+)
+
+// Error is an error of the API with the status and code it is reported with. The cause of
+// internal errors is logged but never shown to clients, as it may reveal storage internals.
+type Error struct {
+	Status int
+	Code   Code
+	Detail string
+	Err    error
+}
+
+// New creates an error reported with the status, code and detail
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// InvalidBody reports a request body that is not the expected JSON
+func InvalidBody() *Error {
+	return New(http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+}
+
+// InvalidParameter reports a missing or invalid query, path or body parameter
+func InvalidParameter(format string, args ...interface{}) *Error {
+	return New(http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf(format, args...))
+}
+
+// Wrap returns the error of the API a domain error maps to, such as driver_not_found for
+// repository.ErrUnknownDriver, and an internal error with the detail otherwise
+func Wrap(err error, detail string) *Error {
+	var apiErr *Error
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, repository.ErrDriverNotFound):
+		return New(http.StatusNotFound, CodeNoDriversFound, "No drivers found")
+	case errors.Is(err, repository.ErrUnknownDriver):
+		return New(http.StatusNotFound, CodeDriverNotFound, "Driver not found")
+	case errors.Is(err, repository.ErrDriverUnavailable):
+		return New(http.StatusConflict, CodeDriverUnavailable, "Driver is no longer available")
+	case errors.Is(err, repository.ErrReservationNotFound):
+		return New(http.StatusConflict, CodeReservationNotFound, "Reservation not found or expired")
+	case errors.Is(err, service.ErrRideNotFound):
+		return New(http.StatusNotFound, CodeRideNotFound, "Ride request not found")
+	case errors.Is(err, service.ErrNoActiveOffer):
+		return New(http.StatusConflict, CodeNoPendingOffer, "No pending offer for this driver")
+	case errors.Is(err, service.ErrInvalidDemandQuery), errors.Is(err, service.ErrDuplicatePickup):
+		return New(http.StatusBadRequest, CodeInvalidParameter, err.Error())
+	default:
+		return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Detail: detail, Err: err}
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Detail, e.Err)
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Problem is the RFC 7807 problem details body of an error, extended with its code and the ID of the request
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Code      Code   `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Write writes the error as problem details. Errors other than an *Error are internal errors.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := Wrap(err, "Internal server error")
+	if apiErr.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "Request failed", "code", apiErr.Code, "error", apiErr)
+	}
+
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Detail,
+		Code:      apiErr.Code,
+		RequestID: logging.RequestID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(problem)
+}
+
+// Handler returns a handler writing the error, e.g. for requests no route matches
+func Handler(err *Error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, err)
+	})
+}
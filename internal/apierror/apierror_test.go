@@ -0,0 +1,78 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitaksi-go-driver/internal/logging"
+	"bitaksi-go-driver/internal/repository"
+	"bitaksi-go-driver/internal/service"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   Code
+	}{
+		{name: "API Error", err: InvalidParameter("Invalid radius"), expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidParameter},
+		{name: "No Drivers", err: fmt.Errorf("search failed: %w", repository.ErrDriverNotFound), expectedStatus: http.StatusNotFound, expectedCode: CodeNoDriversFound},
+		{name: "Unknown Driver", err: repository.ErrUnknownDriver, expectedStatus: http.StatusNotFound, expectedCode: CodeDriverNotFound},
+		{name: "Unavailable Driver", err: repository.ErrDriverUnavailable, expectedStatus: http.StatusConflict, expectedCode: CodeDriverUnavailable},
+		{name: "Expired Reservation", err: repository.ErrReservationNotFound, expectedStatus: http.StatusConflict, expectedCode: CodeReservationNotFound},
+		{name: "Unknown Ride", err: service.ErrRideNotFound, expectedStatus: http.StatusNotFound, expectedCode: CodeRideNotFound},
+		{name: "No Offer", err: service.ErrNoActiveOffer, expectedStatus: http.StatusConflict, expectedCode: CodeNoPendingOffer},
+		{name: "Invalid Demand Query", err: service.ErrInvalidDemandQuery, expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidParameter},
+		{name: "Duplicate Pickup", err: service.ErrDuplicatePickup, expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidParameter},
+		{name: "Internal", err: errors.New("connection reset"), expectedStatus: http.StatusInternalServerError, expectedCode: CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := Wrap(tt.err, "Failed")
+			if apiErr.Status != tt.expectedStatus || apiErr.Code != tt.expectedCode {
+				t.Errorf("expected %d %s, got %d %s", tt.expectedStatus, tt.expectedCode, apiErr.Status, apiErr.Code)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/driver/api/v1/search", nil)
+	req = req.WithContext(logging.WithRequestID(req.Context(), "abc-123"))
+	rec := httptest.NewRecorder()
+
+	Write(rec, req, Wrap(errors.New(`server selection error: "mongo:27017"`), "Failed to search drivers"))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != ContentType {
+		t.Errorf("expected content type %s, got %s", ContentType, contentType)
+	}
+	if strings.Contains(rec.Body.String(), "mongo") {
+		t.Errorf("expected the cause not to be exposed, got %s", rec.Body.String())
+	}
+
+	var problem Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("expected a JSON body: %v", err)
+	}
+	expected := Problem{
+		Type:      "about:blank",
+		Title:     "Internal Server Error",
+		Status:    http.StatusInternalServerError,
+		Detail:    "Failed to search drivers",
+		Code:      CodeInternal,
+		RequestID: "abc-123",
+	}
+	if problem != expected {
+		t.Errorf("expected %+v, got %+v", expected, problem)
+	}
+}
@@ -0,0 +1,65 @@
+// Package auth authenticates the callers of the API and tells what they are allowed to do. Every
+// caller is a principal with a name, used in logs, and the scopes granted to it.
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// Scopes grant access to groups of endpoints
+const (
+	ScopeSearchRead        = "search:read"        // Nearest driver search and batch matching
+	ScopeImportWrite       = "import:write"       // Driver imports
+	ScopeDriversWrite      = "drivers:write"      // Driver location updates
+	ScopeRidesRead         = "rides:read"         // Ride request lookups
+	ScopeRidesWrite        = "rides:write"        // Ride requests and driver responses to offers
+	ScopeReservationsWrite = "reservations:write" // Claiming, confirming and releasing drivers
+	ScopeAnalyticsRead     = "analytics:read"     // Heatmaps, surge and demand reports
+	ScopeDebugRead         = "debug:read"         // Runtime counters, memory statistics and the command line
+)
+
+// AllScopes lists every scope
+var AllScopes = []string{
+	ScopeSearchRead,
+	ScopeImportWrite,
+	ScopeDriversWrite,
+	ScopeRidesRead,
+	ScopeRidesWrite,
+	ScopeReservationsWrite,
+	ScopeAnalyticsRead,
+	ScopeDebugRead,
+}
+
+// ErrInvalidCredentials is returned for credentials that do not authenticate anyone
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal is an authenticated caller
+type Principal struct {
+	Name   string
+	Scopes []string
+}
+
+// HasScope tells whether the principal was granted the scope
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Authenticator finds the principal the credentials of the Authorization header belong to
+type Authenticator interface {
+	Authenticate(ctx context.Context, credentials string) (Principal, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal carried by the context, if the request was authenticated
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minJWKSRefresh limits how often a token signed with an unknown key makes the JWKS be fetched
+// again, so that garbage tokens cannot make the service hammer the issuer
+const minJWKSRefresh = time.Minute
+
+// maxJWKSSize bounds the JWKS documents read
+const maxJWKSSize = 1 << 20
+
+// jwk is a JSON Web Key of the RSA and P-256 kinds RS256 and ES256 tokens are verified with
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verificationKey is a public key with the algorithm it verifies, empty if the JWK does not say
+type verificationKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// JWKS holds the public keys tokens are verified with, loaded from a file or an HTTP(S) URL.
+// Keys loaded from a URL are refreshed periodically and when a token names an unknown key, so the
+// issuer can rotate keys.
+type JWKS struct {
+	source string
+	client *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]verificationKey
+
+	// refreshMu serialises refreshes, so that requests naming the same unknown key wait for one
+	// fetch instead of each starting their own
+	refreshMu   sync.Mutex
+	attemptedAt time.Time
+}
+
+// LoadJWKS loads the key set at the source, a file path or an http:// or https:// URL
+func LoadJWKS(ctx context.Context, source string) (*JWKS, error) {
+	jwks := &JWKS{source: source, client: &http.Client{Timeout: 10 * time.Second}}
+	if err := jwks.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return jwks, nil
+}
+
+func (s *JWKS) remote() bool {
+	return strings.HasPrefix(s.source, "http://") || strings.HasPrefix(s.source, "https://")
+}
+
+// Refresh loads the key set again, keeping the current keys if it fails
+func (s *JWKS) Refresh(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	return s.refresh(ctx)
+}
+
+// refresh loads the key set again. The caller holds s.refreshMu.
+func (s *JWKS) refresh(ctx context.Context) error {
+	// Recorded whether or not the fetch succeeds, so that an unavailable issuer is not retried
+	// by every request
+	s.attemptedAt = time.Now()
+
+	document, err := s.read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS from %s: %w", s.source, err)
+	}
+
+	keys, err := parseJWKS(document)
+	if err != nil {
+		return fmt.Errorf("invalid JWKS from %s: %w", s.source, err)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *JWKS) read(ctx context.Context) ([]byte, error) {
+	if !s.remote() {
+		return os.ReadFile(s.source)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return io.ReadAll(io.LimitReader(response.Body, maxJWKSSize))
+}
+
+// Watch refreshes a key set loaded from a URL every interval until the context is done
+func (s *JWKS) Watch(ctx context.Context, interval time.Duration) {
+	if !s.remote() {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Refresh(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to refresh JWKS, keeping the previous keys", "error", err)
+		}
+	}
+}
+
+// key returns the key of the ID, the only key if the ID is empty and there is a single one
+func (s *JWKS) key(ctx context.Context, kid string) (verificationKey, error) {
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+
+	// The issuer may have rotated its keys since the last refresh
+	if s.remote() {
+		s.refreshForUnknownKey(ctx, kid)
+		if key, ok := s.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return verificationKey{}, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// refreshForUnknownKey refreshes the key set unless that was attempted within minJWKSRefresh. A
+// request arriving during a refresh waits for it, and then finds the keys it fetched.
+func (s *JWKS) refreshForUnknownKey(ctx context.Context, kid string) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	if time.Since(s.attemptedAt) < minJWKSRefresh {
+		return
+	}
+	if err := s.refresh(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to refresh JWKS for an unknown key", "kid", kid, "error", err)
+	}
+}
+
+func (s *JWKS) lookup(kid string) (verificationKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// parseJWKS parses the RSA and P-256 signing keys of a JWKS document, skipping keys of other kinds
+func parseJWKS(document []byte) (map[string]verificationKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(document, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		var publicKey crypto.PublicKey
+		var err error
+		switch key.Kty {
+		case "RSA":
+			publicKey, err = key.rsaPublicKey()
+		case "EC":
+			publicKey, err = key.ecdsaPublicKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", key.Kid, err)
+		}
+		keys[key.Kid] = verificationKey{key: publicKey, alg: key.Alg}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA or P-256 signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	if n.BitLen() < 2048 {
+		return nil, fmt.Errorf("RSA keys must have at least 2048 bits, got %d", n.BitLen())
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jwk) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	if k.Crv != "P-256" {
+		return nil, fmt.Errorf("unsupported curve %q, only P-256 is", k.Crv)
+	}
+	x, errX := base64.RawURLEncoding.DecodeString(k.X)
+	y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+	if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+		return nil, errors.New("invalid coordinates")
+	}
+
+	// Parsing the uncompressed point checks it lies on the curve
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, errors.New("the point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms accepted for bearer tokens
+var jwtAlgorithms = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}
+
+// ScopeMapping grants scopes to the tokens whose scope claim holds a value, e.g. a role of the gateway
+type ScopeMapping struct {
+	Claim  string   `mapstructure:"claim"`
+	Scopes []string `mapstructure:"scopes"`
+}
+
+// JWTOptions configure the verification of bearer tokens
+type JWTOptions struct {
+	Issuer     string
+	Audience   string
+	ScopeClaim string         // Claim holding a space-separated string or a list of scopes
+	Mappings   []ScopeMapping // Values of the scope claim granting other scopes
+	Leeway     time.Duration  // Clock skew tolerated when checking expiry
+}
+
+// JWTAuthenticator authenticates RS256 and ES256 signed JWTs against a JWKS. Tokens must be
+// issued by the issuer for the audience and carry an expiry. Values of the scope claim that are
+// scopes of the service are granted as they are, the mappings grant scopes for the others.
+type JWTAuthenticator struct {
+	jwks    *JWKS
+	options JWTOptions
+	parser  *jwt.Parser
+}
+
+// NewJWTAuthenticator creates an authenticator verifying tokens with the keys of the JWKS
+func NewJWTAuthenticator(jwks *JWKS, options JWTOptions) (*JWTAuthenticator, error) {
+	for _, mapping := range options.Mappings {
+		for _, scope := range mapping.Scopes {
+			if !slices.Contains(AllScopes, scope) {
+				return nil, fmt.Errorf("the mapping of %q has unknown scope %q", mapping.Claim, scope)
+			}
+		}
+	}
+	if options.ScopeClaim == "" {
+		options.ScopeClaim = "scope"
+	}
+
+	return &JWTAuthenticator{
+		jwks:    jwks,
+		options: options,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(jwtAlgorithms),
+			jwt.WithIssuer(options.Issuer),
+			jwt.WithAudience(options.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(options.Leeway),
+		),
+	}, nil
+}
+
+// Authenticate verifies the token and returns its subject as principal with the scopes of its claims
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, token string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := a.jwks.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.alg != "" && key.alg != token.Method.Alg() {
+			return nil, fmt.Errorf("key %q is for %s, not %s", kid, key.alg, token.Method.Alg())
+		}
+		return key.key, nil
+	})
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return Principal{}, fmt.Errorf("%w: the token has no subject", ErrInvalidCredentials)
+	}
+	return Principal{Name: subject, Scopes: a.scopes(claims[a.options.ScopeClaim])}, nil
+}
+
+// scopes returns the scopes of the service granted by the values of the scope claim
+func (a *JWTAuthenticator) scopes(claim interface{}) []string {
+	var values []string
+	switch claim := claim.(type) {
+	case string:
+		values = strings.Fields(claim)
+	case []interface{}:
+		for _, value := range claim {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+
+	var scopes []string
+	grant := func(scope string) {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, value := range values {
+		if slices.Contains(AllScopes, value) {
+			grant(value)
+		}
+		for _, mapping := range a.options.Mappings {
+			if mapping.Claim == value {
+				for _, scope := range mapping.Scopes {
+					grant(scope)
+				}
+			}
+		}
+	}
+	return scopes
+}
+
+// BearerOrKey authenticates "Bearer <token>" credentials with the bearer authenticator and any
+// other credentials as API keys. A nil bearer authenticator rejects bearer tokens.
+type BearerOrKey struct {
+	Keys   Authenticator
+	Bearer Authenticator
+}
+
+// Authenticate picks the authenticator for the kind of the credentials
+func (a BearerOrKey) Authenticate(ctx context.Context, credentials string) (Principal, error) {
+	scheme, token, found := strings.Cut(credentials, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return a.Keys.Authenticate(ctx, credentials)
+	}
+	if a.Bearer == nil {
+		return Principal{}, fmt.Errorf("%w: bearer tokens are not accepted", ErrInvalidCredentials)
+	}
+	return a.Bearer.Authenticate(ctx, strings.TrimSpace(token))
+}
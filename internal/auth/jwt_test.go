@@ -0,0 +1,392 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://gateway.test"
+	testAudience = "driver-service"
+)
+
+// signer is a locally generated signing key with the JWK of its public key
+type signer struct {
+	kid    string
+	method jwt.SigningMethod
+	key    crypto.Signer
+}
+
+func newRSASigner(t *testing.T, kid string) signer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	return signer{kid: kid, method: jwt.SigningMethodRS256, key: key}
+}
+
+func newECSigner(t *testing.T, kid string) signer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	return signer{kid: kid, method: jwt.SigningMethodES256, key: key}
+}
+
+func (s signer) jwk(t *testing.T) jwk {
+	t.Helper()
+	encode := base64.RawURLEncoding.EncodeToString
+	switch key := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return jwk{Kid: s.kid, Kty: "RSA", Alg: "RS256", Use: "sig", N: encode(key.N.Bytes()), E: encode(big.NewInt(int64(key.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		point, err := key.ECDH()
+		if err != nil {
+			t.Fatalf("failed to encode EC key: %v", err)
+		}
+		coordinates := point.Bytes()[1:]
+		return jwk{Kid: s.kid, Kty: "EC", Alg: "ES256", Use: "sig", Crv: "P-256", X: encode(coordinates[:32]), Y: encode(coordinates[32:])}
+	}
+	t.Fatalf("unexpected key type %T", s.key)
+	return jwk{}
+}
+
+func (s signer) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(s.method, claims)
+	token.Header["kid"] = s.kid
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func jwksDocument(t *testing.T, signers ...signer) []byte {
+	t.Helper()
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for _, signer := range signers {
+		set.Keys = append(set.Keys, signer.jwk(t))
+	}
+	document, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to encode JWKS: %v", err)
+	}
+	return document
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"sub":   "dispatch-service",
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		"scope": "search:read rides:read",
+	}
+}
+
+func TestJWTAuthenticator_Authenticate(t *testing.T) {
+	rsaSigner := newRSASigner(t, "rsa-1")
+	ecSigner := newECSigner(t, "ec-1")
+	unknownSigner := newRSASigner(t, "rsa-1")
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(t, rsaSigner, ecSigner), 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+	jwks, err := LoadJWKS(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	authenticator, err := NewJWTAuthenticator(jwks, JWTOptions{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		Mappings: []ScopeMapping{{Claim: "dispatcher", Scopes: []string{ScopeRidesRead, ScopeRidesWrite}}},
+		Leeway:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	with := func(modify func(claims jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		modify(claims)
+		return claims
+	}
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedScopes []string
+	}{
+		{name: "RS256", token: rsaSigner.sign(t, validClaims()), expectedScopes: []string{ScopeSearchRead, ScopeRidesRead}},
+		{name: "ES256", token: ecSigner.sign(t, validClaims()), expectedScopes: []string{ScopeSearchRead, ScopeRidesRead}},
+		{
+			name:           "Scope List With Mapping",
+			token:          rsaSigner.sign(t, with(func(claims jwt.MapClaims) { claims["scope"] = []string{"dispatcher", "openid", ScopeRidesRead} })),
+			expectedScopes: []string{ScopeRidesRead, ScopeRidesWrite},
+		},
+		{
+			name:           "Expired Within Leeway",
+			token:          rsaSigner.sign(t, with(func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-30 * time.Second).Unix() })),
+			expectedScopes: []string{ScopeSearchRead, ScopeRidesRead},
+		},
+		{name: "Wrong Issuer", token: rsaSigner.sign(t, with(func(claims jwt.MapClaims) { claims["iss"] = "https://other.test" }))},
+		{name: "Wrong Audience", token: rsaSigner.sign(t, with(func(claims jwt.MapClaims) { claims["aud"] = []string{"billing-service"} }))},
+		{name: "Expired", token: rsaSigner.sign(t, with(func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }))},
+		{name: "No Expiry", token: rsaSigner.sign(t, with(func(claims jwt.MapClaims) { delete(claims, "exp") }))},
+		{name: "No Subject", token: rsaSigner.sign(t, with(func(claims jwt.MapClaims) { delete(claims, "sub") }))},
+		{name: "Unknown Key", token: unknownSigner.sign(t, validClaims())},
+		{name: "Unknown Kid", token: newECSigner(t, "ec-2").sign(t, validClaims())},
+		{name: "HS256", token: hmacToken},
+		{name: "Malformed", token: "not.a.token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(context.Background(), tt.token)
+			if tt.expectedScopes == nil {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("expected the token to be rejected, got %+v, %v", principal, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if principal.Name != "dispatch-service" {
+				t.Errorf("expected principal dispatch-service, got %q", principal.Name)
+			}
+			if len(principal.Scopes) != len(tt.expectedScopes) {
+				t.Fatalf("expected scopes %v, got %v", tt.expectedScopes, principal.Scopes)
+			}
+			for _, scope := range tt.expectedScopes {
+				if !principal.HasScope(scope) {
+					t.Errorf("expected scopes %v, got %v", tt.expectedScopes, principal.Scopes)
+				}
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticator_AlgorithmMismatch(t *testing.T) {
+	// A JWK for RS256 must not verify a token claiming another algorithm, even one accepted elsewhere
+	ecSigner := newECSigner(t, "key-1")
+	key := ecSigner.jwk(t)
+	key.Alg = "RS256"
+	document, err := json.Marshal(map[string][]jwk{"keys": {key}})
+	if err != nil {
+		t.Fatalf("failed to encode JWKS: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, document, 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+
+	jwks, err := LoadJWKS(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	authenticator, err := NewJWTAuthenticator(jwks, JWTOptions{Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := authenticator.Authenticate(context.Background(), ecSigner.sign(t, validClaims())); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected the token to be rejected, got %v", err)
+	}
+}
+
+func TestJWKS_RefreshOnUnknownKey(t *testing.T) {
+	oldSigner := newRSASigner(t, "2026-01")
+	newSigner := newECSigner(t, "2026-02")
+
+	var mu sync.Mutex
+	document := jwksDocument(t, oldSigner)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(document)
+	}))
+	defer server.Close()
+
+	jwks, err := LoadJWKS(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	authenticator, err := NewJWTAuthenticator(jwks, JWTOptions{Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The issuer rotates to a new key
+	mu.Lock()
+	document = jwksDocument(t, oldSigner, newSigner)
+	mu.Unlock()
+	token := newSigner.sign(t, validClaims())
+
+	if _, err := authenticator.Authenticate(context.Background(), token); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected no refresh within %v of the last one, got %v", minJWKSRefresh, err)
+	}
+
+	jwks.refreshMu.Lock()
+	jwks.attemptedAt = time.Now().Add(-minJWKSRefresh)
+	jwks.refreshMu.Unlock()
+	if _, err := authenticator.Authenticate(context.Background(), token); err != nil {
+		t.Errorf("expected the new key to be fetched, got %v", err)
+	}
+}
+
+func TestJWKS_RefreshThrottledWhileIssuerDown(t *testing.T) {
+	signer := newRSASigner(t, "2026-01")
+
+	var fetches atomic.Int32
+	down := atomic.Bool{}
+	document := jwksDocument(t, signer)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if down.Load() {
+			time.Sleep(50 * time.Millisecond)
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(document)
+	}))
+	defer server.Close()
+
+	jwks, err := LoadJWKS(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	authenticator, err := NewJWTAuthenticator(jwks, JWTOptions{Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	down.Store(true)
+	jwks.refreshMu.Lock()
+	jwks.attemptedAt = time.Now().Add(-minJWKSRefresh)
+	jwks.refreshMu.Unlock()
+
+	// Concurrent tokens of unknown keys share one failed fetch, and later ones do not fetch again
+	token := newECSigner(t, "2026-02").sign(t, validClaims())
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := authenticator.Authenticate(context.Background(), token); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("expected the token to be rejected, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := authenticator.Authenticate(context.Background(), token); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected the token to be rejected, got %v", err)
+	}
+
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("expected the load and a single refresh, got %d fetches", got)
+	}
+	if _, err := authenticator.Authenticate(context.Background(), signer.sign(t, validClaims())); err != nil {
+		t.Errorf("expected the known key to keep working, got %v", err)
+	}
+}
+
+func TestParseJWKS_Invalid(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		document []byte
+	}{
+		{name: "Not JSON", document: []byte("keys")},
+		{name: "No Keys", document: []byte(`{"keys":[]}`)},
+		{name: "Weak RSA Key", document: jwksDocument(t, signer{kid: "weak", method: jwt.SigningMethodRS256, key: weak})},
+		{name: "P-384 Key", document: []byte(`{"keys":[{"kid":"p384","kty":"EC","crv":"P-384","x":"` +
+			base64.RawURLEncoding.EncodeToString(p384.X.Bytes()) + `","y":"` + base64.RawURLEncoding.EncodeToString(p384.Y.Bytes()) + `"}]}`)},
+		{name: "Point Off The Curve", document: []byte(`{"keys":[{"kid":"ec","kty":"EC","crv":"P-256","x":"` +
+			base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `","y":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `"}]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseJWKS(tt.document); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestBearerOrKey_Authenticate(t *testing.T) {
+	keys, err := NewKeyStore(Key{Name: "import-job", Hash: HashKey("import-key"), Scopes: []string{ScopeImportWrite}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	principal, err := BearerOrKey{Keys: keys}.Authenticate(context.Background(), "import-key")
+	if err != nil || principal.Name != "import-job" {
+		t.Errorf("expected the API key to authenticate, got %+v, %v", principal, err)
+	}
+	if _, err := (BearerOrKey{Keys: keys}).Authenticate(context.Background(), "Bearer import-key"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected bearer tokens to be rejected without a bearer authenticator, got %v", err)
+	}
+
+	signer := newECSigner(t, "ec-1")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(t, signer), 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+	jwks, err := LoadJWKS(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bearer, err := NewJWTAuthenticator(jwks, JWTOptions{Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	principal, err = BearerOrKey{Keys: keys, Bearer: bearer}.Authenticate(context.Background(), "bearer "+signer.sign(t, validClaims()))
+	if err != nil || principal.Name != "dispatch-service" || !principal.HasScope(ScopeSearchRead) {
+		t.Errorf("expected the token to authenticate, got %+v, %v", principal, err)
+	}
+}
+
+func TestNewJWTAuthenticator_UnknownMappedScope(t *testing.T) {
+	_, err := NewJWTAuthenticator(&JWKS{}, JWTOptions{Mappings: []ScopeMapping{{Claim: "admin", Scopes: []string{"drivers:delete"}}}})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// hashPrefix marks the algorithm of stored key hashes
+const hashPrefix = "sha256:"
+
+// Key is an API key as stored: its name, the hash of the key and the scopes it grants
+type Key struct {
+	Name   string   `mapstructure:"name"`
+	Hash   string   `mapstructure:"hash"`
+	Scopes []string `mapstructure:"scopes"`
+}
+
+// GenerateKey returns a new random API key
+func GenerateKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// HashKey returns the hash stored for an API key. Keys are random and long, so a fast unsalted
+// hash does not make them guessable, and it keeps authenticating every request cheap.
+func HashKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// storedKey is a validated key with its decoded hash
+type storedKey struct {
+	name   string
+	hash   []byte
+	scopes []string
+}
+
+func parseKeys(keys []Key) ([]storedKey, error) {
+	names := make(map[string]bool, len(keys))
+	parsed := make([]storedKey, 0, len(keys))
+	for i, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("key %d has no name", i+1)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("key %s is defined twice", key.Name)
+		}
+		names[key.Name] = true
+
+		hash, err := hex.DecodeString(strings.TrimPrefix(key.Hash, hashPrefix))
+		if !strings.HasPrefix(key.Hash, hashPrefix) || err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("key %s must have a hash of the form %s<64 hex digits>", key.Name, hashPrefix)
+		}
+		for _, scope := range key.Scopes {
+			if !slices.Contains(AllScopes, scope) {
+				return nil, fmt.Errorf("key %s has unknown scope %q", key.Name, scope)
+			}
+		}
+
+		parsed = append(parsed, storedKey{name: key.Name, hash: hash, scopes: key.Scopes})
+	}
+	return parsed, nil
+}
+
+// KeyStore authenticates API keys against their hashes. Keys read from a file can be replaced at
+// any time, so keys are added and revoked without restarting the service.
+type KeyStore struct {
+	static []storedKey
+
+	mu      sync.RWMutex
+	keys    []storedKey
+	version string // modification time and size of the key file when it was loaded
+}
+
+// NewKeyStore creates a store of the static keys, which stay when the key file is reloaded
+func NewKeyStore(static ...Key) (*KeyStore, error) {
+	parsed, err := parseKeys(static)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyStore{static: parsed, keys: parsed}, nil
+}
+
+// LoadFile replaces the keys read from a file before with those of the YAML file at the path. The
+// keys are kept as they are if the file is invalid.
+func (s *KeyStore) LoadFile(path string) error {
+	// Before reading, so that a change while reading is loaded again by Watch
+	version := modified(path)
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read API key file: %w", err)
+	}
+
+	var file struct {
+		Keys []Key `mapstructure:"keys"`
+	}
+	if err := v.Unmarshal(&file); err != nil {
+		return fmt.Errorf("failed to decode API key file: %w", err)
+	}
+
+	parsed, err := parseKeys(append(s.staticKeys(), file.Keys...))
+	if err != nil {
+		return fmt.Errorf("invalid API key file: %w", err)
+	}
+
+	s.mu.Lock()
+	s.keys = parsed
+	s.version = version
+	s.mu.Unlock()
+	return nil
+}
+
+// staticKeys returns the static keys in their stored form, so that names in the file cannot clash with them
+func (s *KeyStore) staticKeys() []Key {
+	keys := make([]Key, len(s.static))
+	for i, key := range s.static {
+		keys[i] = Key{Name: key.name, Hash: hashPrefix + hex.EncodeToString(key.hash), Scopes: key.scopes}
+	}
+	return keys
+}
+
+// Watch reloads the key file whenever it changes until the context is done, checking every interval
+func (s *KeyStore) Watch(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failed := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := modified(path)
+		s.mu.RLock()
+		changed := current != s.version
+		s.mu.RUnlock()
+		if !changed || current == failed {
+			continue
+		}
+
+		if err := s.LoadFile(path); err != nil {
+			// Not retried until the file changes again, so the error is logged once
+			failed = current
+			slog.ErrorContext(ctx, "Failed to reload API keys, keeping the previous ones", "path", path, "error", err)
+			continue
+		}
+		slog.InfoContext(ctx, "Reloaded API keys", "path", path, "keys", s.Len())
+	}
+}
+
+// modified returns the modification time and size of the file, which change when it is rewritten
+func modified(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// Len returns the number of keys
+func (s *KeyStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// Authenticate returns the principal of the API key. Its hash is compared with every stored hash
+// in constant time, so response times reveal nothing about how close a guess was.
+func (s *KeyStore) Authenticate(ctx context.Context, apiKey string) (Principal, error) {
+	sum := sha256.Sum256([]byte(apiKey))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var match *storedKey
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare(sum[:], s.keys[i].hash) == 1 {
+			match = &s.keys[i]
+		}
+	}
+	if apiKey == "" || match == nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Name: match.name, Scopes: match.scopes}, nil
+}
+
+// LegacyKeyName names the key of the server.api_key setting
+const LegacyKeyName = "default"
+
+// LegacyKey is the single key of the server.api_key setting, granted every scope as it was before
+// keys had scopes
+func LegacyKey(apiKey string) Key {
+	return Key{Name: LegacyKeyName, Hash: HashKey(apiKey), Scopes: AllScopes}
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeKeyFile(t *testing.T, path string, keys ...Key) {
+	t.Helper()
+
+	var content strings.Builder
+	content.WriteString("keys:\n")
+	for _, key := range keys {
+		content.WriteString("  - name: " + key.Name + "\n    hash: " + key.Hash + "\n    scopes: [" + strings.Join(key.Scopes, ", ") + "]\n")
+	}
+	if err := os.WriteFile(path, []byte(content.String()), 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+}
+
+func TestKeyStore_Authenticate(t *testing.T) {
+	keys, err := NewKeyStore(
+		LegacyKey("legacy-key"),
+		Key{Name: "dispatch-service", Hash: HashKey("dispatch-key"), Scopes: []string{ScopeSearchRead, ScopeRidesWrite}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	principal, err := keys.Authenticate(context.Background(), "dispatch-key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if principal.Name != "dispatch-service" || !principal.HasScope(ScopeSearchRead) || principal.HasScope(ScopeImportWrite) {
+		t.Errorf("unexpected principal %+v", principal)
+	}
+
+	principal, err = keys.Authenticate(context.Background(), "legacy-key")
+	if err != nil || principal.Name != LegacyKeyName || len(principal.Scopes) != len(AllScopes) {
+		t.Errorf("expected the legacy key with every scope, got %+v, %v", principal, err)
+	}
+
+	for _, invalid := range []string{"", "dispatch-ke", HashKey("dispatch-key")} {
+		if _, err := keys.Authenticate(context.Background(), invalid); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("expected %q to be rejected, got %v", invalid, err)
+		}
+	}
+}
+
+func TestNewKeyStore_Invalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		keys          []Key
+		expectedError string
+	}{
+		{name: "Missing Name", keys: []Key{{Hash: HashKey("a")}}, expectedError: "key 1 has no name"},
+		{name: "Duplicate Name", keys: []Key{{Name: "a", Hash: HashKey("a")}, {Name: "a", Hash: HashKey("b")}}, expectedError: "key a is defined twice"},
+		{name: "Plain Key", keys: []Key{{Name: "a", Hash: "secret"}}, expectedError: "key a must have a hash of the form sha256:"},
+		{name: "Unknown Scope", keys: []Key{{Name: "a", Hash: HashKey("a"), Scopes: []string{"admin"}}}, expectedError: `key a has unknown scope "admin"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyStore(tt.keys...); err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("expected error %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestKeyStore_LoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.yaml")
+	writeKeyFile(t, path, Key{Name: "importer", Hash: HashKey("import-key"), Scopes: []string{ScopeImportWrite}})
+
+	keys, err := NewKeyStore(LegacyKey("legacy-key"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := keys.LoadFile(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if principal, err := keys.Authenticate(context.Background(), "import-key"); err != nil || !principal.HasScope(ScopeImportWrite) {
+		t.Errorf("expected the key of the file, got %+v, %v", principal, err)
+	}
+
+	// An invalid file keeps the previous keys
+	writeKeyFile(t, path, Key{Name: "importer", Hash: "import-key"})
+	if err := keys.LoadFile(path); err == nil {
+		t.Fatal("expected an error for a key without hash")
+	}
+	if _, err := keys.Authenticate(context.Background(), "import-key"); err != nil {
+		t.Errorf("expected the previous keys to be kept, got %v", err)
+	}
+
+	// Static keys stay when keys of the file are revoked
+	writeKeyFile(t, path)
+	if err := keys.LoadFile(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := keys.Authenticate(context.Background(), "import-key"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected the revoked key to be rejected, got %v", err)
+	}
+	if _, err := keys.Authenticate(context.Background(), "legacy-key"); err != nil {
+		t.Errorf("expected the static key to stay, got %v", err)
+	}
+}
+
+func TestKeyStore_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.yaml")
+	writeKeyFile(t, path)
+
+	keys, err := NewKeyStore()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := keys.LoadFile(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go keys.Watch(ctx, path, 10*time.Millisecond)
+
+	writeKeyFile(t, path, Key{Name: "importer", Hash: HashKey("import-key"), Scopes: []string{ScopeImportWrite}})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := keys.Authenticate(context.Background(), "import-key"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the added key to be loaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
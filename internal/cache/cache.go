@@ -0,0 +1,196 @@
+// Package cache serves nearest-driver searches from a geospatial read cache in front of the
+// storage backend, keeping it up to date by writing driver changes through to it.
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/storage"
+)
+
+// MaxLatitude is the largest absolute latitude a DriverCache can index, the limit of Redis GEO
+// commands. Drivers beyond it are only served by the storage backend.
+const MaxLatitude = 85.05112878
+
+// DriverCache holds the positions of drivers and which of them are available
+type DriverCache interface {
+	// Fill stores the drivers returned by load, holding the reserved ones, if the cache holds no
+	// driver. The drivers replace the contents of the cache at once, and only if no other process
+	// filled it meanwhile. Fill reports whether it filled the cache.
+	Fill(ctx context.Context, load func(ctx context.Context) ([]models.DriverWithDistance, error)) (bool, error)
+	// AddDrivers stores the drivers at their locations, as available unless the cache holds them
+	AddDrivers(ctx context.Context, drivers []models.DriverWithDistance) error
+	// MoveDriver updates the position of a driver, keeping their availability
+	MoveDriver(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error
+	// HoldDriver makes a driver unavailable until the given time, or until freed if it is zero
+	HoldDriver(ctx context.Context, id primitive.ObjectID, until time.Time) error
+	// FreeDriver makes a held driver available again
+	FreeDriver(ctx context.Context, id primitive.ObjectID) error
+	// ForgetDrivers removes drivers, so that searches no longer find them in the cache
+	ForgetDrivers(ctx context.Context, ids ...primitive.ObjectID) error
+	// FindNearestDrivers returns the available drivers within radius meters, nearest first
+	FindNearestDrivers(ctx context.Context, latitude, longitude float64, radius, limit int) ([]models.DriverWithDistance, error)
+}
+
+// Repository serves nearest-driver searches from a DriverCache and falls back to the storage
+// backend when the cache has fewer drivers in range than asked for or fails. Writes go to the storage backend first
+// and are then written through to the cache; reservations are always decided by the backend. Once
+// the backend accepted a write it has happened, so a failure to write it through only evicts the
+// drivers concerned from the cache instead of failing the write.
+type Repository struct {
+	storage.DriverStore
+	cache DriverCache
+}
+
+func NewRepository(store storage.DriverStore, cache DriverCache) *Repository {
+	return &Repository{DriverStore: store, cache: cache}
+}
+
+// Warm fills an empty cache with the drivers of the storage backend. A cache that is already filled
+// is kept up to date by the replicas writing through it, so it is left alone: rebuilding it would
+// lose the changes written through while the drivers are listed.
+func (r *Repository) Warm(ctx context.Context) error {
+	var count int
+	filled, err := r.cache.Fill(ctx, func(ctx context.Context) ([]models.DriverWithDistance, error) {
+		drivers, err := r.DriverStore.ListDrivers(ctx)
+		count = len(drivers)
+		return drivers, err
+	})
+	if err != nil {
+		return err
+	}
+
+	if filled {
+		slog.InfoContext(ctx, "Cached driver locations", "drivers", count)
+	} else {
+		slog.InfoContext(ctx, "Driver cache already filled, keeping it")
+	}
+	return nil
+}
+
+// SaveDrivers assigns the IDs of new drivers up front, so they can be cached once they are stored
+func (r *Repository) SaveDrivers(ctx context.Context, locations []models.DriverWithDistance) error {
+	drivers := make([]models.DriverWithDistance, len(locations))
+	copy(drivers, locations)
+	for i := range drivers {
+		if drivers[i].ID.IsZero() {
+			drivers[i].ID = primitive.NewObjectID()
+		}
+	}
+
+	if err := r.DriverStore.SaveDrivers(ctx, drivers); err != nil {
+		return err
+	}
+	if err := r.cache.AddDrivers(ctx, drivers); err != nil {
+		ids := make([]primitive.ObjectID, len(drivers))
+		for i, driver := range drivers {
+			ids[i] = driver.ID
+		}
+		r.evict(ctx, "SaveDrivers", err, ids...)
+	}
+	return nil
+}
+
+func (r *Repository) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	if err := r.DriverStore.UpdateDriverLocation(ctx, id, latitude, longitude); err != nil {
+		return err
+	}
+	if err := r.cache.MoveDriver(ctx, id, latitude, longitude); err != nil {
+		r.evict(ctx, "UpdateDriverLocation", err, id)
+	}
+	return nil
+}
+
+func (r *Repository) FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
+	drivers, err := r.FindNearestDrivers(ctx, latitude, longitude, radius, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &drivers[0], nil
+}
+
+func (r *Repository) FindNearestDrivers(ctx context.Context, latitude, longitude float64, radius, limit int) ([]models.DriverWithDistance, error) {
+	if !cacheable(latitude, radius) {
+		return r.DriverStore.FindNearestDrivers(ctx, latitude, longitude, radius, limit)
+	}
+
+	drivers, err := r.cache.FindNearestDrivers(ctx, latitude, longitude, radius, limit)
+	if err != nil {
+		slog.WarnContext(ctx, "Driver cache search failed, falling back to storage", "error", err)
+	}
+	if len(drivers) < limit {
+		// The cache may miss evicted drivers, which the backend finds
+		return r.DriverStore.FindNearestDrivers(ctx, latitude, longitude, radius, limit)
+	}
+	return drivers, nil
+}
+
+func (r *Repository) ReserveDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	if err := r.DriverStore.ReserveDriver(ctx, id, reservationID); err != nil {
+		return err
+	}
+	if err := r.cache.HoldDriver(ctx, id, time.Time{}); err != nil {
+		r.evict(ctx, "ReserveDriver", err, id)
+	}
+	return nil
+}
+
+func (r *Repository) ClaimNearestDriver(ctx context.Context, latitude, longitude float64, radius int, ttl time.Duration, reservationID string) (*models.DriverWithDistance, error) {
+	driver, err := r.DriverStore.ClaimNearestDriver(ctx, latitude, longitude, radius, ttl, reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var until time.Time
+	if driver.ReservedUntil != nil {
+		until = *driver.ReservedUntil
+	}
+	if err := r.cache.HoldDriver(ctx, driver.ID, until); err != nil {
+		r.evict(ctx, "ClaimNearestDriver", err, driver.ID)
+	}
+	return driver, nil
+}
+
+func (r *Repository) ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	if err := r.DriverStore.ConfirmReservation(ctx, id, reservationID); err != nil {
+		return err
+	}
+	if err := r.cache.HoldDriver(ctx, id, time.Time{}); err != nil {
+		r.evict(ctx, "ConfirmReservation", err, id)
+	}
+	return nil
+}
+
+func (r *Repository) ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	if err := r.DriverStore.ReleaseDriver(ctx, id, reservationID); err != nil {
+		return err
+	}
+	if err := r.cache.FreeDriver(ctx, id); err != nil {
+		r.evict(ctx, "ReleaseDriver", err, id)
+	}
+	return nil
+}
+
+// evict removes drivers whose change could not be written through from the cache, so that it
+// does not serve them with their previous location or availability
+func (r *Repository) evict(ctx context.Context, operation string, err error, ids ...primitive.ObjectID) {
+	slog.ErrorContext(ctx, "Failed to write driver change through to the cache, evicting the drivers",
+		"operation", operation, "drivers", len(ids), "error", err)
+	if err := r.cache.ForgetDrivers(ctx, ids...); err != nil {
+		slog.ErrorContext(ctx, "Failed to evict drivers from the cache, it may serve them stale until warmed again",
+			"operation", operation, "drivers", len(ids), "error", err)
+	}
+}
+
+// cacheable reports whether a search circle lies entirely within the latitudes a DriverCache indexes
+func cacheable(latitude float64, radius int) bool {
+	angularRadius := float64(radius) / geo.EarthRadius * 180 / math.Pi
+	return math.Abs(latitude)+angularRadius <= MaxLatitude
+}
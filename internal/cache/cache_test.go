@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+	"bitaksi-go-driver/internal/repository/repositorytest"
+)
+
+// newTestCache returns a cache in an in-process Redis stand-in
+func newTestCache(t *testing.T) (*RedisDriverCache, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisDriverCache(client, "test"), server
+}
+
+func driverAt(latitude, longitude float64) models.DriverWithDistance {
+	return models.DriverWithDistance{Location: models.Location{Type: "Point", Coordinates: []float64{longitude, latitude}}}
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) (repositorytest.DriverRepository, repositorytest.Setup) {
+		cache, _ := newTestCache(t)
+		return NewRepository(repository.NewMemoryDriverRepository(), cache), nil
+	})
+}
+
+func TestRepository_Warm(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryDriverRepository()
+	if err := store.SaveDrivers(ctx, []models.DriverWithDistance{driverAt(41, 29), driverAt(41.001, 29)}); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+	reserved, err := store.FindNearestDriver(ctx, 41, 29, 100)
+	if err != nil {
+		t.Fatalf("FindNearestDriver failed: %v", err)
+	}
+	if err := store.ReserveDriver(ctx, reserved.ID, "r1"); err != nil {
+		t.Fatalf("ReserveDriver failed: %v", err)
+	}
+
+	cache, server := newTestCache(t)
+	if err := NewRepository(store, cache).Warm(ctx); err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+
+	// The reserved driver is cached, but not available
+	drivers, err := cache.FindNearestDrivers(ctx, 41, 29, 1000, 5)
+	if err != nil {
+		t.Fatalf("FindNearestDrivers failed: %v", err)
+	}
+	if len(drivers) != 1 || drivers[0].Location.Coordinates[1] != 41.001 {
+		t.Errorf("expected only the available driver, got %+v", drivers)
+	}
+	for _, key := range server.Keys() {
+		if strings.Contains(key, ":staging:") {
+			t.Errorf("expected no staged key to be left, got %s", key)
+		}
+	}
+	if ttl := server.TTL("test:locations"); ttl != 0 {
+		t.Errorf("expected the cache not to expire, got a TTL of %v", ttl)
+	}
+}
+
+func TestRepository_WarmKeepsFilledCache(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryDriverRepository()
+	cache, server := newTestCache(t)
+	repo := NewRepository(store, cache)
+	if err := repo.SaveDrivers(ctx, []models.DriverWithDistance{driverAt(41, 29)}); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+	claimed, err := repo.ClaimNearestDriver(ctx, 41, 29, 100, time.Minute, "r1")
+	if err != nil {
+		t.Fatalf("ClaimNearestDriver failed: %v", err)
+	}
+
+	// Another replica starting must neither drop the hold nor bring back drivers the cache moved on from
+	if err := store.UpdateDriverLocation(ctx, claimed.ID, 50, 29); err != nil {
+		t.Fatalf("UpdateDriverLocation failed: %v", err)
+	}
+	if err := NewRepository(store, cache).Warm(ctx); err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if drivers, _ := cache.FindNearestDrivers(ctx, 41, 29, 100, 5); len(drivers) != 0 {
+		t.Errorf("expected the held driver to stay held, got %+v", drivers)
+	}
+	if drivers, _ := cache.FindNearestDrivers(ctx, 50, 29, 100, 5); len(drivers) != 0 {
+		t.Errorf("expected the cache to be left alone, got %+v", drivers)
+	}
+	for _, key := range server.Keys() {
+		if strings.Contains(key, ":staging:") {
+			t.Errorf("expected no staged key to be left, got %s", key)
+		}
+	}
+}
+
+func TestRepository_WritesThrough(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestCache(t)
+	repo := NewRepository(repository.NewMemoryDriverRepository(), cache)
+
+	if err := repo.SaveDrivers(ctx, []models.DriverWithDistance{driverAt(41, 29)}); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+	drivers, err := cache.FindNearestDrivers(ctx, 41, 29, 100, 5)
+	if err != nil || len(drivers) != 1 {
+		t.Fatalf("expected the saved driver to be cached, got %+v, %v", drivers, err)
+	}
+	id := drivers[0].ID
+
+	claimed, err := repo.ClaimNearestDriver(ctx, 41, 29, 100, time.Minute, "r1")
+	if err != nil || claimed.ID != id {
+		t.Fatalf("expected the driver to be claimed, got %+v, %v", claimed, err)
+	}
+	if drivers, _ := cache.FindNearestDrivers(ctx, 41, 29, 100, 5); len(drivers) != 0 {
+		t.Errorf("expected the claimed driver to be unavailable in the cache, got %+v", drivers)
+	}
+
+	if err := repo.ReleaseDriver(ctx, id, "r1"); err != nil {
+		t.Fatalf("ReleaseDriver failed: %v", err)
+	}
+	if err := repo.UpdateDriverLocation(ctx, id, 41.01, 29); err != nil {
+		t.Fatalf("UpdateDriverLocation failed: %v", err)
+	}
+	drivers, err = cache.FindNearestDrivers(ctx, 41.01, 29, 100, 5)
+	if err != nil || len(drivers) != 1 || drivers[0].ID != id {
+		t.Errorf("expected the released driver at their new location, got %+v, %v", drivers, err)
+	}
+}
+
+// failingHolds is a cache whose holds cannot be written
+type failingHolds struct {
+	*RedisDriverCache
+}
+
+func (c failingHolds) HoldDriver(ctx context.Context, id primitive.ObjectID, until time.Time) error {
+	return errors.New("connection reset")
+}
+
+func TestRepository_EvictsOnFailedWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestCache(t)
+	repo := NewRepository(repository.NewMemoryDriverRepository(), failingHolds{cache})
+
+	if err := repo.SaveDrivers(ctx, []models.DriverWithDistance{driverAt(41, 29), driverAt(41.001, 29)}); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+
+	// The claim succeeded in the backend, so it is not failed, but the cache must not offer the driver
+	claimed, err := repo.ClaimNearestDriver(ctx, 41, 29, 1000, time.Minute, "r1")
+	if err != nil {
+		t.Fatalf("expected the claim to succeed, got %v", err)
+	}
+	drivers, err := cache.FindNearestDrivers(ctx, 41, 29, 1000, 5)
+	if err != nil {
+		t.Fatalf("FindNearestDrivers failed: %v", err)
+	}
+	if len(drivers) != 1 || drivers[0].ID == claimed.ID {
+		t.Errorf("expected only the unclaimed driver in the cache, got %+v", drivers)
+	}
+}
+
+func TestRepository_FallsBackToStorage(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryDriverRepository()
+	cache, server := newTestCache(t)
+	repo := NewRepository(store, cache)
+
+	// Saved behind the back of the cache, so searching it misses
+	if err := store.SaveDrivers(ctx, []models.DriverWithDistance{driverAt(41, 29)}); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+	if _, err := repo.FindNearestDriver(ctx, 41, 29, 100); err != nil {
+		t.Errorf("expected the driver from storage on a cache miss, got %v", err)
+	}
+
+	server.Close()
+	if _, err := repo.FindNearestDriver(ctx, 41, 29, 100); err != nil {
+		t.Errorf("expected the driver from storage when the cache is down, got %v", err)
+	}
+}
+
+func TestRepository_FallsBackOnPartialResults(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryDriverRepository()
+	cache, _ := newTestCache(t)
+	repo := NewRepository(store, cache)
+
+	// One driver is cached, the nearer one is missing from the cache like an evicted driver
+	if err := repo.SaveDrivers(ctx, []models.DriverWithDistance{driverAt(41.001, 29)}); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+	if err := store.SaveDrivers(ctx, []models.DriverWithDistance{driverAt(41, 29)}); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+
+	drivers, err := repo.FindNearestDrivers(ctx, 41, 29, 1000, 2)
+	if err != nil {
+		t.Fatalf("FindNearestDrivers failed: %v", err)
+	}
+	if len(drivers) != 2 || drivers[0].Location.Coordinates[1] != 41 {
+		t.Errorf("expected both drivers from storage, nearest first, got %+v", drivers)
+	}
+}
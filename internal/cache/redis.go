@@ -0,0 +1,291 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// searchSlack is the number of extra drivers fetched per search. Redis stores positions as 52 bit
+// geohashes and uses a smaller earth radius, so its order can differ from the exact one for drivers
+// within centimeters of each other; the extra drivers are re-ranked by their exact distance.
+const searchSlack = 8
+
+// addDrivers stores the exact locations and indexes the drivers unless they are held
+var addDrivers = redis.NewScript(`
+for i = 1, #ARGV, 3 do
+	local id = ARGV[i + 2]
+	redis.call('HSET', KEYS[1], id, ARGV[i] .. ',' .. ARGV[i + 1])
+	if not redis.call('ZSCORE', KEYS[3], id) then
+		redis.call('GEOADD', KEYS[2], ARGV[i], ARGV[i + 1], id)
+	end
+end
+return #ARGV / 3`)
+
+// moveDriver updates the exact location and, unless the driver is held, the indexed position
+var moveDriver = redis.NewScript(`
+redis.call('HSET', KEYS[1], ARGV[3], ARGV[1] .. ',' .. ARGV[2])
+if not redis.call('ZSCORE', KEYS[3], ARGV[3]) then
+	redis.call('GEOADD', KEYS[2], ARGV[1], ARGV[2], ARGV[3])
+end
+return 0`)
+
+// freeDrivers indexes the drivers again and forgets their holds
+var freeDrivers = redis.NewScript(`
+for _, id in ipairs(ARGV) do
+	local location = redis.call('HGET', KEYS[1], id)
+	if location then
+		local longitude, latitude = string.match(location, '([^,]+),([^,]+)')
+		redis.call('GEOADD', KEYS[2], longitude, latitude, id)
+	end
+	redis.call('ZREM', KEYS[3], id)
+end
+return #ARGV`)
+
+// fill moves the keys of a staged cache to those of the cache unless it holds drivers by now
+var fill = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('DEL', KEYS[4], KEYS[5], KEYS[6])
+	return 0
+end
+for i = 1, 3 do
+	if redis.call('EXISTS', KEYS[i + 3]) == 1 then
+		redis.call('RENAME', KEYS[i + 3], KEYS[i])
+		redis.call('PERSIST', KEYS[i])
+	else
+		redis.call('DEL', KEYS[i])
+	end
+end
+return 1`)
+
+// stagingTTL bounds how long the keys of a staged cache outlive a process dying while filling it
+const stagingTTL = 10 * time.Minute
+
+// RedisDriverCache is a DriverCache in a Redis compatible store supporting GEOADD and GEOSEARCH.
+// The exact location of every driver is kept in a hash, the available drivers in a geo set and the
+// holds in a sorted set scored by their expiry, +inf for holds lasting until the driver is freed;
+// expired holds are freed before each search. A driver is available unless it is held, so drivers
+// evicted from the cache are indexed again by their next location update or import.
+type RedisDriverCache struct {
+	client    redis.UniversalClient
+	locations string
+	available string
+	holds     string
+	now       func() time.Time
+}
+
+func NewRedisDriverCache(client redis.UniversalClient, prefix string) *RedisDriverCache {
+	return &RedisDriverCache{
+		client:    client,
+		locations: prefix + ":locations",
+		available: prefix + ":available",
+		holds:     prefix + ":holds",
+		now:       time.Now,
+	}
+}
+
+func (c *RedisDriverCache) Fill(ctx context.Context, load func(ctx context.Context) ([]models.DriverWithDistance, error)) (bool, error) {
+	if filled, err := c.client.Exists(ctx, c.locations).Result(); err != nil || filled > 0 {
+		return false, err
+	}
+	drivers, err := load(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// Staged under other keys, so the cache is never seen half filled and is moved in at once
+	staged := *c
+	suffix := ":staging:" + primitive.NewObjectID().Hex()
+	staged.locations, staged.available, staged.holds = c.locations+suffix, c.available+suffix, c.holds+suffix
+	stagedKeys := []string{staged.locations, staged.available, staged.holds}
+	defer c.client.Del(context.WithoutCancel(ctx), stagedKeys...)
+
+	if err := staged.AddDrivers(ctx, drivers); err != nil {
+		return false, err
+	}
+	for _, driver := range drivers {
+		if driver.Status != models.DriverStatusReserved {
+			continue
+		}
+
+		var until time.Time
+		if driver.ReservedUntil != nil {
+			until = *driver.ReservedUntil
+		}
+		if err := staged.HoldDriver(ctx, driver.ID, until); err != nil {
+			return false, err
+		}
+	}
+
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range stagedKeys {
+			pipe.Expire(ctx, key, stagingTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	filled, err := fill.Run(ctx, c.client, []string{c.locations, c.available, c.holds, staged.locations, staged.available, staged.holds}).Int()
+	return filled == 1, err
+}
+
+func (c *RedisDriverCache) AddDrivers(ctx context.Context, drivers []models.DriverWithDistance) error {
+	args := make([]interface{}, 0, 3*len(drivers))
+	for _, driver := range drivers {
+		latitude, longitude := driver.Location.Coordinates[1], driver.Location.Coordinates[0]
+		if latitude < -MaxLatitude || latitude > MaxLatitude {
+			continue
+		}
+		args = append(args, strconv.FormatFloat(longitude, 'g', -1, 64), strconv.FormatFloat(latitude, 'g', -1, 64), driver.ID.Hex())
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	return addDrivers.Run(ctx, c.client, []string{c.locations, c.available, c.holds}, args...).Err()
+}
+
+func (c *RedisDriverCache) MoveDriver(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	if latitude < -MaxLatitude || latitude > MaxLatitude {
+		// The driver left the indexable latitudes, searches there are served by the storage backend
+		return c.ForgetDrivers(ctx, id)
+	}
+
+	return moveDriver.Run(ctx, c.client, []string{c.locations, c.available, c.holds},
+		strconv.FormatFloat(longitude, 'g', -1, 64), strconv.FormatFloat(latitude, 'g', -1, 64), id.Hex()).Err()
+}
+
+func (c *RedisDriverCache) HoldDriver(ctx context.Context, id primitive.ObjectID, until time.Time) error {
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		score := math.Inf(1)
+		if !until.IsZero() {
+			score = float64(until.UnixMilli())
+		}
+		pipe.ZRem(ctx, c.available, id.Hex())
+		pipe.ZAdd(ctx, c.holds, redis.Z{Score: score, Member: id.Hex()})
+		return nil
+	})
+	return err
+}
+
+func (c *RedisDriverCache) FreeDriver(ctx context.Context, id primitive.ObjectID) error {
+	return freeDrivers.Run(ctx, c.client, []string{c.locations, c.available, c.holds}, id.Hex()).Err()
+}
+
+func (c *RedisDriverCache) FindNearestDrivers(ctx context.Context, latitude, longitude float64, radius, limit int) ([]models.DriverWithDistance, error) {
+	if err := c.freeExpired(ctx); err != nil {
+		return nil, err
+	}
+
+	// The radius is padded, so drivers just inside it are found despite the rounding of Redis
+	ids, err := c.client.GeoSearch(ctx, c.available, &redis.GeoSearchQuery{
+		Longitude:  longitude,
+		Latitude:   latitude,
+		Radius:     float64(radius)*1.001 + 1,
+		RadiusUnit: "m",
+		Sort:       "ASC",
+		Count:      limit + searchSlack,
+	}).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	locations, err := c.client.HMGet(ctx, c.locations, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	drivers := make([]models.DriverWithDistance, 0, len(ids))
+	for i, id := range ids {
+		location, ok := locations[i].(string)
+		if !ok {
+			continue
+		}
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cached driver ID %q: %w", id, err)
+		}
+		driverLatitude, driverLongitude, err := parseLocation(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cached location of driver %s: %w", id, err)
+		}
+
+		distance := geo.Distance(latitude, longitude, driverLatitude, driverLongitude)
+		if distance > float64(radius) {
+			continue
+		}
+		drivers = append(drivers, models.DriverWithDistance{
+			ID:       objectID,
+			Location: models.Location{Type: "Point", Coordinates: []float64{driverLongitude, driverLatitude}},
+			Status:   models.DriverStatusAvailable,
+			Distance: distance,
+		})
+	}
+
+	sort.SliceStable(drivers, func(i, j int) bool { return drivers[i].Distance < drivers[j].Distance })
+	if len(drivers) > limit {
+		drivers = drivers[:limit]
+	}
+	return drivers, nil
+}
+
+// freeExpired makes the drivers whose hold expired available again
+func (c *RedisDriverCache) freeExpired(ctx context.Context) error {
+	ids, err := c.client.ZRangeByScore(ctx, c.holds, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(c.now().UnixMilli(), 10),
+	}).Result()
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return freeDrivers.Run(ctx, c.client, []string{c.locations, c.available, c.holds}, args...).Err()
+}
+
+func (c *RedisDriverCache) ForgetDrivers(ctx context.Context, ids ...primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	members := make([]string, len(ids))
+	for i, id := range ids {
+		members[i] = id.Hex()
+	}
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, c.locations, members...)
+		pipe.ZRem(ctx, c.available, members)
+		pipe.ZRem(ctx, c.holds, members)
+		return nil
+	})
+	return err
+}
+
+func parseLocation(location string) (latitude, longitude float64, err error) {
+	lon, lat, ok := strings.Cut(location, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("malformed location %q", location)
+	}
+	if longitude, err = strconv.ParseFloat(lon, 64); err != nil {
+		return 0, 0, err
+	}
+	if latitude, err = strconv.ParseFloat(lat, 64); err != nil {
+		return 0, 0, err
+	}
+	return latitude, longitude, nil
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+)
+
+func TestRedisDriverCache_Holds(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestCache(t)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	driver := driverAt(41, 29)
+	driver.ID = primitive.NewObjectID()
+	if err := cache.AddDrivers(ctx, []models.DriverWithDistance{driver}); err != nil {
+		t.Fatalf("AddDrivers failed: %v", err)
+	}
+
+	expectAvailable := func(want bool) {
+		t.Helper()
+		drivers, err := cache.FindNearestDrivers(ctx, 41, 29, 100, 5)
+		if err != nil {
+			t.Fatalf("FindNearestDrivers failed: %v", err)
+		}
+		if got := len(drivers) == 1; got != want {
+			t.Errorf("expected available %v, got drivers %+v", want, drivers)
+		}
+	}
+
+	if err := cache.HoldDriver(ctx, driver.ID, now.Add(time.Minute)); err != nil {
+		t.Fatalf("HoldDriver failed: %v", err)
+	}
+	expectAvailable(false)
+
+	// A driver moving while held stays held, and is found at the new location once the hold expires
+	if err := cache.MoveDriver(ctx, driver.ID, 41.01, 29); err != nil {
+		t.Fatalf("MoveDriver failed: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	expectAvailable(false)
+	if drivers, _ := cache.FindNearestDrivers(ctx, 41.01, 29, 100, 5); len(drivers) != 1 {
+		t.Errorf("expected the driver to be available after the hold expired, got %+v", drivers)
+	}
+
+	// Holds without expiry last until the driver is freed
+	if err := cache.HoldDriver(ctx, driver.ID, time.Time{}); err != nil {
+		t.Fatalf("HoldDriver failed: %v", err)
+	}
+	now = now.Add(24 * time.Hour)
+	if drivers, _ := cache.FindNearestDrivers(ctx, 41.01, 29, 100, 5); len(drivers) != 0 {
+		t.Errorf("expected the driver to stay held, got %+v", drivers)
+	}
+
+	// Saving a held driver again moves them without freeing them
+	driver.Location.Coordinates = []float64{29, 41.02}
+	if err := cache.AddDrivers(ctx, []models.DriverWithDistance{driver}); err != nil {
+		t.Fatalf("AddDrivers failed: %v", err)
+	}
+	if drivers, _ := cache.FindNearestDrivers(ctx, 41.02, 29, 100, 5); len(drivers) != 0 {
+		t.Errorf("expected the driver to stay held, got %+v", drivers)
+	}
+	if err := cache.FreeDriver(ctx, driver.ID); err != nil {
+		t.Fatalf("FreeDriver failed: %v", err)
+	}
+	if drivers, _ := cache.FindNearestDrivers(ctx, 41.02, 29, 100, 5); len(drivers) != 1 {
+		t.Errorf("expected the freed driver to be available, got %+v", drivers)
+	}
+}
+
+func TestRedisDriverCache_ExactLocations(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestCache(t)
+
+	// Redis rounds positions to about half a meter, the cache returns them unrounded
+	driver := driverAt(41.0012345678, 29.0098765432)
+	driver.ID = primitive.NewObjectID()
+	if err := cache.AddDrivers(ctx, []models.DriverWithDistance{driver}); err != nil {
+		t.Fatalf("AddDrivers failed: %v", err)
+	}
+
+	drivers, err := cache.FindNearestDrivers(ctx, 41, 29, 1000, 5)
+	if err != nil || len(drivers) != 1 {
+		t.Fatalf("expected one driver, got %+v, %v", drivers, err)
+	}
+	if got := drivers[0].Location.Coordinates; got[0] != 29.0098765432 || got[1] != 41.0012345678 {
+		t.Errorf("expected the exact location, got %v", got)
+	}
+}
+
+func TestRedisDriverCache_SkipsPolarDrivers(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestCache(t)
+
+	driver := driverAt(89, 0)
+	driver.ID = primitive.NewObjectID()
+	if err := cache.AddDrivers(ctx, []models.DriverWithDistance{driver}); err != nil {
+		t.Errorf("expected drivers beyond MaxLatitude to be skipped, got %v", err)
+	}
+	if err := cache.MoveDriver(ctx, driver.ID, 89.5, 0); err != nil {
+		t.Errorf("expected drivers beyond MaxLatitude to be skipped, got %v", err)
+	}
+}
+
+func TestRedisDriverCache_ReindexesForgottenDrivers(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestCache(t)
+
+	driver := driverAt(41, 29)
+	driver.ID = primitive.NewObjectID()
+	if err := cache.AddDrivers(ctx, []models.DriverWithDistance{driver}); err != nil {
+		t.Fatalf("AddDrivers failed: %v", err)
+	}
+	if err := cache.ForgetDrivers(ctx, driver.ID); err != nil {
+		t.Fatalf("ForgetDrivers failed: %v", err)
+	}
+
+	// The next location update of an evicted driver indexes them again
+	if err := cache.MoveDriver(ctx, driver.ID, 41.01, 29); err != nil {
+		t.Fatalf("MoveDriver failed: %v", err)
+	}
+	if drivers, _ := cache.FindNearestDrivers(ctx, 41.01, 29, 100, 5); len(drivers) != 1 {
+		t.Errorf("expected the moved driver to be available, got %+v", drivers)
+	}
+
+	// So does importing them again
+	if err := cache.ForgetDrivers(ctx, driver.ID); err != nil {
+		t.Fatalf("ForgetDrivers failed: %v", err)
+	}
+	if err := cache.AddDrivers(ctx, []models.DriverWithDistance{driver}); err != nil {
+		t.Fatalf("AddDrivers failed: %v", err)
+	}
+	if drivers, _ := cache.FindNearestDrivers(ctx, 41, 29, 100, 5); len(drivers) != 1 {
+		t.Errorf("expected the imported driver to be available, got %+v", drivers)
+	}
+}
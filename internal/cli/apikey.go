@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"bitaksi-go-driver/internal/auth"
+)
+
+func newAPIKeyCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "apikey",
+		Short: "Manage API keys",
+	}
+
+	var name string
+	var scopes []string
+	generate := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate an API key and the entry to add to the keys file",
+		Long: "Generate a random API key for a consuming service. The key is printed once, only its hash is " +
+			"stored in the keys file. Scopes: " + strings.Join(auth.AllScopes, ", ") + ".",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, scope := range scopes {
+				if !slices.Contains(auth.AllScopes, scope) {
+					return fmt.Errorf("unknown scope %q, must be one of %s", scope, strings.Join(auth.AllScopes, ", "))
+				}
+			}
+
+			key, err := auth.GenerateKey()
+			if err != nil {
+				return fmt.Errorf("failed to generate key: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "API key, give it to %s as it cannot be shown again:\n  %s\n\n", name, key)
+			fmt.Fprintf(out, "Entry of the keys file:\n  - name: %s\n    hash: %s\n    scopes: [%s]\n", name, auth.HashKey(key), strings.Join(scopes, ", "))
+			return nil
+		},
+	}
+	generate.Flags().StringVar(&name, "name", "", "Name of the consuming service, shown in access logs")
+	generate.Flags().StringSliceVar(&scopes, "scope", nil, "Scope granted to the key, repeatable")
+	generate.MarkFlagRequired("name")
+	generate.MarkFlagRequired("scope")
+
+	command.AddCommand(generate)
+	return command
+}
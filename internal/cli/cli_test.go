@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bitaksi-go-driver/internal/service"
+)
+
+// TestMain runs the commands from the repository root, where the configuration file lives
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	t.Setenv("STORAGE_BACKEND", "memory")
+	t.Setenv("CACHE_ENABLED", "false")
+
+	var out bytes.Buffer
+	command := NewRootCommand()
+	command.SetArgs(args)
+	command.SetOut(&out)
+	command.SetErr(&out)
+	err := command.Execute()
+	return out.String(), err
+}
+
+func TestCommands(t *testing.T) {
+	csvFile := filepath.Join(t.TempDir(), "drivers.csv")
+	if err := os.WriteFile(csvFile, []byte("Latitude,Longitude\n41,29\n41.001,29\n"), 0o600); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedOutput string
+		expectedError  string
+	}{
+		{
+			name:           "Config Validate",
+			args:           []string{"config", "validate"},
+			expectedOutput: "Configuration is valid",
+		},
+		{
+			name:           "Import",
+			args:           []string{"import", csvFile},
+			expectedOutput: "Imported 2 drivers",
+		},
+		{
+			name:          "Import Missing File",
+			args:          []string{"import", filepath.Join(t.TempDir(), "missing.csv")},
+			expectedError: "failed to open CSV file",
+		},
+		{
+			name:           "Export",
+			args:           []string{"export"},
+			expectedOutput: "Latitude,Longitude,ID",
+		},
+		{
+			name:          "Search Finds Nothing",
+			args:          []string{"search", "--lat", "41", "--lon", "29", "--radius", "1000"},
+			expectedError: "no drivers found within the radius of 1000 meters",
+		},
+		{
+			name:          "Search Invalid Latitude",
+			args:          []string{"search", "--lat", "91", "--lon", "29", "--radius", "1000"},
+			expectedError: "--lat must be in [-90, 90]",
+		},
+		{
+			name:          "Search Missing Flag",
+			args:          []string{"search", "--lat", "41", "--lon", "29"},
+			expectedError: `required flag(s) "radius" not set`,
+		},
+		{
+			name:          "Migrate Down Invalid Steps",
+			args:          []string{"migrate", "down", "zero"},
+			expectedError: "invalid number of migrations to revert: zero",
+		},
+		{
+			name:           "Simulate",
+			args:           []string{"simulate", "--drivers", "5", "--zone", "sxk9", "--tick", "10ms", "--duration", "50ms"},
+			expectedOutput: "Simulated 5 drivers",
+		},
+		{
+			name:          "Simulate Invalid Bounding Box",
+			args:          []string{"simulate", "--bbox", "41,29,41.1"},
+			expectedError: "--bbox must be min_lat,min_lon,max_lat,max_lon",
+		},
+		{
+			name:          "Simulate Invalid Mode",
+			args:          []string{"simulate", "--mode", "teleport", "--duration", "10ms"},
+			expectedError: "invalid simulation options: the mode must be random-walk or waypoints",
+		},
+		{
+			name:           "Load Test",
+			args:           []string{"loadtest", "--url", "http://127.0.0.1:1", "--concurrency", "1", "--duration", "50ms"},
+			expectedOutput: `"connection_refused"`,
+		},
+		{
+			name:          "Load Test Invalid Distribution",
+			args:          []string{"loadtest", "--distribution", "gaussian", "--duration", "50ms"},
+			expectedError: "invalid load test options: the distribution must be uniform or hotspots",
+		},
+		{
+			name:          "Load Test Missing Drivers File",
+			args:          []string{"loadtest", "--drivers", filepath.Join(t.TempDir(), "missing.csv")},
+			expectedError: "failed to open",
+		},
+		{
+			name:           "API Key Generate",
+			args:           []string{"apikey", "generate", "--name", "rider-app", "--scope", "search:read,rides:write"},
+			expectedOutput: "    scopes: [search:read, rides:write]",
+		},
+		{
+			name:          "API Key Unknown Scope",
+			args:          []string{"apikey", "generate", "--name", "rider-app", "--scope", "admin"},
+			expectedError: `unknown scope "admin"`,
+		},
+		{
+			name:           "Migrate Memory",
+			args:           []string{"migrate", "status"},
+			expectedOutput: "The memory backend has no schema to migrate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := run(t, tt.args...)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !strings.Contains(output, tt.expectedOutput) {
+				t.Errorf("expected output containing %q, got %q", tt.expectedOutput, output)
+			}
+		})
+	}
+}
+
+func TestPublishSearchCache_Twice(t *testing.T) {
+	// Servers started one after the other in a process, as in tests, must not panic on expvar.Publish
+	first := service.NewSearchCache(time.Second, 7, 10)
+	second := service.NewSearchCache(time.Second, 7, 10)
+	publishSearchCache(first)
+	publishSearchCache(second)
+
+	if currentSearchCache.Load() != second {
+		t.Error("expected the counters of the latest search cache to be published")
+	}
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"bitaksi-go-driver/internal/config"
+	"bitaksi-go-driver/internal/service"
+)
+
+// sampleDriversFile holds the drivers loaded by the seed command
+const sampleDriversFile = "./docs/Coordinates.csv"
+
+// withDriverService runs fn with a driver service over the configured storage. Searches made by
+// commands are not recorded as demand and not answered from the search cache.
+func withDriverService(ctx context.Context, fn func(cfg *config.Config, driverService *service.DriverService) error) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	repos, closeStorage, err := openStorage(ctx, cfg, storageOptions{})
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	driverService := service.NewDriverService(repos.Drivers, etaOptions(cfg)...)
+	return fn(cfg, &driverService)
+}
+
+func newImportCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import drivers from a CSV file of latitude, longitude and optional ID rows",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDriverService(cmd.Context(), func(cfg *config.Config, driverService *service.DriverService) error {
+				count, err := driverService.ImportLocationsFromFile(cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Imported %d drivers\n", count)
+				return nil
+			})
+		},
+	}
+}
+
+func newExportCommand() *cobra.Command {
+	var output string
+
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Export every driver as CSV that the import command reads back",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDriverService(cmd.Context(), func(cfg *config.Config, driverService *service.DriverService) error {
+				out := cmd.OutOrStdout()
+				if output != "" {
+					file, err := os.Create(output)
+					if err != nil {
+						return fmt.Errorf("failed to create %s: %w", output, err)
+					}
+					defer file.Close()
+					out = file
+				}
+
+				count, err := driverService.ExportLocations(cmd.Context(), out)
+				if err != nil {
+					return err
+				}
+				if output != "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "Exported %d drivers to %s\n", count, output)
+				}
+				return nil
+			})
+		},
+	}
+	command.Flags().StringVarP(&output, "output", "o", "", "file to write instead of standard output")
+	return command
+}
+
+func newSearchCommand() *cobra.Command {
+	var latitude, longitude float64
+	var radius int
+
+	command := &cobra.Command{
+		Use:   "search",
+		Short: "Find the nearest available driver",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 || radius <= 0 {
+				return fmt.Errorf("--lat must be in [-90, 90], --lon in [-180, 180] and --radius positive")
+			}
+
+			return withDriverService(cmd.Context(), func(cfg *config.Config, driverService *service.DriverService) error {
+				driver, err := driverService.FindNearestDriver(cmd.Context(), latitude, longitude, radius)
+				if err != nil {
+					return err
+				}
+
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(driver)
+			})
+		},
+	}
+	command.Flags().Float64Var(&latitude, "lat", 0, "latitude of the search point")
+	command.Flags().Float64Var(&longitude, "lon", 0, "longitude of the search point")
+	command.Flags().IntVar(&radius, "radius", 0, "search radius in meters")
+	command.MarkFlagRequired("lat")
+	command.MarkFlagRequired("lon")
+	command.MarkFlagRequired("radius")
+	return command
+}
+
+func newSeedCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "seed",
+		Short: "Load the bundled sample drivers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDriverService(cmd.Context(), func(cfg *config.Config, driverService *service.DriverService) error {
+				count, err := driverService.ImportLocationsFromFile(cmd.Context(), sampleDriversFile)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Seeded %d drivers\n", count)
+				return nil
+			})
+		},
+	}
+}
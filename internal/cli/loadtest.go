@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"bitaksi-go-driver/internal/loadtest"
+)
+
+func newLoadTestCommand() *cobra.Command {
+	var options loadtest.Options
+	var bbox, zone, driversFile, output string
+
+	command := &cobra.Command{
+		Use:   "loadtest",
+		Short: "Send searches and location updates to a running instance and report latencies as JSON",
+		Long: "Send searches and location updates to a running instance at the given concurrency and rate, and\n" +
+			"report throughput, latency percentiles and errors per operation as JSON. Drivers found by searches\n" +
+			"are updated afterwards; pass an export with --drivers to update drivers from the start.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if options.Bounds, err = resolveBounds(bbox, zone); err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("api-key") {
+				cfg, err := loadConfig()
+				if err != nil {
+					return err
+				}
+				options.APIKey = cfg.Server.APIKey
+			}
+			if driversFile != "" {
+				if options.DriverIDs, err = readDriverIDs(driversFile); err != nil {
+					return err
+				}
+			}
+			options.BaseURL = strings.TrimSuffix(options.BaseURL, "/")
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			report, err := loadtest.Run(ctx, options)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("failed to create %s: %w", output, err)
+				}
+				defer file.Close()
+				out = file
+			}
+
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		},
+	}
+	command.Flags().StringVar(&options.BaseURL, "url", "http://localhost:8080", "base URL of the instance under test")
+	command.Flags().StringVar(&options.APIKey, "api-key", "", "API key, defaults to the configured one")
+	command.Flags().IntVar(&options.Concurrency, "concurrency", 10, "number of requests in flight at most")
+	command.Flags().Float64Var(&options.Rate, "rate", 0, "requests per second, as fast as possible if zero")
+	command.Flags().DurationVar(&options.Duration, "duration", 30*time.Second, "how long to send requests")
+	command.Flags().DurationVar(&options.Timeout, "timeout", 5*time.Second, "timeout of each request")
+	command.Flags().Float64Var(&options.UpdateRatio, "update-ratio", 0.2, "fraction of the requests that are location updates")
+	command.Flags().StringVar(&bbox, "bbox", "40.95,28.9,41.1,29.1", "bounding box as min_lat,min_lon,max_lat,max_lon")
+	command.Flags().StringVar(&zone, "zone", "", "geohash cell to send requests in instead of the bounding box")
+	command.Flags().StringVar(&options.Distribution, "distribution", loadtest.DistributionUniform, "spatial distribution, uniform or hotspots")
+	command.Flags().IntVar(&options.Hotspots, "hotspots", 5, "number of hotspots of the hotspots distribution")
+	command.Flags().IntVar(&options.Radius, "radius", 5000, "search radius in meters")
+	command.Flags().Int64Var(&options.Seed, "seed", 1, "random seed of the request positions")
+	command.Flags().StringVar(&driversFile, "drivers", "", "CSV export of drivers to update")
+	command.Flags().StringVarP(&output, "output", "o", "", "file to write the report to instead of standard output")
+	return command
+}
+
+// readDriverIDs reads the ID column of a CSV file written by the export command
+func readDriverIDs(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	column := -1
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), "ID") {
+			column = i
+		}
+	}
+	if column < 0 {
+		return nil, fmt.Errorf("%s has no ID column", path)
+	}
+
+	var ids []string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if column < len(record) && record[column] != "" {
+			ids = append(ids, record[column])
+		}
+	}
+}
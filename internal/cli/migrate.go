@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"bitaksi-go-driver/internal/storage"
+)
+
+func newMigrateCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema migrations, applying pending ones by default",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigration(cmd, storage.MigrateUp, 0)
+		},
+	}
+
+	command.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply the pending migrations",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runMigration(cmd, storage.MigrateUp, 0)
+			},
+		},
+		&cobra.Command{
+			Use:   "down [steps]",
+			Short: "Revert the most recent migrations, one unless steps is given",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				steps := 1
+				if len(args) == 1 {
+					var err error
+					if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+						return fmt.Errorf("invalid number of migrations to revert: %s", args[0])
+					}
+				}
+				return runMigration(cmd, storage.MigrateDown, steps)
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "List the applied and pending migrations",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runMigration(cmd, storage.MigrateStatus, 0)
+			},
+		},
+	)
+	return command
+}
+
+func runMigration(cmd *cobra.Command, command string, steps int) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := storage.Migrate(cmd.Context(), cfg, command, steps, cmd.OutOrStdout()); err != nil {
+		return fmt.Errorf("failed to migrate %s storage: %w", cfg.Storage.Backend, err)
+	}
+	return nil
+}
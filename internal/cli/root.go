@@ -0,0 +1,142 @@
+// Package cli implements the driver-service command line: the HTTP server and the maintenance
+// commands operators run against the same storage without going through the API.
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+
+	"bitaksi-go-driver/internal/cache"
+	"bitaksi-go-driver/internal/config"
+	"bitaksi-go-driver/internal/db"
+	"bitaksi-go-driver/internal/logging"
+	"bitaksi-go-driver/internal/metrics"
+	"bitaksi-go-driver/internal/service"
+	"bitaksi-go-driver/internal/storage"
+	"bitaksi-go-driver/internal/tracing"
+)
+
+// NewRootCommand returns the driver-service command with all its subcommands
+func NewRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "driver-service",
+		Short:         "Manages driver locations and finds the nearest drivers",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	root.AddCommand(
+		newServeCommand(),
+		newImportCommand(),
+		newExportCommand(),
+		newSearchCommand(),
+		newSeedCommand(),
+		newSimulateCommand(),
+		newLoadTestCommand(),
+		newMigrateCommand(),
+		newConfigCommand(),
+		newAPIKeyCommand(),
+	)
+	return root
+}
+
+// Execute runs the command selected by the command line arguments
+func Execute() {
+	if err := NewRootCommand().Execute(); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+// loadConfig loads and validates the configuration
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := logging.Setup(cfg.Logging.Level, cfg.Logging.Format); err != nil {
+		return nil, fmt.Errorf("failed to set up logging: %w", err)
+	}
+	return cfg, nil
+}
+
+// storageOptions select what openStorage adds around the storage backend
+type storageOptions struct {
+	warmCache bool             // Fill an empty driver cache from the backend before returning
+	metrics   *metrics.Metrics // Instrument the backend and its connection pool if not nil
+	tracing   bool             // Trace repository operations and MongoDB commands
+}
+
+// openStorage opens the configured storage backend, putting the driver cache in front of it if
+// enabled so that changes made by any command are written through. The returned function closes both.
+func openStorage(ctx context.Context, cfg *config.Config, options storageOptions) (*storage.Repositories, func(), error) {
+	var opts []storage.Option
+	if options.metrics != nil {
+		opts = append(opts, storage.WithPoolMonitor(options.metrics.PoolMonitor()))
+	}
+	if options.tracing {
+		opts = append(opts, storage.WithCommandMonitor(otelmongo.NewMonitor()))
+	}
+
+	repos, err := storage.Open(ctx, cfg, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s storage: %w", cfg.Storage.Backend, err)
+	}
+	if options.metrics != nil {
+		// Below the cache, so the timings are those of the backend
+		repos.Drivers = metrics.NewRepository(repos.Drivers, options.metrics)
+	}
+	closeRepos := func() {
+		if err := repos.Close(context.Background()); err != nil {
+			slog.Error("Error closing storage", "error", err)
+		}
+	}
+
+	closeAll := closeRepos
+	if cfg.Cache.Enabled {
+		client, err := db.ConnectRedis(cfg)
+		if err != nil {
+			closeRepos()
+			return nil, nil, fmt.Errorf("failed to connect to the driver cache: %w", err)
+		}
+		closeAll = func() {
+			client.Close()
+			closeRepos()
+		}
+
+		cachedRepo := cache.NewRepository(repos.Drivers, cache.NewRedisDriverCache(client, cfg.Cache.Prefix))
+		if options.warmCache {
+			if err := cachedRepo.Warm(ctx); err != nil {
+				closeAll()
+				return nil, nil, fmt.Errorf("failed to warm the driver cache: %w", err)
+			}
+		}
+		repos.Drivers = cachedRepo
+	}
+
+	if options.tracing {
+		// Above the cache, so searches answered by it show up in traces too
+		repos.Drivers = tracing.NewRepository(repos.Drivers)
+	}
+	return repos, closeAll, nil
+}
+
+// etaOptions configures travel-time ranking of nearby drivers from the configuration
+func etaOptions(cfg *config.Config) []service.Option {
+	switch cfg.ETA.Provider {
+	case "heuristic":
+		return []service.Option{service.WithETAProvider(service.NewHeuristicETAProvider(), cfg.ETA.Candidates)}
+	case "osrm":
+		return []service.Option{service.WithETAProvider(service.NewOSRMETAProvider(cfg.ETA.OSRMURL, cfg.ETA.Timeout), cfg.ETA.Candidates)}
+	default:
+		return nil
+	}
+}
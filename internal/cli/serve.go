@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	httpSwagger "github.com/swaggo/http-swagger"
+
+	"bitaksi-go-driver/internal/api"
+	"bitaksi-go-driver/internal/auth"
+	"bitaksi-go-driver/internal/clientip"
+	"bitaksi-go-driver/internal/config"
+	"bitaksi-go-driver/internal/health"
+	"bitaksi-go-driver/internal/metrics"
+	"bitaksi-go-driver/internal/service"
+	"bitaksi-go-driver/internal/tracing"
+)
+
+func newServeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Start the HTTP server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			return serve(cfg)
+		},
+	}
+}
+
+// serve wires the services and runs the HTTP server until it receives SIGINT or SIGTERM
+func serve(cfg *config.Config) error {
+	var m *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		m = metrics.New()
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Error flushing traces", "error", err)
+		}
+	}()
+
+	// Initialize storage
+	repos, closeStorage, err := openStorage(context.Background(), cfg, storageOptions{
+		warmCache: true,
+		metrics:   m,
+		tracing:   tracing.Enabled(cfg),
+	})
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+	driverRepo, searchEventRepo, rideRepo := repos.Drivers, repos.SearchEvents, repos.Rides
+
+	// Initialize services
+	surgeOptions := []service.SurgeOption{service.WithCellTTL(cfg.Surge.CellTTL)}
+	driverOptions := etaOptions(cfg)
+	if cfg.Demand.Enabled {
+		// Counted from the stored searches, so that every replica returns the same multiplier
+		surgeOptions = append(surgeOptions, service.WithStoredDemand(searchEventRepo))
+	}
+	surgeService := service.NewSurgeService(driverRepo, cfg.Surge.Precision, cfg.Surge.Window, cfg.Surge.Buckets, surgePolicy(cfg), surgeOptions...)
+	if !cfg.Demand.Enabled {
+		driverOptions = append(driverOptions, service.WithSearchRecorder(surgeService))
+	}
+	var reservationRepo service.ReservationRepository = driverRepo
+	if cfg.SearchCache.Enabled {
+		searchCache := service.NewSearchCache(cfg.SearchCache.TTL, cfg.SearchCache.Precision, cfg.SearchCache.MaxEntries)
+		publishSearchCache(searchCache)
+		driverOptions = append(driverOptions, service.WithSearchCache(searchCache))
+		// So that searches stop returning drivers once they are reserved
+		reservationRepo = searchCache.Reservations(driverRepo)
+	}
+	if cfg.Demand.Enabled {
+		demandRecorder := service.NewDemandRecorder(searchEventRepo, cfg.Demand.SampleRate, cfg.Demand.Precision, cfg.Demand.BufferSize, cfg.Demand.FlushInterval)
+		demandRecorder.Start()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := demandRecorder.Close(ctx); err != nil {
+				slog.Error("Error flushing search events", "error", err)
+			}
+		}()
+		driverOptions = append(driverOptions, service.WithSearchRecorder(demandRecorder))
+	}
+	driverService := service.NewDriverService(driverRepo, driverOptions...)
+	dispatchService := service.NewDispatchService(driverRepo, reservationRepo, rideRepo, cfg.Dispatch.OfferTimeout, cfg.Dispatch.MaxOffers)
+	reservationService := service.NewReservationService(reservationRepo, cfg.Reservation.TTL)
+	matchingService := service.NewMatchingService(driverRepo, cfg.Matching.Candidates)
+	heatmapService := service.NewHeatmapService(driverRepo)
+	demandService := service.NewDemandService(searchEventRepo, cfg.Demand.Precision)
+
+	// Set up router
+	routerOptions := []api.RouterOption{
+		api.WithDispatch(dispatchService),
+		api.WithReservations(&reservationService),
+		api.WithMatching(&matchingService, cfg.Matching.MaxBatchSize),
+		api.WithHeatmap(&heatmapService),
+		api.WithSurge(surgeService),
+		api.WithDemand(&demandService),
+		api.WithDebugVars(),
+	}
+	if m != nil {
+		routerOptions = append(routerOptions, api.WithMetrics(m))
+	}
+	if tracing.Enabled(cfg) {
+		routerOptions = append(routerOptions, api.WithTracing(cfg.Tracing.ServiceName))
+	}
+	if cfg.AccessLog.Enabled {
+		// After tracing, so that the access log carries the trace ID of the request
+		trustedProxies, err := clientip.ParseTrusted(cfg.AccessLog.TrustedProxies)
+		if err != nil {
+			return err
+		}
+		routerOptions = append(routerOptions, api.WithAccessLog(trustedProxies, cfg.AccessLog.SearchSampleRate))
+	}
+	keys, err := openKeyStore(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Auth.KeysFile != "" {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go keys.Watch(ctx, cfg.Auth.KeysFile, cfg.Auth.ReloadInterval)
+	}
+	var authenticator auth.Authenticator = keys
+	if cfg.Auth.JWT.Enabled {
+		jwks, err := auth.LoadJWKS(context.Background(), cfg.Auth.JWT.JWKS)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go jwks.Watch(ctx, cfg.Auth.JWT.RefreshInterval)
+
+		bearer, err := auth.NewJWTAuthenticator(jwks, auth.JWTOptions{
+			Issuer:     cfg.Auth.JWT.Issuer,
+			Audience:   cfg.Auth.JWT.Audience,
+			ScopeClaim: cfg.Auth.JWT.ScopeClaim,
+			Mappings:   cfg.Auth.JWT.ScopeMapping,
+			Leeway:     cfg.Auth.JWT.Leeway,
+		})
+		if err != nil {
+			return err
+		}
+		authenticator = auth.BearerOrKey{Keys: keys, Bearer: bearer}
+	}
+	routerOptions = append(routerOptions, api.WithAuthenticator(authenticator))
+
+	checker := health.NewChecker(cfg.Health.Timeout, repos.Checks...)
+	routerOptions = append(routerOptions, api.WithReadiness(checker))
+	router := api.SetupRouter(&driverService, cfg, routerOptions...)
+
+	// Add Swagger documentation
+	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+
+	// Start HTTP server with graceful shutdown
+	return startServer(router, cfg, checker)
+}
+
+// currentSearchCache is the search cache of the running server, whose counters are published as expvar
+var (
+	currentSearchCache     atomic.Pointer[service.SearchCache]
+	publishSearchCacheOnce sync.Once
+)
+
+// publishSearchCache publishes the counters of the cache at /debug/vars. expvar variables cannot be
+// replaced, so the variable is published once and reads the cache of the latest server.
+func publishSearchCache(cache *service.SearchCache) {
+	currentSearchCache.Store(cache)
+	publishSearchCacheOnce.Do(func() {
+		expvar.Publish("search_cache", expvar.Func(func() interface{} {
+			if cache := currentSearchCache.Load(); cache != nil {
+				return cache.Stats()
+			}
+			return nil
+		}))
+	})
+}
+
+// openKeyStore loads the API keys of server.api_key and of auth.keys_file
+func openKeyStore(cfg *config.Config) (*auth.KeyStore, error) {
+	var static []auth.Key
+	if cfg.Server.APIKey != "" {
+		static = append(static, auth.LegacyKey(cfg.Server.APIKey))
+	}
+
+	keys, err := auth.NewKeyStore(static...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Auth.KeysFile != "" {
+		if err := keys.LoadFile(cfg.Auth.KeysFile); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// surgePolicy converts the configured surge curve and caps
+func surgePolicy(cfg *config.Config) service.SurgePolicy {
+	policy := service.SurgePolicy{
+		MinMultiplier: cfg.Surge.MinMultiplier,
+		MaxMultiplier: cfg.Surge.MaxMultiplier,
+		Step:          cfg.Surge.Step,
+	}
+	for _, point := range cfg.Surge.Curve {
+		policy.Curve = append(policy.Curve, service.SurgeCurvePoint{Ratio: point.Ratio, Multiplier: point.Multiplier})
+	}
+	return policy
+}
+
+// startServer starts the HTTP server and handles graceful shutdown. On a shutdown signal the server
+// reports not ready for the shutdown delay, so that load balancers stop sending requests before it
+// stops accepting them.
+func startServer(router http.Handler, cfg *config.Config, checker *health.Checker) error {
+	// Create HTTP server
+	server := &http.Server{
+		Addr:     cfg.Server.Port,
+		Handler:  router,
+		ErrorLog: slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+	}
+
+	// Run the server in a separate goroutine
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "addr", cfg.Server.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	// Wait for shutdown signals
+	shutdownChan := make(chan os.Signal, 1)
+	signal.Notify(shutdownChan, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-serverErr:
+		return err
+	case <-shutdownChan:
+	}
+
+	// Gracefully shut down the server
+	slog.Info("Shutting down server", "delay", cfg.Health.ShutdownDelay)
+	checker.ShutDown()
+	time.Sleep(cfg.Health.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	slog.Info("Server exited gracefully")
+	return nil
+}
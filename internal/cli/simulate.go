@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"bitaksi-go-driver/internal/config"
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/service"
+	"bitaksi-go-driver/internal/simulator"
+)
+
+func newSimulateCommand() *cobra.Command {
+	var options simulator.Options
+	var bbox, zone string
+	var tick, duration time.Duration
+
+	command := &cobra.Command{
+		Use:   "simulate",
+		Short: "Add synthetic drivers and keep moving them until the duration passes or interrupted",
+		Long: "Add synthetic drivers within a bounding box or geohash zone and move them at realistic speeds,\n" +
+			"pushing every position through the driver service. The same seed gives the same paths.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if options.Bounds, err = resolveBounds(bbox, zone); err != nil {
+				return err
+			}
+			if tick <= 0 || duration < 0 {
+				return fmt.Errorf("--tick must be positive and --duration not negative")
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return withDriverService(ctx, func(cfg *config.Config, driverService *service.DriverService) error {
+				simulation, err := simulator.New(driverService, options)
+				if err != nil {
+					return err
+				}
+				if err := simulation.Run(ctx, tick, duration); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Simulated %d drivers\n", options.Drivers)
+				return nil
+			})
+		},
+	}
+	command.Flags().IntVar(&options.Drivers, "drivers", 100, "number of drivers to simulate")
+	command.Flags().StringVar(&bbox, "bbox", "40.95,28.9,41.1,29.1", "bounding box as min_lat,min_lon,max_lat,max_lon")
+	command.Flags().StringVar(&zone, "zone", "", "geohash cell to simulate in instead of the bounding box")
+	command.Flags().StringVar(&options.Mode, "mode", simulator.ModeRandomWalk, "movement mode, random-walk or waypoints")
+	command.Flags().Float64Var(&options.SpeedKmh, "speed", 30, "average speed in km/h")
+	command.Flags().Int64Var(&options.Seed, "seed", 1, "random seed, the same seed gives the same paths")
+	command.Flags().DurationVar(&tick, "tick", time.Second, "interval between location updates")
+	command.Flags().DurationVar(&duration, "duration", 0, "how long to simulate, until interrupted if zero")
+	return command
+}
+
+// resolveBounds returns the bounds of the geohash zone if given and of the bounding box otherwise
+func resolveBounds(bbox, zone string) (geo.Bounds, error) {
+	if zone != "" {
+		return geo.DecodeGeohash(zone)
+	}
+	return parseBounds(bbox)
+}
+
+// parseBounds parses a min_lat,min_lon,max_lat,max_lon bounding box
+func parseBounds(bbox string) (geo.Bounds, error) {
+	parts := strings.Split(bbox, ",")
+	if len(parts) != 4 {
+		return geo.Bounds{}, fmt.Errorf("--bbox must be min_lat,min_lon,max_lat,max_lon, got %q", bbox)
+	}
+
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return geo.Bounds{}, fmt.Errorf("--bbox must be min_lat,min_lon,max_lat,max_lon, got %q", bbox)
+		}
+		values[i] = value
+	}
+	return geo.Bounds{MinLat: values[0], MinLon: values[1], MaxLat: values[2], MaxLon: values[3]}, nil
+}
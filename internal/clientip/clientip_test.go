@@ -0,0 +1,58 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrusted(t *testing.T) {
+	prefixes, err := ParseTrusted([]string{"10.0.0.0/8", "192.168.1.7", "::1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prefixes) != 3 || prefixes[1].String() != "192.168.1.7/32" || prefixes[2].String() != "::1/128" {
+		t.Errorf("unexpected prefixes %v", prefixes)
+	}
+
+	if _, err := ParseTrusted([]string{"proxy.internal"}); err == nil {
+		t.Error("expected an error for a host name")
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	trusted, _ := ParseTrusted([]string{"10.0.0.0/8"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		expected   string
+	}{
+		{name: "Direct", remoteAddr: "203.0.113.5:4711", expected: "203.0.113.5"},
+		{name: "Untrusted Headers Ignored", remoteAddr: "203.0.113.5:4711", forwarded: "198.51.100.1", realIP: "198.51.100.2", expected: "203.0.113.5"},
+		{name: "Forwarded By Trusted Proxy", remoteAddr: "10.0.0.2:4711", forwarded: "198.51.100.1", expected: "198.51.100.1"},
+		{name: "Chain Of Trusted Proxies", remoteAddr: "10.0.0.2:4711", forwarded: "198.51.100.1, 10.0.0.3", expected: "198.51.100.1"},
+		{name: "Spoofed Hop Skipped", remoteAddr: "10.0.0.2:4711", forwarded: "1.2.3.4, 198.51.100.1", expected: "198.51.100.1"},
+		{name: "Malformed Hop", remoteAddr: "10.0.0.2:4711", forwarded: "garbage, 10.0.0.3", expected: "10.0.0.3"},
+		{name: "Real IP", remoteAddr: "10.0.0.2:4711", realIP: "198.51.100.2", expected: "198.51.100.2"},
+		{name: "IPv6", remoteAddr: "[2001:db8::1]:4711", expected: "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if ip := FromRequest(req, trusted); ip != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, ip)
+			}
+		})
+	}
+}
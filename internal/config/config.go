@@ -1,16 +1,26 @@
 package config
 
 import (
-	"log"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"bitaksi-go-driver/internal/auth"
 )
 
 type Config struct {
 	Server struct {
-		APIKey string `mapstructure:"api_key"`
+		APIKey string `mapstructure:"api_key"` // Legacy single key granted every scope, optional with auth.keys_file
 		Port   string `mapstructure:"port"`
 	} `mapstructure:"server"`
+	Storage struct {
+		Backend string `mapstructure:"backend"` // mongo, memory or postgres
+	} `mapstructure:"storage"`
+	Migrations struct {
+		RunOnStartup bool `mapstructure:"run_on_startup"` // Otherwise the server refuses to start until "migrate" is run
+	} `mapstructure:"migrations"`
 	MongoDB struct {
 		Username   string `mapstructure:"username"`
 		Password   string `mapstructure:"password"`
@@ -19,6 +29,102 @@ type Config struct {
 		Database   string `mapstructure:"database"`
 		Collection string `mapstructure:"collection"`
 	} `mapstructure:"mongodb"`
+	Postgres struct {
+		Username string `mapstructure:"username"`
+		Password string `mapstructure:"password"`
+		Host     string `mapstructure:"host"`
+		Port     int    `mapstructure:"port"`
+		Database string `mapstructure:"database"`
+		SSLMode  string `mapstructure:"sslmode"`
+	} `mapstructure:"postgres"`
+	ETA struct {
+		Provider   string        `mapstructure:"provider"`   // none, heuristic or osrm
+		Candidates int           `mapstructure:"candidates"` // Straight-line nearest drivers to re-rank
+		OSRMURL    string        `mapstructure:"osrm_url"`
+		Timeout    time.Duration `mapstructure:"timeout"`
+	} `mapstructure:"eta"`
+	Dispatch struct {
+		OfferTimeout time.Duration `mapstructure:"offer_timeout"` // How long a driver has to answer an offer
+		MaxOffers    int           `mapstructure:"max_offers"`    // Nearest drivers offered before giving up
+	} `mapstructure:"dispatch"`
+	Reservation struct {
+		TTL time.Duration `mapstructure:"ttl"` // Unconfirmed reservations are released after this long
+	} `mapstructure:"reservation"`
+	Matching struct {
+		Candidates   int `mapstructure:"candidates"`     // Nearest drivers considered per pickup
+		MaxBatchSize int `mapstructure:"max_batch_size"` // Maximum pickups per match request
+	} `mapstructure:"matching"`
+	Surge struct {
+		Precision     int           `mapstructure:"precision"` // Geohash precision of surge cells
+		Window        time.Duration `mapstructure:"window"`    // Sliding window over which searches count as demand
+		Buckets       int           `mapstructure:"buckets"`   // Resolution of the sliding window
+		CellTTL       time.Duration `mapstructure:"cell_ttl"`  // How long the supply and demand counted for a cell are reused
+		MinMultiplier float64       `mapstructure:"min_multiplier"`
+		MaxMultiplier float64       `mapstructure:"max_multiplier"`
+		Step          float64       `mapstructure:"step"` // Multipliers are rounded to this step
+		Curve         []struct {
+			Ratio      float64 `mapstructure:"ratio"` // Searches per available driver
+			Multiplier float64 `mapstructure:"multiplier"`
+		} `mapstructure:"curve"`
+	} `mapstructure:"surge"`
+	Demand struct {
+		Enabled       bool          `mapstructure:"enabled"`
+		SampleRate    float64       `mapstructure:"sample_rate"`    // Fraction of searches recorded
+		Precision     int           `mapstructure:"precision"`      // Searches are snapped to the center of geohash cells of this precision
+		BufferSize    int           `mapstructure:"buffer_size"`    // Events waiting to be written before new ones are dropped
+		FlushInterval time.Duration `mapstructure:"flush_interval"` // Longest time an event waits to be written
+		Retention     time.Duration `mapstructure:"retention"`      // Stored events are deleted after this time
+	} `mapstructure:"demand"`
+	Cache struct {
+		Enabled  bool   `mapstructure:"enabled"`
+		Address  string `mapstructure:"address"` // host:port of a Redis compatible server
+		Password string `mapstructure:"password"`
+		DB       int    `mapstructure:"db"`
+		Prefix   string `mapstructure:"prefix"` // Prefix of the keys holding the cached drivers
+	} `mapstructure:"cache"`
+	SearchCache struct {
+		Enabled    bool          `mapstructure:"enabled"`
+		TTL        time.Duration `mapstructure:"ttl"`
+		Precision  int           `mapstructure:"precision"` // Searches from the same geohash cell of this precision share results
+		MaxEntries int           `mapstructure:"max_entries"`
+	} `mapstructure:"search_cache"`
+	Metrics struct {
+		Enabled bool `mapstructure:"enabled"` // Serves Prometheus metrics at /metrics
+	} `mapstructure:"metrics"`
+	Tracing struct {
+		Exporter    string  `mapstructure:"exporter"` // none, stdout or otlp
+		Endpoint    string  `mapstructure:"endpoint"` // host:port of the OTLP/HTTP collector
+		Insecure    bool    `mapstructure:"insecure"` // Sends spans over plain HTTP instead of HTTPS
+		SampleRatio float64 `mapstructure:"sample_ratio"`
+		ServiceName string  `mapstructure:"service_name"`
+	} `mapstructure:"tracing"`
+	Logging struct {
+		Level  string `mapstructure:"level"`  // debug, info, warn or error
+		Format string `mapstructure:"format"` // json or text
+	} `mapstructure:"logging"`
+	AccessLog struct {
+		Enabled          bool     `mapstructure:"enabled"`
+		TrustedProxies   []string `mapstructure:"trusted_proxies"`    // Addresses or CIDR ranges allowed to set X-Forwarded-For
+		SearchSampleRate float64  `mapstructure:"search_sample_rate"` // Fraction of successful searches logged
+	} `mapstructure:"access_log"`
+	Health struct {
+		Timeout       time.Duration `mapstructure:"timeout"`        // Of each readiness check
+		ShutdownDelay time.Duration `mapstructure:"shutdown_delay"` // Time to report not ready before shutting down
+	} `mapstructure:"health"`
+	Auth struct {
+		KeysFile       string        `mapstructure:"keys_file"`       // YAML file of named, hashed API keys with their scopes
+		ReloadInterval time.Duration `mapstructure:"reload_interval"` // How often the keys file is checked for changes
+		JWT            struct {
+			Enabled         bool                `mapstructure:"enabled"`          // Accept bearer tokens of the gateway besides API keys
+			JWKS            string              `mapstructure:"jwks"`             // File path or http(s) URL of the signing keys
+			Issuer          string              `mapstructure:"issuer"`           // Required iss claim
+			Audience        string              `mapstructure:"audience"`         // Required aud claim
+			ScopeClaim      string              `mapstructure:"scope_claim"`      // Claim holding the scopes, a space-separated string or a list
+			ScopeMapping    []auth.ScopeMapping `mapstructure:"scope_mapping"`    // Scopes granted by other values of the scope claim
+			Leeway          time.Duration       `mapstructure:"leeway"`           // Clock skew tolerated when checking expiry
+			RefreshInterval time.Duration       `mapstructure:"refresh_interval"` // How often a JWKS URL is fetched again
+		} `mapstructure:"jwt"`
+	} `mapstructure:"auth"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -26,17 +132,17 @@ func LoadConfig() (*Config, error) {
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath("./config/")
 
+	// Nested keys can be overridden from the environment, e.g. STORAGE_BACKEND=memory
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err != nil {
-		log.Printf("Error reading config file: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
 
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
-		log.Printf("Unable to decode into struct: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("unable to decode into struct: %w", err)
 	}
 
 	return &config, nil
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"bitaksi-go-driver/internal/auth"
+	"bitaksi-go-driver/internal/clientip"
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/logging"
+)
+
+// Validate reports every setting that would make the service fail or misbehave
+func (c *Config) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+	checkPrecision := func(key string, precision int) {
+		check(precision >= 1 && precision <= geo.MaxGeohashPrecision, "%s must be between 1 and %d, got %d", key, geo.MaxGeohashPrecision, precision)
+	}
+
+	check(c.Server.Port != "", "server.port is required")
+	check(c.Server.APIKey != "" || c.Auth.KeysFile != "" || c.Auth.JWT.Enabled, "server.api_key, auth.keys_file or auth.jwt is required")
+	check(c.Auth.KeysFile == "" || c.Auth.ReloadInterval > 0, "auth.reload_interval must be positive")
+	if c.Auth.JWT.Enabled {
+		check(c.Auth.JWT.JWKS != "", "auth.jwt.jwks is required when bearer tokens are enabled")
+		check(c.Auth.JWT.Issuer != "" && c.Auth.JWT.Audience != "", "auth.jwt.issuer and auth.jwt.audience are required when bearer tokens are enabled")
+		check(c.Auth.JWT.Leeway >= 0, "auth.jwt.leeway must not be negative")
+		check(c.Auth.JWT.RefreshInterval > 0, "auth.jwt.refresh_interval must be positive")
+		for _, mapping := range c.Auth.JWT.ScopeMapping {
+			for _, scope := range mapping.Scopes {
+				check(slices.Contains(auth.AllScopes, scope), "auth.jwt.scope_mapping: unknown scope %q for %q", scope, mapping.Claim)
+			}
+		}
+	}
+
+	_, err := logging.ParseLevel(c.Logging.Level)
+	check(err == nil, "logging.level must be debug, info, warn or error, got %q", c.Logging.Level)
+	switch c.Logging.Format {
+	case "", logging.FormatJSON, logging.FormatText:
+	default:
+		check(false, "logging.format must be json or text, got %q", c.Logging.Format)
+	}
+	_, err = clientip.ParseTrusted(c.AccessLog.TrustedProxies)
+	check(err == nil, "access_log.trusted_proxies: %v", err)
+	check(c.AccessLog.SearchSampleRate >= 0 && c.AccessLog.SearchSampleRate <= 1, "access_log.search_sample_rate must be between 0 and 1")
+	check(c.Health.Timeout > 0, "health.timeout must be positive")
+	check(c.Health.ShutdownDelay >= 0, "health.shutdown_delay must not be negative")
+
+	switch c.Storage.Backend {
+	case "", "mongo":
+		check(c.MongoDB.Host != "" && c.MongoDB.Database != "", "mongodb.host and mongodb.database are required by the mongo backend")
+	case "postgres":
+		check(c.Postgres.Host != "" && c.Postgres.Database != "", "postgres.host and postgres.database are required by the postgres backend")
+	case "memory":
+	default:
+		check(false, "storage.backend must be mongo, postgres or memory, got %q", c.Storage.Backend)
+	}
+
+	switch c.ETA.Provider {
+	case "", "none", "heuristic":
+	case "osrm":
+		check(c.ETA.OSRMURL != "", "eta.osrm_url is required by the osrm provider")
+	default:
+		check(false, "eta.provider must be none, heuristic or osrm, got %q", c.ETA.Provider)
+	}
+	if c.ETA.Provider != "" && c.ETA.Provider != "none" {
+		check(c.ETA.Candidates > 1, "eta.candidates must be greater than 1 to rank drivers by ETA, got %d", c.ETA.Candidates)
+	}
+
+	check(c.Dispatch.OfferTimeout > 0, "dispatch.offer_timeout must be positive")
+	check(c.Dispatch.MaxOffers > 0, "dispatch.max_offers must be positive")
+	check(c.Reservation.TTL > 0, "reservation.ttl must be positive")
+	check(c.Matching.Candidates > 0, "matching.candidates must be positive")
+	check(c.Matching.MaxBatchSize > 0, "matching.max_batch_size must be positive")
+
+	checkPrecision("surge.precision", c.Surge.Precision)
+	check(c.Surge.Window > 0 && c.Surge.Buckets > 0, "surge.window and surge.buckets must be positive")
+	check(c.Surge.MinMultiplier <= c.Surge.MaxMultiplier, "surge.min_multiplier must not exceed surge.max_multiplier")
+	check(c.Surge.CellTTL >= 0, "surge.cell_ttl must not be negative")
+
+	checkPrecision("demand.precision", c.Demand.Precision)
+	check(c.Demand.Retention > 0, "demand.retention must be positive")
+	if c.Demand.Enabled {
+		check(c.Demand.SampleRate > 0 && c.Demand.SampleRate <= 1, "demand.sample_rate must be in (0, 1], got %v", c.Demand.SampleRate)
+		check(c.Demand.BufferSize > 0, "demand.buffer_size must be positive")
+		check(c.Demand.FlushInterval > 0, "demand.flush_interval must be positive")
+		// Surge counts the stored searches by the cells containing theirs
+		check(c.Demand.Precision >= c.Surge.Precision, "demand.precision must be at least surge.precision, got %d and %d", c.Demand.Precision, c.Surge.Precision)
+	}
+
+	if c.Cache.Enabled {
+		check(c.Cache.Address != "", "cache.address is required when the cache is enabled")
+	}
+	if c.SearchCache.Enabled {
+		check(c.SearchCache.TTL > 0, "search_cache.ttl must be positive")
+		checkPrecision("search_cache.precision", c.SearchCache.Precision)
+		check(c.SearchCache.MaxEntries > 0, "search_cache.max_entries must be positive")
+	}
+
+	switch c.Tracing.Exporter {
+	case "", "none":
+	case "stdout", "otlp":
+		check(c.Tracing.Exporter != "otlp" || c.Tracing.Endpoint != "", "tracing.endpoint is required by the otlp exporter")
+		check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be in [0, 1], got %v", c.Tracing.SampleRatio)
+	default:
+		check(false, "tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+
+	return errors.Join(problems...)
+}
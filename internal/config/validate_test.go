@@ -0,0 +1,159 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"bitaksi-go-driver/internal/auth"
+)
+
+func validConfig() *Config {
+	var cfg Config
+	cfg.Server.Port = ":8080"
+	cfg.Server.APIKey = "secret"
+	cfg.Storage.Backend = "memory"
+	cfg.Health.Timeout = 2 * time.Second
+	cfg.Dispatch.OfferTimeout = 15 * time.Second
+	cfg.Dispatch.MaxOffers = 5
+	cfg.Reservation.TTL = 30 * time.Second
+	cfg.Matching.Candidates = 10
+	cfg.Matching.MaxBatchSize = 200
+	cfg.Surge.Precision = 6
+	cfg.Surge.Window = 5 * time.Minute
+	cfg.Surge.Buckets = 10
+	cfg.Demand.Precision = 7
+	cfg.Demand.Retention = time.Hour
+	return &cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		modify        func(cfg *Config)
+		expectedError string
+	}{
+		{
+			name:   "Valid",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name:          "Missing API Key",
+			modify:        func(cfg *Config) { cfg.Server.APIKey = "" },
+			expectedError: "server.api_key, auth.keys_file or auth.jwt is required",
+		},
+		{
+			name: "JWT Without Issuer",
+			modify: func(cfg *Config) {
+				cfg.Server.APIKey = ""
+				cfg.Auth.JWT.Enabled = true
+				cfg.Auth.JWT.JWKS = "https://gateway.internal/.well-known/jwks.json"
+				cfg.Auth.JWT.Audience = "driver-service"
+				cfg.Auth.JWT.RefreshInterval = 15 * time.Minute
+			},
+			expectedError: "auth.jwt.issuer and auth.jwt.audience are required",
+		},
+		{
+			name: "JWT Mapping To Unknown Scope",
+			modify: func(cfg *Config) {
+				cfg.Auth.JWT.Enabled = true
+				cfg.Auth.JWT.JWKS = "config/jwks.json"
+				cfg.Auth.JWT.Issuer = "https://gateway.internal"
+				cfg.Auth.JWT.Audience = "driver-service"
+				cfg.Auth.JWT.RefreshInterval = 15 * time.Minute
+				cfg.Auth.JWT.ScopeMapping = []auth.ScopeMapping{{Claim: "dispatcher", Scopes: []string{"rides:delete"}}}
+			},
+			expectedError: `auth.jwt.scope_mapping: unknown scope "rides:delete" for "dispatcher"`,
+		},
+		{
+			name:          "Unknown Log Level",
+			modify:        func(cfg *Config) { cfg.Logging.Level = "verbose" },
+			expectedError: `logging.level must be debug, info, warn or error, got "verbose"`,
+		},
+		{
+			name:          "Invalid Trusted Proxy",
+			modify:        func(cfg *Config) { cfg.AccessLog.TrustedProxies = []string{"10.0.0.0/8", "lb.internal"} },
+			expectedError: `access_log.trusted_proxies: invalid trusted proxy "lb.internal"`,
+		},
+		{
+			name:          "Unknown Backend",
+			modify:        func(cfg *Config) { cfg.Storage.Backend = "cassandra" },
+			expectedError: `storage.backend must be mongo, postgres or memory, got "cassandra"`,
+		},
+		{
+			name:          "Mongo Without Host",
+			modify:        func(cfg *Config) { cfg.Storage.Backend = "mongo" },
+			expectedError: "mongodb.host and mongodb.database are required",
+		},
+		{
+			name: "ETA Without Candidates",
+			modify: func(cfg *Config) {
+				cfg.ETA.Provider = "heuristic"
+				cfg.ETA.Candidates = 1
+			},
+			expectedError: "eta.candidates must be greater than 1 to rank drivers by ETA, got 1",
+		},
+		{
+			name: "Invalid Sample Rate",
+			modify: func(cfg *Config) {
+				cfg.Demand.Enabled = true
+				cfg.Demand.SampleRate = 2
+				cfg.Demand.BufferSize = 10
+				cfg.Demand.FlushInterval = time.Second
+			},
+			expectedError: "demand.sample_rate must be in (0, 1], got 2",
+		},
+		{
+			name: "Demand Coarser Than Surge",
+			modify: func(cfg *Config) {
+				cfg.Demand.Enabled = true
+				cfg.Demand.SampleRate = 1
+				cfg.Demand.BufferSize = 10
+				cfg.Demand.FlushInterval = time.Second
+				cfg.Demand.Precision = 5
+			},
+			expectedError: "demand.precision must be at least surge.precision, got 5 and 6",
+		},
+		{
+			name: "Invalid Search Cache",
+			modify: func(cfg *Config) {
+				cfg.SearchCache.Enabled = true
+				cfg.SearchCache.TTL = time.Second
+				cfg.SearchCache.Precision = 13
+				cfg.SearchCache.MaxEntries = 10
+			},
+			expectedError: "search_cache.precision must be between 1 and 12, got 13",
+		},
+		{
+			name: "OTLP Without Endpoint",
+			modify: func(cfg *Config) {
+				cfg.Tracing.Exporter = "otlp"
+				cfg.Tracing.SampleRatio = 1
+			},
+			expectedError: "tracing.endpoint is required by the otlp exporter",
+		},
+		{
+			name:          "Unknown Exporter",
+			modify:        func(cfg *Config) { cfg.Tracing.Exporter = "zipkin" },
+			expectedError: `tracing.exporter must be none, stdout or otlp, got "zipkin"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("expected error containing %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -11,8 +12,9 @@ import (
 	"bitaksi-go-driver/internal/config"
 )
 
-// ConnectMongo connects to MongoDB and returns a client instance.
-func ConnectMongo(cfg *config.Config) (*mongo.Client, error) {
+// ConnectMongo connects to MongoDB and returns a client instance. The options, such as monitors,
+// are applied after the connection settings of the configuration.
+func ConnectMongo(cfg *config.Config, opts ...*options.ClientOptions) (*mongo.Client, error) {
 	uri := createMongoURI(cfg)
 
 	clientOptions := options.Client().ApplyURI(uri)
@@ -21,7 +23,7 @@ func ConnectMongo(cfg *config.Config) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, clientOptions)
+	client, err := mongo.Connect(ctx, append([]*options.ClientOptions{clientOptions}, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
@@ -31,7 +33,7 @@ func ConnectMongo(cfg *config.Config) (*mongo.Client, error) {
 		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
-	fmt.Println("Connected to MongoDB successfully!")
+	slog.Info("Connected to MongoDB")
 	return client, nil
 }
 
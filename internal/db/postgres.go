@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitaksi-go-driver/internal/config"
+)
+
+// ConnectPostgres connects to PostgreSQL and returns a connection pool.
+func ConnectPostgres(cfg *config.Config) (*pgxpool.Pool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, createPostgresURL(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	// Ping the PostgreSQL server to ensure connectivity
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping PostgreSQL: %w", err)
+	}
+
+	slog.Info("Connected to PostgreSQL")
+	return pool, nil
+}
+
+// createPostgresURL constructs the PostgreSQL connection URL from the configuration.
+func createPostgresURL(cfg *config.Config) string {
+	connURL := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(cfg.Postgres.Username, cfg.Postgres.Password),
+		Host:   fmt.Sprintf("%s:%d", cfg.Postgres.Host, cfg.Postgres.Port),
+		Path:   cfg.Postgres.Database,
+	}
+	if cfg.Postgres.SSLMode != "" {
+		connURL.RawQuery = url.Values{"sslmode": {cfg.Postgres.SSLMode}}.Encode()
+	}
+	return connURL.String()
+}
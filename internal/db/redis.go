@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"bitaksi-go-driver/internal/config"
+)
+
+// ConnectRedis connects to a Redis compatible server and returns a client instance.
+func ConnectRedis(cfg *config.Config) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Cache.Address,
+		Password: cfg.Cache.Password,
+		DB:       cfg.Cache.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Ping the Redis server to ensure connectivity
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	slog.Info("Connected to Redis")
+	return client, nil
+}
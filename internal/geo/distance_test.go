@@ -0,0 +1,88 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat1     float64
+		lon1     float64
+		lat2     float64
+		lon2     float64
+		expected float64
+	}{
+		{name: "Same Point", lat1: 41.0, lon1: 29.0, lat2: 41.0, lon2: 29.0, expected: 0},
+		{name: "One Degree Of Latitude", lat1: 0, lon1: 0, lat2: 1, lon2: 0, expected: 111319.5},
+		{name: "Across The Antimeridian", lat1: 0, lon1: 179.5, lat2: 0, lon2: -179.5, expected: 111319.5},
+		{name: "Pole To Pole", lat1: 90, lon1: 0, lat2: -90, lon2: 0, expected: math.Pi * EarthRadius},
+		{name: "Across The Bosphorus", lat1: 41.0422, lon1: 29.0083, lat2: 41.0451, lon2: 29.0339, expected: 2170},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := Distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(distance-tt.expected) > 10 {
+				t.Errorf("expected %.1f meters, got %.1f", tt.expected, distance)
+			}
+		})
+	}
+}
+
+func TestBearing(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat1     float64
+		lon1     float64
+		lat2     float64
+		lon2     float64
+		expected float64
+	}{
+		{name: "North", lat1: 41, lon1: 29, lat2: 42, lon2: 29, expected: 0},
+		{name: "East On The Equator", lat1: 0, lon1: 29, lat2: 0, lon2: 30, expected: 90},
+		{name: "South", lat1: 41, lon1: 29, lat2: 40, lon2: 29, expected: 180},
+		{name: "West Across The Antimeridian", lat1: 0, lon1: -179.5, lat2: 0, lon2: 179.5, expected: 270},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bearing := Bearing(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(bearing-tt.expected) > 1e-6 {
+				t.Errorf("expected %.6f degrees, got %.6f", tt.expected, bearing)
+			}
+		})
+	}
+}
+
+func TestDestination(t *testing.T) {
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		bearing   float64
+		distance  float64
+	}{
+		{name: "North", latitude: 41, longitude: 29, bearing: 0, distance: 1000},
+		{name: "North East", latitude: 41, longitude: 29, bearing: 45, distance: 2500},
+		{name: "Across The Antimeridian", latitude: 0, longitude: 179.999, bearing: 90, distance: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latitude, longitude := Destination(tt.latitude, tt.longitude, tt.bearing, tt.distance)
+			if longitude < -180 || longitude >= 180 {
+				t.Errorf("expected a normalised longitude, got %v", longitude)
+			}
+			if distance := Distance(tt.latitude, tt.longitude, latitude, longitude); math.Abs(distance-tt.distance) > 0.01 {
+				t.Errorf("expected to travel %.2f meters, travelled %.2f", tt.distance, distance)
+			}
+			if tt.distance > 0 && tt.longitude < 179 {
+				if bearing := Bearing(tt.latitude, tt.longitude, latitude, longitude); math.Abs(bearing-tt.bearing) > 1e-6 {
+					t.Errorf("expected bearing %.6f, got %.6f", tt.bearing, bearing)
+				}
+			}
+		})
+	}
+}
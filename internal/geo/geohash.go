@@ -0,0 +1,165 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// MaxGeohashPrecision is the longest geohash supported, cells are about 4.8cm x 4.8cm
+const MaxGeohashPrecision = 12
+
+// ErrInvalidGeohash is returned when decoding a malformed geohash
+var ErrInvalidGeohash = errors.New("invalid geohash")
+
+// Bounds is a latitude/longitude rectangle
+type Bounds struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// Center returns the middle of the rectangle
+func (b Bounds) Center() (latitude, longitude float64) {
+	return (b.MinLat + b.MaxLat) / 2, (b.MinLon + b.MaxLon) / 2
+}
+
+// Contains reports whether the point lies inside the rectangle. A rectangle whose MinLon is
+// greater than its MaxLon crosses the antimeridian.
+func (b Bounds) Contains(latitude, longitude float64) bool {
+	if latitude < b.MinLat || latitude > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return longitude >= b.MinLon && longitude <= b.MaxLon
+	}
+	return longitude >= b.MinLon || longitude <= b.MaxLon
+}
+
+// RandomPoint returns a point uniformly distributed over the area of a rectangle not crossing the
+// antimeridian, so that tall rectangles are not denser towards the poles
+func (b Bounds) RandomPoint(random *rand.Rand) (latitude, longitude float64) {
+	minSin := math.Sin(b.MinLat * math.Pi / 180)
+	maxSin := math.Sin(b.MaxLat * math.Pi / 180)
+
+	latitude = math.Asin(minSin+random.Float64()*(maxSin-minSin)) * 180 / math.Pi
+	longitude = b.MinLon + random.Float64()*(b.MaxLon-b.MinLon)
+	return latitude, longitude
+}
+
+// GeohashCellSize returns the height and width in degrees of geohash cells of the given precision.
+// Geohashes interleave 5 bits per character starting with longitude, so longitude gets the extra
+// bit for odd bit counts.
+func GeohashCellSize(precision int) (latHeight, lonWidth float64) {
+	latBits, lonBits := geohashBits(precision)
+	return 180 / math.Exp2(float64(latBits)), 360 / math.Exp2(float64(lonBits))
+}
+
+// GeohashCellIndex returns the row and column of the geohash cell containing the point, counted
+// from the south-west corner of the world. Cells can therefore be computed with plain arithmetic,
+// e.g. inside a database aggregation, and turned into geohashes with GeohashFromCellIndex.
+func GeohashCellIndex(latitude, longitude float64, precision int) (latIndex, lonIndex int64) {
+	latHeight, lonWidth := GeohashCellSize(precision)
+	latBits, lonBits := geohashBits(precision)
+
+	latIndex = int64(math.Floor((latitude + 90) / latHeight))
+	lonIndex = int64(math.Floor((longitude + 180) / lonWidth))
+
+	// The north pole and the antimeridian belong to the last row and column
+	return min(latIndex, int64(1)<<latBits-1), min(lonIndex, int64(1)<<lonBits-1)
+}
+
+// GeohashCellCount returns the number of geohash cells of the given precision that overlap the
+// rectangle, counting the columns on both sides of the antimeridian for rectangles crossing it
+func GeohashCellCount(b Bounds, precision int) int64 {
+	minLatIndex, minLonIndex := GeohashCellIndex(b.MinLat, b.MinLon, precision)
+	maxLatIndex, maxLonIndex := GeohashCellIndex(b.MaxLat, b.MaxLon, precision)
+
+	columns := maxLonIndex - minLonIndex + 1
+	if b.MinLon > b.MaxLon {
+		_, lonBits := geohashBits(precision)
+		columns += int64(1) << lonBits
+	}
+	return (maxLatIndex - minLatIndex + 1) * columns
+}
+
+// GeohashFromCellIndex encodes the cell at the given row and column as a geohash
+func GeohashFromCellIndex(latIndex, lonIndex int64, precision int) string {
+	latBits, lonBits := geohashBits(precision)
+
+	var hash strings.Builder
+	value, bits := 0, 0
+	for i := 0; i < 5*precision; i++ {
+		var bit int64
+		if i%2 == 0 {
+			lonBits--
+			bit = (lonIndex >> lonBits) & 1
+		} else {
+			latBits--
+			bit = (latIndex >> latBits) & 1
+		}
+
+		value = value<<1 | int(bit)
+		bits++
+		if bits == 5 {
+			hash.WriteByte(geohashAlphabet[value])
+			value, bits = 0, 0
+		}
+	}
+
+	return hash.String()
+}
+
+// EncodeGeohash returns the geohash of the given precision containing the point
+func EncodeGeohash(latitude, longitude float64, precision int) string {
+	latIndex, lonIndex := GeohashCellIndex(latitude, longitude, precision)
+	return GeohashFromCellIndex(latIndex, lonIndex, precision)
+}
+
+// DecodeGeohash returns the rectangle covered by the geohash
+func DecodeGeohash(hash string) (Bounds, error) {
+	if hash == "" || len(hash) > MaxGeohashPrecision {
+		return Bounds{}, ErrInvalidGeohash
+	}
+
+	var latIndex, lonIndex int64
+	bit := 0
+	for _, c := range hash {
+		value := strings.IndexRune(geohashAlphabet, c)
+		if value < 0 {
+			return Bounds{}, ErrInvalidGeohash
+		}
+
+		for shift := 4; shift >= 0; shift-- {
+			b := int64(value>>shift) & 1
+			if bit%2 == 0 {
+				lonIndex = lonIndex<<1 | b
+			} else {
+				latIndex = latIndex<<1 | b
+			}
+			bit++
+		}
+	}
+
+	return GeohashCellBounds(latIndex, lonIndex, len(hash)), nil
+}
+
+// GeohashCellBounds returns the rectangle covered by the cell at the given row and column
+func GeohashCellBounds(latIndex, lonIndex int64, precision int) Bounds {
+	latHeight, lonWidth := GeohashCellSize(precision)
+	return Bounds{
+		MinLat: float64(latIndex)*latHeight - 90,
+		MinLon: float64(lonIndex)*lonWidth - 180,
+		MaxLat: float64(latIndex+1)*latHeight - 90,
+		MaxLon: float64(lonIndex+1)*lonWidth - 180,
+	}
+}
+
+func geohashBits(precision int) (latBits, lonBits int) {
+	total := 5 * precision
+	return total / 2, (total + 1) / 2
+}
@@ -0,0 +1,110 @@
+package geo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestEncodeGeohash(t *testing.T) {
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		precision int
+		expected  string
+	}{
+		{name: "Istanbul", latitude: 41.0082, longitude: 28.9784, precision: 7, expected: "sxk973m"},
+		{name: "Jutland", latitude: 57.64911, longitude: 10.40744, precision: 11, expected: "u4pruydqqvj"},
+		{name: "South West Corner", latitude: -90, longitude: -180, precision: 3, expected: "000"},
+		{name: "North East Corner", latitude: 90, longitude: 180, precision: 3, expected: "zzz"},
+		{name: "Single Character", latitude: 41.0082, longitude: 28.9784, precision: 1, expected: "s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := EncodeGeohash(tt.latitude, tt.longitude, tt.precision)
+			if hash != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, hash)
+			}
+		})
+	}
+}
+
+func TestDecodeGeohash(t *testing.T) {
+	for precision := 1; precision <= 9; precision++ {
+		hash := EncodeGeohash(41.0082, 28.9784, precision)
+
+		bounds, err := DecodeGeohash(hash)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bounds.Contains(41.0082, 28.9784) {
+			t.Errorf("precision %d: bounds %+v do not contain the encoded point", precision, bounds)
+		}
+
+		latHeight, lonWidth := GeohashCellSize(precision)
+		if math.Abs(bounds.MaxLat-bounds.MinLat-latHeight) > 1e-9 || math.Abs(bounds.MaxLon-bounds.MinLon-lonWidth) > 1e-9 {
+			t.Errorf("precision %d: unexpected cell size %+v", precision, bounds)
+		}
+
+		latitude, longitude := bounds.Center()
+		if EncodeGeohash(latitude, longitude, precision) != hash {
+			t.Errorf("precision %d: center of %s encodes to a different cell", precision, hash)
+		}
+	}
+
+	for _, hash := range []string{"", "sxk9a", "sxk9hq9sxk9hq"} {
+		if _, err := DecodeGeohash(hash); err != ErrInvalidGeohash {
+			t.Errorf("expected ErrInvalidGeohash for %q, got %v", hash, err)
+		}
+	}
+}
+
+func TestBoundsContains(t *testing.T) {
+	antimeridian := Bounds{MinLat: -10, MinLon: 170, MaxLat: 10, MaxLon: -170}
+	if !antimeridian.Contains(0, 179) || !antimeridian.Contains(0, -175) || antimeridian.Contains(0, 0) {
+		t.Error("unexpected containment for bounds crossing the antimeridian")
+	}
+}
+
+func TestGeohashCellCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		bounds    Bounds
+		precision int
+		expected  int64
+	}{
+		{"single cell", Bounds{MinLat: 41, MinLon: 29, MaxLat: 41, MaxLon: 29}, 6, 1},
+		{"world", Bounds{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180}, 1, 32},
+		{"crossing the antimeridian", Bounds{MinLat: -10, MinLon: 170, MaxLat: 10, MaxLon: -170}, 2, 4 * 2},
+		{"world at high precision", Bounds{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180}, 9, 1 << 45},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if count := GeohashCellCount(tt.bounds, tt.precision); count != tt.expected {
+				t.Errorf("expected %d cells, got %d", tt.expected, count)
+			}
+		})
+	}
+}
+
+func TestBoundsRandomPoint(t *testing.T) {
+	bounds := Bounds{MinLat: 0, MinLon: 10, MaxLat: 60, MaxLon: 20}
+	random := rand.New(rand.NewSource(1))
+
+	// The band above 30° covers 1 - sin(30°) / sin(60°), about 42% of the area
+	above := 0
+	for i := 0; i < 10000; i++ {
+		latitude, longitude := bounds.RandomPoint(random)
+		if !bounds.Contains(latitude, longitude) {
+			t.Fatalf("point (%v, %v) outside the bounds", latitude, longitude)
+		}
+		if latitude > 30 {
+			above++
+		}
+	}
+	if above < 4000 || above > 4400 {
+		t.Errorf("expected about 4200 points above 30°, got %d", above)
+	}
+}
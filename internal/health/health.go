@@ -0,0 +1,103 @@
+// Package health checks whether the dependencies of the service can serve requests, for the
+// readiness probe of the orchestrator.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses of readiness reports and their checks
+const (
+	StatusOK           = "ok"
+	StatusFailed       = "failed"
+	StatusReady        = "ready"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check verifies one dependency, e.g. that the database answers pings
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of a check. Reports are served without authentication, so the
+// reason of a failed check is only logged, as it may name hosts or internals of the drivers.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Report tells whether the service is ready, with the result of every check by name
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Ready tells whether the report allows routing requests to the service
+func (r Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// Checker runs the checks concurrently, each within the timeout
+type Checker struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewChecker creates a checker of the given checks
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// ShutDown marks the service as shutting down, so that it reports not ready while it drains
+func (c *Checker) ShutDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready runs the checks and reports the service ready if every check passed and it is not shutting down
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusReady, Checks: make(map[string]CheckResult, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := c.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusNotReady
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	started := time.Now()
+	err := check.Check(ctx)
+	result := CheckResult{Status: StatusOK, LatencyMS: float64(time.Since(started).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusFailed
+		slog.WarnContext(ctx, "Readiness check failed", "check", check.Name, "error", err)
+	}
+	return result
+}
@@ -0,0 +1,45 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	healthy := Check{Name: "database", Check: func(ctx context.Context) error { return nil }}
+	failing := Check{Name: "index", Check: func(ctx context.Context) error { return errors.New("index is missing") }}
+	hanging := Check{Name: "cache", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	report := NewChecker(time.Second, healthy).Ready(context.Background())
+	if !report.Ready() || report.Checks["database"].Status != StatusOK {
+		t.Errorf("expected a ready report, got %+v", report)
+	}
+
+	report = NewChecker(50*time.Millisecond, healthy, failing, hanging).Ready(context.Background())
+	if report.Ready() || report.Status != StatusNotReady {
+		t.Fatalf("expected a not ready report, got %+v", report)
+	}
+	if result := report.Checks["index"]; result.Status != StatusFailed {
+		t.Errorf("unexpected index result %+v", result)
+	}
+	if result := report.Checks["cache"]; result.Status != StatusFailed || result.LatencyMS < 50 {
+		t.Errorf("expected the cache check to time out, got %+v", result)
+	}
+}
+
+func TestChecker_ShutDown(t *testing.T) {
+	checker := NewChecker(time.Second)
+	if !checker.Ready(context.Background()).Ready() {
+		t.Fatal("expected a checker without checks to be ready")
+	}
+
+	checker.ShutDown()
+	if report := checker.Ready(context.Background()); report.Status != StatusShuttingDown {
+		t.Errorf("expected %s, got %s", StatusShuttingDown, report.Status)
+	}
+}
@@ -0,0 +1,244 @@
+// Package loadtest drives the search and location update APIs of a running instance and reports
+// throughput, latency percentiles and errors, to compare releases against each other.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"bitaksi-go-driver/internal/geo"
+)
+
+// Spatial distributions of the generated requests
+const (
+	// DistributionUniform spreads requests evenly over the bounds
+	DistributionUniform = "uniform"
+	// DistributionHotspots concentrates requests around a few random points of the bounds, like
+	// demand around stations and venues
+	DistributionHotspots = "hotspots"
+)
+
+// Operations of the load test
+const (
+	OperationSearch = "search"
+	OperationUpdate = "update"
+)
+
+// maxMove is the farthest in meters a location update moves a driver from their last known position
+const maxMove = 200
+
+// ErrInvalidOptions is returned when the load test cannot run with the given options
+var ErrInvalidOptions = errors.New("invalid load test options")
+
+// Options configure a load test
+type Options struct {
+	BaseURL      string // e.g. http://localhost:8080
+	APIKey       string
+	Concurrency  int           // Number of requests in flight at most
+	Rate         float64       // Requests per second across all workers, unlimited if zero
+	Duration     time.Duration // How long to send requests
+	Timeout      time.Duration // Timeout of each request
+	UpdateRatio  float64       // Fraction of the requests that are location updates
+	Bounds       geo.Bounds    // Searches and updates lie within these bounds
+	Distribution string
+	Hotspots     int // Number of hotspots of the hotspots distribution
+	Radius       int // Search radius in meters
+	Seed         int64
+	DriverIDs    []string // Drivers to update before searches find any
+}
+
+// Validate returns ErrInvalidOptions describing the first invalid option
+func (o Options) Validate() error {
+	bounds := o.Bounds
+	switch {
+	case o.BaseURL == "":
+		return fmt.Errorf("%w: the base URL is required", ErrInvalidOptions)
+	case o.Concurrency <= 0:
+		return fmt.Errorf("%w: the concurrency must be positive", ErrInvalidOptions)
+	case o.Rate < 0:
+		return fmt.Errorf("%w: the rate must not be negative", ErrInvalidOptions)
+	case o.Duration <= 0 || o.Timeout <= 0:
+		return fmt.Errorf("%w: the duration and timeout must be positive", ErrInvalidOptions)
+	case o.UpdateRatio < 0 || o.UpdateRatio > 1:
+		return fmt.Errorf("%w: the update ratio must be between 0 and 1", ErrInvalidOptions)
+	case bounds.MinLat >= bounds.MaxLat || bounds.MinLon >= bounds.MaxLon ||
+		bounds.MinLat < -90 || bounds.MaxLat > 90 || bounds.MinLon < -180 || bounds.MaxLon > 180:
+		return fmt.Errorf("%w: the bounds must be a non-empty rectangle not crossing the antimeridian", ErrInvalidOptions)
+	case o.Distribution != DistributionUniform && o.Distribution != DistributionHotspots:
+		return fmt.Errorf("%w: the distribution must be %s or %s", ErrInvalidOptions, DistributionUniform, DistributionHotspots)
+	case o.Distribution == DistributionHotspots && o.Hotspots <= 0:
+		return fmt.Errorf("%w: the number of hotspots must be positive", ErrInvalidOptions)
+	case o.Radius <= 0:
+		return fmt.Errorf("%w: the radius must be positive", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// Run sends requests until the duration has passed or the context is done and reports the results.
+// With a rate, latencies are measured from when each request was due rather than sent, so that a
+// server too slow to keep up shows in the percentiles instead of only lowering the throughput.
+func Run(ctx context.Context, options Options) (*Report, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Duration)
+	defer cancel()
+
+	client := &http.Client{
+		Timeout:   options.Timeout,
+		Transport: &http.Transport{MaxIdleConnsPerHost: options.Concurrency},
+	}
+	defer client.CloseIdleConnections()
+
+	random := rand.New(rand.NewSource(options.Seed))
+	points := newPointGenerator(options, random)
+	drivers := newDriverPool(options.DriverIDs)
+	recorder := newRecorder()
+
+	var due <-chan time.Time
+	if options.Rate > 0 {
+		due = pace(ctx, options.Rate)
+	}
+
+	started := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < options.Concurrency; i++ {
+		worker := &worker{
+			client:   client,
+			options:  options,
+			random:   rand.New(rand.NewSource(options.Seed + int64(i) + 1)),
+			points:   points,
+			drivers:  drivers,
+			recorder: recorder,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.run(ctx, due)
+		}()
+	}
+	wg.Wait()
+
+	return recorder.report(options, started, time.Since(started)), nil
+}
+
+// pace sends the time each request is due at the given rate until the context is done. Requests
+// fall behind schedule rather than being dropped when every worker is busy.
+func pace(ctx context.Context, rate float64) <-chan time.Time {
+	due := make(chan time.Time)
+	interval := time.Duration(float64(time.Second) / rate)
+
+	go func() {
+		defer close(due)
+
+		next := time.Now()
+		for {
+			if wait := time.Until(next); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case due <- next:
+			}
+			next = next.Add(interval)
+		}
+	}()
+	return due
+}
+
+// pointGenerator draws request positions from the spatial distribution
+type pointGenerator struct {
+	bounds   geo.Bounds
+	hotspots [][2]float64
+	spread   float64 // standard deviation in meters of the distance to a hotspot
+}
+
+func newPointGenerator(options Options, random *rand.Rand) *pointGenerator {
+	generator := &pointGenerator{bounds: options.Bounds}
+	if options.Distribution != DistributionHotspots {
+		return generator
+	}
+
+	for i := 0; i < options.Hotspots; i++ {
+		latitude, longitude := options.Bounds.RandomPoint(random)
+		generator.hotspots = append(generator.hotspots, [2]float64{latitude, longitude})
+	}
+
+	// 95% of the requests around a hotspot lie within a tenth of the shorter side of the bounds
+	height := geo.Distance(options.Bounds.MinLat, options.Bounds.MinLon, options.Bounds.MaxLat, options.Bounds.MinLon)
+	latitude, _ := options.Bounds.Center()
+	width := geo.Distance(latitude, options.Bounds.MinLon, latitude, options.Bounds.MaxLon)
+	generator.spread = math.Min(height, width) / 20
+	return generator
+}
+
+func (g *pointGenerator) point(random *rand.Rand) (latitude, longitude float64) {
+	if len(g.hotspots) == 0 {
+		return g.bounds.RandomPoint(random)
+	}
+
+	hotspot := g.hotspots[random.Intn(len(g.hotspots))]
+	latitude, longitude = geo.Destination(hotspot[0], hotspot[1], random.Float64()*360, math.Abs(random.NormFloat64())*g.spread)
+	return clamp(g.bounds, latitude, longitude)
+}
+
+func clamp(bounds geo.Bounds, latitude, longitude float64) (float64, float64) {
+	return math.Max(bounds.MinLat, math.Min(bounds.MaxLat, latitude)),
+		math.Max(bounds.MinLon, math.Min(bounds.MaxLon, longitude))
+}
+
+// driverPool holds the drivers known to exist, with their last known positions if any
+type driverPool struct {
+	mu        sync.Mutex
+	ids       []string
+	positions map[string]*[2]float64 // nil while the position is unknown
+}
+
+func newDriverPool(ids []string) *driverPool {
+	pool := &driverPool{positions: make(map[string]*[2]float64)}
+	for _, id := range ids {
+		pool.add(id, nil)
+	}
+	return pool
+}
+
+// add remembers a driver and, if not nil, their position
+func (p *driverPool) add(id string, position *[2]float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current, ok := p.positions[id]
+	if !ok {
+		p.ids = append(p.ids, id)
+	}
+	if position != nil {
+		current = position
+	}
+	p.positions[id] = current
+}
+
+// pick returns a random known driver and their last known position, nil if unknown
+func (p *driverPool) pick(random *rand.Rand) (id string, position *[2]float64, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.ids) == 0 {
+		return "", nil, false
+	}
+	id = p.ids[random.Intn(len(p.ids))]
+	return id, p.positions[id], true
+}
@@ -0,0 +1,207 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/api"
+	"bitaksi-go-driver/internal/config"
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+// driverServiceStub finds one driver in the northern half of the bounds and fails every tenth update
+type driverServiceStub struct {
+	driver models.DriverWithDistance
+
+	mu      sync.Mutex
+	updates int
+	unknown int
+}
+
+func (s *driverServiceStub) ImportLocations(ctx context.Context) error {
+	return nil
+}
+
+func (s *driverServiceStub) FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
+	if latitude < 41 {
+		return nil, repository.ErrDriverNotFound
+	}
+	driver := s.driver
+	return &driver, nil
+}
+
+func (s *driverServiceStub) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id != s.driver.ID {
+		s.unknown++
+		return repository.ErrDriverNotFound
+	}
+	s.updates++
+	if s.updates%10 == 0 {
+		return errors.New("database unavailable")
+	}
+	return nil
+}
+
+var istanbul = geo.Bounds{MinLat: 40.95, MinLon: 28.9, MaxLat: 41.05, MaxLon: 29.1}
+
+func newServer(t *testing.T) (*httptest.Server, *driverServiceStub) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Server.APIKey = "test-api-key"
+	driverService := &driverServiceStub{driver: models.DriverWithDistance{
+		ID:       primitive.NewObjectID(),
+		Location: models.Location{Type: "Point", Coordinates: []float64{29, 41.02}},
+	}}
+
+	server := httptest.NewServer(api.SetupRouter(driverService, cfg))
+	t.Cleanup(server.Close)
+	return server, driverService
+}
+
+func options(baseURL string) Options {
+	return Options{
+		BaseURL:      baseURL,
+		APIKey:       "test-api-key",
+		Concurrency:  4,
+		Duration:     300 * time.Millisecond,
+		Timeout:      time.Second,
+		UpdateRatio:  0.3,
+		Bounds:       istanbul,
+		Distribution: DistributionUniform,
+		Radius:       1000,
+		Seed:         1,
+	}
+}
+
+func TestRun(t *testing.T) {
+	for _, distribution := range []string{DistributionUniform, DistributionHotspots} {
+		t.Run(distribution, func(t *testing.T) {
+			server, driverService := newServer(t)
+			opts := options(server.URL)
+			opts.Distribution = distribution
+			opts.Hotspots = 3
+
+			report, err := Run(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+
+			search, update := report.Operations[OperationSearch], report.Operations[OperationUpdate]
+			if search.Requests == 0 || update.Requests == 0 {
+				t.Fatalf("expected searches and updates, got %+v", report.Operations)
+			}
+			if report.Total.Requests != search.Requests+update.Requests {
+				t.Errorf("expected the total to add up, got %d", report.Total.Requests)
+			}
+			if search.Errors != 0 || search.NotFound == 0 || search.NotFound == search.Requests {
+				t.Errorf("expected found and not found searches without errors, got %+v", search)
+			}
+			if update.ErrorKinds["http_500"] == 0 || update.Errors != update.ErrorKinds["http_500"] {
+				t.Errorf("expected failed updates by status, got %+v", update.ErrorKinds)
+			}
+			if driverService.unknown != 0 {
+				t.Errorf("expected updates of found drivers only, got %d updates of unknown drivers", driverService.unknown)
+			}
+
+			latency := report.Total.Latency
+			if latency.Min <= 0 || latency.Min > latency.P50 || latency.P50 > latency.P95 || latency.P95 > latency.P99 || latency.P99 > latency.Max {
+				t.Errorf("expected ordered latency percentiles, got %+v", latency)
+			}
+			if report.Total.Throughput <= 0 {
+				t.Errorf("expected a positive throughput, got %v", report.Total.Throughput)
+			}
+		})
+	}
+}
+
+func TestRun_Rate(t *testing.T) {
+	server, _ := newServer(t)
+	opts := options(server.URL)
+	opts.Rate = 50
+	opts.Duration = 500 * time.Millisecond
+
+	report, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Total.Requests < 15 || report.Total.Requests > 30 {
+		t.Errorf("expected about 25 requests at 50 per second, got %d", report.Total.Requests)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	server, _ := newServer(t)
+	unauthorized := options(server.URL)
+	unauthorized.APIKey = "wrong-key"
+
+	report, err := Run(context.Background(), unauthorized)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Total.Errors == 0 || report.Total.Errors != report.Total.ErrorKinds["http_401"] || report.Total.ErrorRate != 1 {
+		t.Errorf("expected every request to be unauthorized, got %+v", report.Total)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	report, err = Run(context.Background(), options(closed.URL))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Total.ErrorKinds[outcomeConnectionRefused] == 0 {
+		t.Errorf("expected refused connections, got %+v", report.Total.ErrorKinds)
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *Options)
+	}{
+		{name: "No Base URL", modify: func(o *Options) { o.BaseURL = "" }},
+		{name: "No Concurrency", modify: func(o *Options) { o.Concurrency = 0 }},
+		{name: "Negative Rate", modify: func(o *Options) { o.Rate = -1 }},
+		{name: "No Duration", modify: func(o *Options) { o.Duration = 0 }},
+		{name: "Update Ratio Above One", modify: func(o *Options) { o.UpdateRatio = 1.5 }},
+		{name: "Empty Bounds", modify: func(o *Options) { o.Bounds.MaxLat = o.Bounds.MinLat }},
+		{name: "Unknown Distribution", modify: func(o *Options) { o.Distribution = "gaussian" }},
+		{name: "No Hotspots", modify: func(o *Options) { o.Distribution = DistributionHotspots }},
+		{name: "No Radius", modify: func(o *Options) { o.Radius = 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := options("http://localhost:8080")
+			tt.modify(&opts)
+			if err := opts.Validate(); !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("expected ErrInvalidOptions, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	for p, expected := range map[float64]time.Duration{0: time.Millisecond, 50: 50 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := percentile(latencies, p); got != expected {
+			t.Errorf("p%v: expected %s, got %s", p, expected, got)
+		}
+	}
+}
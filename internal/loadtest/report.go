@@ -0,0 +1,157 @@
+package loadtest
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Outcomes of requests besides the HTTP status of failed responses, which are recorded as http_<status>
+const (
+	outcomeOK                = "ok"
+	outcomeNotFound          = "not_found" // A search found no driver, which is not an error
+	outcomeInvalidResponse   = "invalid_response"
+	outcomeTimeout           = "timeout"
+	outcomeCanceled          = "canceled"
+	outcomeConnectionRefused = "connection_refused"
+	outcomeConnectionReset   = "connection_reset"
+	outcomeTransportError    = "transport_error"
+)
+
+// Report summarises a load test. It is written as JSON, so runs can be compared against each other.
+type Report struct {
+	StartedAt   time.Time                  `json:"started_at"`
+	Duration    float64                    `json:"duration_seconds"`
+	Concurrency int                        `json:"concurrency"`
+	TargetRate  float64                    `json:"target_rate,omitempty"`
+	Total       OperationReport            `json:"total"`
+	Operations  map[string]OperationReport `json:"operations"`
+}
+
+// OperationReport summarises the requests of one operation
+type OperationReport struct {
+	Requests   int            `json:"requests"`
+	Errors     int            `json:"errors"`
+	NotFound   int            `json:"not_found,omitempty"`
+	Throughput float64        `json:"throughput"` // requests per second
+	ErrorRate  float64        `json:"error_rate"`
+	Latency    LatencyReport  `json:"latency_ms"`
+	ErrorKinds map[string]int `json:"error_kinds,omitempty"`
+}
+
+// LatencyReport holds latency statistics in milliseconds
+type LatencyReport struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// recorder collects the outcome and latency of every request
+type recorder struct {
+	mu         sync.Mutex
+	operations map[string]*operationResults
+}
+
+type operationResults struct {
+	latencies []time.Duration
+	outcomes  map[string]int
+}
+
+func newRecorder() *recorder {
+	return &recorder{operations: make(map[string]*operationResults)}
+}
+
+func (r *recorder) record(operation, outcome string, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results, ok := r.operations[operation]
+	if !ok {
+		results = &operationResults{outcomes: make(map[string]int)}
+		r.operations[operation] = results
+	}
+	results.latencies = append(results.latencies, latency)
+	results.outcomes[outcome]++
+}
+
+func (r *recorder) report(options Options, started time.Time, elapsed time.Duration) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{
+		StartedAt:   started.UTC(),
+		Duration:    elapsed.Seconds(),
+		Concurrency: options.Concurrency,
+		TargetRate:  options.Rate,
+		Operations:  make(map[string]OperationReport, len(r.operations)),
+	}
+
+	total := &operationResults{outcomes: make(map[string]int)}
+	for operation, results := range r.operations {
+		report.Operations[operation] = results.summarise(elapsed)
+		total.latencies = append(total.latencies, results.latencies...)
+		for outcome, count := range results.outcomes {
+			total.outcomes[outcome] += count
+		}
+	}
+	report.Total = total.summarise(elapsed)
+
+	return report
+}
+
+func (o *operationResults) summarise(elapsed time.Duration) OperationReport {
+	report := OperationReport{Requests: len(o.latencies)}
+	if report.Requests == 0 {
+		return report
+	}
+
+	for outcome, count := range o.outcomes {
+		switch outcome {
+		case outcomeOK:
+		case outcomeNotFound:
+			report.NotFound = count
+		default:
+			if report.ErrorKinds == nil {
+				report.ErrorKinds = make(map[string]int)
+			}
+			report.ErrorKinds[outcome] = count
+			report.Errors += count
+		}
+	}
+	report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	report.ErrorRate = float64(report.Errors) / float64(report.Requests)
+
+	latencies := append([]time.Duration(nil), o.latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var sum time.Duration
+	for _, latency := range latencies {
+		sum += latency
+	}
+	report.Latency = LatencyReport{
+		Min:  milliseconds(latencies[0]),
+		Mean: milliseconds(sum / time.Duration(len(latencies))),
+		P50:  milliseconds(percentile(latencies, 50)),
+		P95:  milliseconds(percentile(latencies, 95)),
+		P99:  milliseconds(percentile(latencies, 99)),
+		Max:  milliseconds(latencies[len(latencies)-1]),
+	}
+	return report
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
@@ -0,0 +1,156 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// worker sends one request at a time
+type worker struct {
+	client   *http.Client
+	options  Options
+	random   *rand.Rand
+	points   *pointGenerator
+	drivers  *driverPool
+	recorder *recorder
+}
+
+// run sends requests until the context is done, when due if paced and back to back otherwise
+func (w *worker) run(ctx context.Context, due <-chan time.Time) {
+	for {
+		start := time.Now()
+		if due != nil {
+			var ok bool
+			if start, ok = <-due; !ok {
+				return
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+
+		operation, outcome := w.send(ctx)
+		if ctx.Err() != nil && (outcome == outcomeCanceled || outcome == outcomeTimeout) {
+			// The test ended during the request, which says nothing about the server
+			return
+		}
+		w.recorder.record(operation, outcome, time.Since(start))
+	}
+}
+
+// send makes a search or, as often as the update ratio says and once a driver is known, a location update
+func (w *worker) send(ctx context.Context) (operation, outcome string) {
+	if w.random.Float64() < w.options.UpdateRatio {
+		if id, position, ok := w.drivers.pick(w.random); ok {
+			return OperationUpdate, w.update(ctx, id, position)
+		}
+	}
+	return OperationSearch, w.search(ctx)
+}
+
+func (w *worker) search(ctx context.Context) string {
+	latitude, longitude := w.points.point(w.random)
+	query := url.Values{
+		"latitude":  {strconv.FormatFloat(latitude, 'f', -1, 64)},
+		"longitude": {strconv.FormatFloat(longitude, 'f', -1, 64)},
+		"radius":    {strconv.Itoa(w.options.Radius)},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, w.options.BaseURL+"/driver/api/v1/search?"+query.Encode(), nil)
+	if err != nil {
+		return classify(err)
+	}
+
+	var driver models.DriverWithDistance
+	outcome := w.do(request, &driver)
+	if outcome == outcomeOK && !driver.ID.IsZero() && len(driver.Location.Coordinates) == 2 {
+		// Found drivers are updated later on, so updates hit drivers that exist
+		w.drivers.add(driver.ID.Hex(), &[2]float64{driver.Location.Coordinates[1], driver.Location.Coordinates[0]})
+	}
+	return outcome
+}
+
+func (w *worker) update(ctx context.Context, id string, position *[2]float64) string {
+	var latitude, longitude float64
+	if position == nil {
+		latitude, longitude = w.points.point(w.random)
+	} else {
+		latitude, longitude = geo.Destination(position[0], position[1], w.random.Float64()*360, w.random.Float64()*maxMove)
+		latitude, longitude = clamp(w.options.Bounds, latitude, longitude)
+	}
+
+	body, err := json.Marshal(map[string]float64{"latitude": latitude, "longitude": longitude})
+	if err != nil {
+		return classify(err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut,
+		w.options.BaseURL+"/driver/api/v1/drivers/"+url.PathEscape(id)+"/location", bytes.NewReader(body))
+	if err != nil {
+		return classify(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	outcome := w.do(request, nil)
+	if outcome == outcomeOK {
+		w.drivers.add(id, &[2]float64{latitude, longitude})
+	}
+	return outcome
+}
+
+// do sends the request and decodes a successful response into result if it is not nil
+func (w *worker) do(request *http.Request, result interface{}) string {
+	request.Header.Set("Authorization", w.options.APIKey)
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return classify(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, response.Body)
+		if response.StatusCode == http.StatusNotFound && request.Method == http.MethodGet {
+			return outcomeNotFound
+		}
+		return fmt.Sprintf("http_%d", response.StatusCode)
+	}
+
+	if result == nil {
+		io.Copy(io.Discard, response.Body)
+		return outcomeOK
+	}
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return outcomeInvalidResponse
+	}
+	return outcomeOK
+}
+
+// classify names the failure of a request that got no response
+func classify(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return outcomeCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return outcomeTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return outcomeConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return outcomeConnectionReset
+	default:
+		return outcomeTransportError
+	}
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logger.DebugContext(context.Background(), "hidden")
+	logger.InfoContext(WithRequestID(context.Background(), "abc-123"), "Driver moved", "driver_id", "42")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 record above the debug level, got %d: %s", len(lines), buf.String())
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("expected a JSON record: %v", err)
+	}
+	if record["msg"] != "Driver moved" || record["request_id"] != "abc-123" || record["driver_id"] != "42" {
+		t.Errorf("unexpected record %v", record)
+	}
+}
+
+func TestNew_Text(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "debug", FormatText)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logger.With("component", "cache").DebugContext(WithRequestID(context.Background(), "abc-123"), "Cache miss")
+	if line := buf.String(); !strings.Contains(line, "request_id=abc-123") || !strings.Contains(line, "component=cache") {
+		t.Errorf("unexpected record %q", line)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "verbose", FormatJSON); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,123 @@
+// Package matching solves min-cost assignment problems between riders and drivers.
+package matching
+
+import "math"
+
+// Assign solves the rectangular assignment problem for the cost matrix using the Hungarian
+// algorithm. cost[i][j] is the cost of assigning row i to column j; +Inf marks pairs that must
+// not be assigned. It returns, for every row, the assigned column or -1 if the row stays
+// unassigned. Among all assignments it first maximizes the number of assigned rows and then
+// minimizes their total cost.
+func Assign(cost [][]float64) []int {
+	rows := len(cost)
+	if rows == 0 {
+		return nil
+	}
+	cols := len(cost[0])
+
+	assignment := make([]int, rows)
+	for i := range assignment {
+		assignment[i] = -1
+	}
+	if cols == 0 {
+		return assignment
+	}
+
+	// Forbidden pairs get a penalty larger than any combination of allowed pairs, so the
+	// optimum never trades an allowed assignment for a forbidden one
+	penalty := 1.0
+	for _, row := range cost {
+		for _, c := range row {
+			if !math.IsInf(c, 1) {
+				penalty += math.Abs(c)
+			}
+		}
+	}
+
+	// The algorithm below needs at least as many columns as rows, so transpose if necessary
+	transposed := rows > cols
+	n, m := rows, cols
+	if transposed {
+		n, m = cols, rows
+	}
+	at := func(i, j int) float64 {
+		if transposed {
+			i, j = j, i
+		}
+		c := cost[i][j]
+		if math.IsInf(c, 1) {
+			return penalty
+		}
+		return c
+	}
+
+	// Potentials-based Hungarian algorithm, O(n^2 * m), with 1-based indices
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	match := make([]int, m+1) // match[j] is the row assigned to column j
+	way := make([]int, m+1)
+
+	for i := 1; i <= n; i++ {
+		match[0] = i
+		j0 := 0
+		minv := make([]float64, m+1)
+		used := make([]bool, m+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+
+		for match[j0] != 0 {
+			used[j0] = true
+			i0 := match[j0]
+			delta := math.Inf(1)
+			j1 := 0
+
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				cur := at(i0-1, j-1) - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[match[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+		}
+
+		// Augment along the alternating path
+		for j0 != 0 {
+			j1 := way[j0]
+			match[j0] = match[j1]
+			j0 = j1
+		}
+	}
+
+	for j := 1; j <= m; j++ {
+		if match[j] == 0 {
+			continue
+		}
+		row, col := match[j]-1, j-1
+		if transposed {
+			row, col = col, row
+		}
+		if !math.IsInf(cost[row][col], 1) {
+			assignment[row] = col
+		}
+	}
+
+	return assignment
+}
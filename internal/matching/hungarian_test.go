@@ -0,0 +1,145 @@
+package matching
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+var inf = math.Inf(1)
+
+func TestAssign(t *testing.T) {
+	tests := []struct {
+		name     string
+		cost     [][]float64
+		expected []int
+	}{
+		{
+			name:     "Empty",
+			cost:     nil,
+			expected: nil,
+		},
+		{
+			name: "Greedy Is Not Optimal",
+			// Greedy nearest-first gives rider 0 driver 0 (1) and rider 1 driver 1 (100) = 101,
+			// the optimum is 2 + 3 = 5
+			cost: [][]float64{
+				{1, 2},
+				{3, 100},
+			},
+			expected: []int{1, 0},
+		},
+		{
+			name: "Classic Square",
+			cost: [][]float64{
+				{4, 1, 3},
+				{2, 0, 5},
+				{3, 2, 2},
+			},
+			expected: []int{1, 0, 2},
+		},
+		{
+			name: "More Drivers Than Riders",
+			cost: [][]float64{
+				{9, 2, 7, 8},
+				{6, 4, 3, 7},
+			},
+			expected: []int{1, 2},
+		},
+		{
+			name: "More Riders Than Drivers",
+			cost: [][]float64{
+				{5},
+				{1},
+				{3},
+			},
+			expected: []int{-1, 0, -1},
+		},
+		{
+			name: "Forbidden Pairs Leave Riders Unmatched",
+			cost: [][]float64{
+				{inf, inf},
+				{1, inf},
+				{2, 3},
+			},
+			expected: []int{-1, 0, 1},
+		},
+		{
+			name: "Maximizes Matches Before Cost",
+			// Pairing rider 0 with driver 0 is cheapest but would leave rider 1 unmatched
+			cost: [][]float64{
+				{1, 50},
+				{2, inf},
+			},
+			expected: []int{1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assignment := Assign(tt.cost)
+			if !reflect.DeepEqual(assignment, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, assignment)
+			}
+		})
+	}
+}
+
+func TestAssign_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+
+	for iteration := 0; iteration < 200; iteration++ {
+		rows, cols := 1+rng.Intn(5), 1+rng.Intn(5)
+		cost := make([][]float64, rows)
+		for i := range cost {
+			cost[i] = make([]float64, cols)
+			for j := range cost[i] {
+				cost[i][j] = float64(rng.Intn(100))
+				if rng.Intn(4) == 0 {
+					cost[i][j] = inf
+				}
+			}
+		}
+
+		matched, total := score(cost, Assign(cost))
+		bestMatched, bestTotal := bruteForce(cost, 0, make([]bool, cols))
+		if matched != bestMatched || total != bestTotal {
+			t.Fatalf("cost %v: expected %d matches costing %v, got %d costing %v", cost, bestMatched, bestTotal, matched, total)
+		}
+	}
+}
+
+func score(cost [][]float64, assignment []int) (int, float64) {
+	matched, total := 0, 0.0
+	for i, j := range assignment {
+		if j >= 0 {
+			matched++
+			total += cost[i][j]
+		}
+	}
+	return matched, total
+}
+
+// bruteForce returns the maximum number of matches and their minimum cost for rows from row on
+func bruteForce(cost [][]float64, row int, used []bool) (int, float64) {
+	if row == len(cost) {
+		return 0, 0
+	}
+
+	bestMatched, bestTotal := bruteForce(cost, row+1, used)
+	for j := range used {
+		if used[j] || math.IsInf(cost[row][j], 1) {
+			continue
+		}
+		used[j] = true
+		matched, total := bruteForce(cost, row+1, used)
+		used[j] = false
+
+		matched, total = matched+1, total+cost[row][j]
+		if matched > bestMatched || (matched == bestMatched && total < bestTotal) {
+			bestMatched, bestTotal = matched, total
+		}
+	}
+	return bestMatched, bestTotal
+}
@@ -0,0 +1,153 @@
+// Package metrics collects Prometheus metrics of the HTTP API, the storage backend and the MongoDB
+// connection pool, and serves them in the Prometheus exposition format.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+
+	"bitaksi-go-driver/internal/repository"
+)
+
+// Results of repository operations
+const (
+	ResultOK       = "ok"
+	ResultNotFound = "not_found"
+	ResultError    = "error"
+)
+
+// Metrics holds the collectors of the service in their own registry, so tests can create as many as needed
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests      *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	operationDuration *prometheus.HistogramVec
+	importedRows      prometheus.Counter
+	poolOpen          prometheus.Gauge
+	poolInUse         prometheus.Gauge
+	poolCheckouts     *prometheus.CounterVec
+	poolCheckoutWait  prometheus.Histogram
+	poolClears        prometheus.Counter
+}
+
+// New creates the collectors and registers them along with the Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route template, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of HTTP requests by route template, method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "repository_operation_duration_seconds",
+			Help:    "Latency of storage backend operations by operation and result: ok, not_found or error.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 15),
+		}, []string{"operation", "result"}),
+		importedRows: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "driver_import_rows_total",
+			Help: "Drivers saved by imports.",
+		}),
+		poolOpen: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mongodb_pool_connections_open",
+			Help: "Open connections of the MongoDB connection pool.",
+		}),
+		poolInUse: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mongodb_pool_connections_in_use",
+			Help: "Connections of the MongoDB connection pool checked out by operations.",
+		}),
+		poolCheckouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mongodb_pool_checkouts_total",
+			Help: "Connection checkouts from the MongoDB connection pool by result: ok or the reason of the failure.",
+		}, []string{"result"}),
+		poolCheckoutWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "mongodb_pool_checkout_duration_seconds",
+			Help:    "Time operations waited to check out a connection from the MongoDB connection pool.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}),
+		poolClears: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mongodb_pool_clears_total",
+			Help: "Times the MongoDB connection pool was cleared after a server error.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.operationDuration,
+		m.importedRows,
+		m.poolOpen,
+		m.poolInUse,
+		m.poolCheckouts,
+		m.poolCheckoutWait,
+		m.poolClears,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest records an HTTP request. The route is the template the request matched, not its
+// path, so that IDs in paths do not create a time series each.
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, method, code).Inc()
+	m.httpDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// ObserveOperation records a storage backend operation. Searches finding no driver and operations
+// on missing drivers or reservations count as not found rather than as errors.
+func (m *Metrics) ObserveOperation(operation string, duration time.Duration, err error) {
+	result := ResultOK
+	switch {
+	case repository.IsNotFound(err):
+		result = ResultNotFound
+	case err != nil:
+		result = ResultError
+	}
+	m.operationDuration.WithLabelValues(operation, result).Observe(duration.Seconds())
+}
+
+// AddImportedRows counts drivers saved by an import
+func (m *Metrics) AddImportedRows(rows int) {
+	m.importedRows.Add(float64(rows))
+}
+
+// PoolMonitor returns a monitor that tracks the MongoDB connection pool, to pass to the client options
+func (m *Metrics) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: func(e *event.PoolEvent) {
+		switch e.Type {
+		case event.ConnectionCreated:
+			m.poolOpen.Inc()
+		case event.ConnectionClosed:
+			m.poolOpen.Dec()
+		case event.GetSucceeded:
+			m.poolInUse.Inc()
+			m.poolCheckouts.WithLabelValues(ResultOK).Inc()
+			m.poolCheckoutWait.Observe(e.Duration.Seconds())
+		case event.GetFailed:
+			m.poolCheckouts.WithLabelValues(e.Reason).Inc()
+			m.poolCheckoutWait.Observe(e.Duration.Seconds())
+		case event.ConnectionReturned:
+			m.poolInUse.Dec()
+		case event.PoolCleared:
+			m.poolClears.Inc()
+		}
+	}}
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func expectMetrics(t *testing.T, output string, expected ...string) {
+	t.Helper()
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("expected metrics to contain %q", line)
+		}
+	}
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	repo := NewRepository(repository.NewMemoryDriverRepository(), m)
+
+	drivers := []models.DriverWithDistance{
+		{Location: models.Location{Type: "Point", Coordinates: []float64{29, 41}}},
+		{Location: models.Location{Type: "Point", Coordinates: []float64{29.001, 41}}},
+	}
+	if err := repo.SaveDrivers(ctx, drivers); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+	if _, err := repo.FindNearestDriver(ctx, 41, 29, 1000); err != nil {
+		t.Fatalf("FindNearestDriver failed: %v", err)
+	}
+	if _, err := repo.FindNearestDriver(ctx, 0, 0, 1000); !errors.Is(err, repository.ErrDriverNotFound) {
+		t.Fatalf("expected ErrDriverNotFound, got %v", err)
+	}
+	if err := repo.UpdateDriverLocation(ctx, primitive.NewObjectID(), 41, 29); err == nil {
+		t.Fatalf("expected updating a missing driver to fail")
+	}
+	if _, err := repo.ListDrivers(ctx); err != nil {
+		t.Fatalf("ListDrivers failed: %v", err)
+	}
+
+	expectMetrics(t, scrape(t, m),
+		`driver_import_rows_total 2`,
+		`repository_operation_duration_seconds_count{operation="SaveDrivers",result="ok"} 1`,
+		`repository_operation_duration_seconds_count{operation="FindNearestDriver",result="ok"} 1`,
+		`repository_operation_duration_seconds_count{operation="FindNearestDriver",result="not_found"} 1`,
+		`repository_operation_duration_seconds_count{operation="UpdateDriverLocation",result="not_found"} 1`,
+		`repository_operation_duration_seconds_count{operation="ListDrivers",result="ok"} 1`,
+	)
+}
+
+func TestPoolMonitor(t *testing.T) {
+	m := New()
+	monitor := m.PoolMonitor()
+
+	for _, e := range []event.PoolEvent{
+		{Type: event.ConnectionCreated},
+		{Type: event.ConnectionCreated},
+		{Type: event.ConnectionCreated},
+		{Type: event.ConnectionClosed},
+		{Type: event.GetSucceeded, Duration: time.Millisecond},
+		{Type: event.GetSucceeded, Duration: time.Millisecond},
+		{Type: event.ConnectionReturned},
+		{Type: event.GetFailed, Reason: event.ReasonTimedOut, Duration: time.Second},
+		{Type: event.PoolCleared},
+	} {
+		e := e
+		monitor.Event(&e)
+	}
+
+	expectMetrics(t, scrape(t, m),
+		`mongodb_pool_connections_open 2`,
+		`mongodb_pool_connections_in_use 1`,
+		`mongodb_pool_checkouts_total{result="ok"} 2`,
+		`mongodb_pool_checkouts_total{result="timeout"} 1`,
+		`mongodb_pool_checkout_duration_seconds_count 3`,
+		`mongodb_pool_clears_total 1`,
+		`go_goroutines`,
+	)
+}
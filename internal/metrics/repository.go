@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/storage"
+)
+
+// Repository times the operations of a storage backend, wrapping it below any cache so that the
+// timings are those of the backend itself
+type Repository struct {
+	store   storage.DriverStore
+	metrics *Metrics
+}
+
+func NewRepository(store storage.DriverStore, metrics *Metrics) *Repository {
+	return &Repository{store: store, metrics: metrics}
+}
+
+// observe records the operation that started at the given time and returns its error
+func (r *Repository) observe(operation string, started time.Time, err error) error {
+	r.metrics.ObserveOperation(operation, time.Since(started), err)
+	return err
+}
+
+// SaveDrivers also counts the saved drivers as imported rows
+func (r *Repository) SaveDrivers(ctx context.Context, locations []models.DriverWithDistance) error {
+	started := time.Now()
+	err := r.store.SaveDrivers(ctx, locations)
+	if err == nil {
+		r.metrics.AddImportedRows(len(locations))
+	}
+	return r.observe("SaveDrivers", started, err)
+}
+
+func (r *Repository) FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
+	started := time.Now()
+	driver, err := r.store.FindNearestDriver(ctx, latitude, longitude, radius)
+	return driver, r.observe("FindNearestDriver", started, err)
+}
+
+func (r *Repository) FindNearestDrivers(ctx context.Context, latitude, longitude float64, radius, limit int) ([]models.DriverWithDistance, error) {
+	started := time.Now()
+	drivers, err := r.store.FindNearestDrivers(ctx, latitude, longitude, radius, limit)
+	return drivers, r.observe("FindNearestDrivers", started, err)
+}
+
+func (r *Repository) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	started := time.Now()
+	return r.observe("UpdateDriverLocation", started, r.store.UpdateDriverLocation(ctx, id, latitude, longitude))
+}
+
+func (r *Repository) ListDrivers(ctx context.Context) ([]models.DriverWithDistance, error) {
+	started := time.Now()
+	drivers, err := r.store.ListDrivers(ctx)
+	return drivers, r.observe("ListDrivers", started, err)
+}
+
+func (r *Repository) ReserveDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	started := time.Now()
+	return r.observe("ReserveDriver", started, r.store.ReserveDriver(ctx, id, reservationID))
+}
+
+func (r *Repository) ClaimNearestDriver(ctx context.Context, latitude, longitude float64, radius int, ttl time.Duration, reservationID string) (*models.DriverWithDistance, error) {
+	started := time.Now()
+	driver, err := r.store.ClaimNearestDriver(ctx, latitude, longitude, radius, ttl, reservationID)
+	return driver, r.observe("ClaimNearestDriver", started, err)
+}
+
+func (r *Repository) ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	started := time.Now()
+	return r.observe("ConfirmReservation", started, r.store.ConfirmReservation(ctx, id, reservationID))
+}
+
+func (r *Repository) ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	started := time.Now()
+	return r.observe("ReleaseDriver", started, r.store.ReleaseDriver(ctx, id, reservationID))
+}
+
+func (r *Repository) AggregateDriverCells(ctx context.Context, bounds geo.Bounds, precision int) ([]models.HeatmapCell, error) {
+	started := time.Now()
+	cells, err := r.store.AggregateDriverCells(ctx, bounds, precision)
+	return cells, r.observe("AggregateDriverCells", started, err)
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"bitaksi-go-driver/internal/clientip"
+)
+
+// AccessLogOptions configure the access log
+type AccessLogOptions struct {
+	// TrustedProxies may set X-Forwarded-For and X-Real-IP to the address of the client
+	TrustedProxies []netip.Prefix
+	// SampleRates are the fractions of successful requests logged by route template, every request
+	// being logged for routes without one. Failed requests are always logged.
+	SampleRates map[string]float64
+}
+
+// identityKey carries a pointer for the API key middleware of a subrouter to tell the access log,
+// further out, who made the request
+type identityKey struct{}
+
+// SetClientIdentity records the identity of the API key that authenticated the request for the
+// access log. It must never be the key itself.
+func SetClientIdentity(ctx context.Context, identity string) {
+	if slot, ok := ctx.Value(identityKey{}).(*string); ok {
+		*slot = identity
+	}
+}
+
+// AccessLogMiddleware logs the method, route template, status, body size and latency of requests
+// along with the address and API key identity of the client
+func AccessLogMiddleware(logger *slog.Logger, options AccessLogOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			identity := new(string)
+
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), identityKey{}, identity)))
+
+			route := routeTemplate(r)
+			if rate, ok := options.SampleRates[route]; ok && recorder.status < http.StatusBadRequest && rand.Float64() >= rate {
+				return
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.Int("status", recorder.status),
+				slog.Int64("bytes", recorder.bytes),
+				slog.Duration("latency", time.Since(started)),
+				slog.String("remote_ip", clientip.FromRequest(r, options.TrustedProxies)),
+			}
+			if *identity != "" {
+				attrs = append(attrs, slog.String("client", *identity))
+			}
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "HTTP request", attrs...)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"bitaksi-go-driver/internal/auth"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	keys, err := auth.NewKeyStore(auth.Key{Name: "dispatch-service", Hash: auth.HashKey("secret-key")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	router := mux.NewRouter()
+	router.Use(AccessLogMiddleware(slog.New(slog.NewJSONHandler(&buf, nil)), AccessLogOptions{
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		SampleRates:    map[string]float64{"/api/search": 0},
+	}))
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(AuthMiddleware(keys))
+	api.HandleFunc("/drivers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "42"}`))
+	})
+	api.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {})
+
+	send := func(path, apiKey string) map[string]any {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "10.0.0.2:4711"
+		req.Header.Set("X-Forwarded-For", "198.51.100.1")
+		req.Header.Set("Authorization", apiKey)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		if buf.Len() == 0 {
+			return nil
+		}
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("expected a JSON record: %v", err)
+		}
+		return record
+	}
+
+	record := send("/api/drivers/42", "secret-key")
+	expected := map[string]any{
+		"method":    "GET",
+		"route":     "/api/drivers/{id}",
+		"status":    float64(200),
+		"bytes":     float64(len(`{"id": "42"}`)),
+		"remote_ip": "198.51.100.1",
+		"client":    "dispatch-service",
+	}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, record[key])
+		}
+	}
+	if _, ok := record["latency"]; !ok {
+		t.Error("expected the latency to be logged")
+	}
+
+	record = send("/api/drivers/42", "wrong-key")
+	if record["status"] != float64(http.StatusUnauthorized) || record["client"] != nil {
+		t.Errorf("expected an unauthenticated request without client, got %v", record)
+	}
+	if strings.Contains(buf.String(), "secret-key") || strings.Contains(buf.String(), "wrong-key") {
+		t.Error("expected API keys never to be logged")
+	}
+
+	if record := send("/api/search", "secret-key"); record != nil {
+		t.Errorf("expected successful searches to be sampled out, got %v", record)
+	}
+	if record := send("/api/search", "wrong-key"); record == nil {
+		t.Error("expected failed searches to be logged")
+	}
+}
@@ -1,24 +1,42 @@
 package middleware
 
 import (
-	"bitaksi-go-driver/internal/config"
+	"errors"
 	"net/http"
+
+	"bitaksi-go-driver/internal/apierror"
+	"bitaksi-go-driver/internal/auth"
 )
 
-// APIKeyMiddleware validates the API key in the Authorization header
-func APIKeyMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+// AuthMiddleware authenticates the credentials in the Authorization header and puts the principal
+// in the request context for RequireScope
+func AuthMiddleware(authenticator auth.Authenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Retrieve the Authorization header
-			apiKey := r.Header.Get("Authorization")
-
-			// Validate the API key
-			if apiKey != cfg.Server.APIKey {
-				http.Error(w, "Unauthorized: Invalid API key", http.StatusUnauthorized)
+			principal, err := authenticator.Authenticate(r.Context(), r.Header.Get("Authorization"))
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Missing or invalid credentials"))
+				return
+			}
+			if err != nil {
+				apierror.Write(w, r, apierror.Wrap(err, "Failed to authenticate"))
 				return
 			}
 
-			// Proceed to the next handler
+			SetClientIdentity(r.Context(), principal.Name)
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequireScope lets only principals granted the scope through, after AuthMiddleware
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal, ok := auth.PrincipalFrom(r.Context()); !ok || !principal.HasScope(scope) {
+				apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodeInsufficientScope, "The credentials are not granted the "+scope+" scope"))
+				return
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
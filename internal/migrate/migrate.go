@@ -0,0 +1,385 @@
+// Package migrate applies versioned schema changes, such as indexes and backfills, to the MongoDB
+// database. Migrations run once at startup or from the command line instead of on the request path.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// namespaceNotFound is the MongoDB error code for listing the indexes of a missing collection
+const namespaceNotFound = 26
+
+// lockTimeout is how long a lock is held without being renewed, so a crashed process cannot block
+// migrations forever
+const lockTimeout = 10 * time.Minute
+
+// lockRenewInterval is how often a process running migrations renews its lock, several times per
+// lockTimeout so that a failed renewal is retried before the lock expires
+const lockRenewInterval = lockTimeout / 5
+
+// lockRetryInterval is how often a process waiting for the lock checks whether it was released
+const lockRetryInterval = time.Second
+
+// ErrSchemaOutdated is returned when migrations are pending or required indexes are missing
+var ErrSchemaOutdated = errors.New("database schema is outdated, run the migrations")
+
+// errLockLost is the cause of stopping migrations when another process took over the lock
+var errLockLost = errors.New("the migration lock was taken over by another process")
+
+// Migration is one schema change. Versions are applied in ascending order and each only once;
+// Down reverts Up and runs in descending order.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error
+}
+
+// MigrationStatus tells whether a migration has been applied
+type MigrationStatus struct {
+	Version     int
+	Description string
+	AppliedAt   *time.Time // nil while the migration is pending
+}
+
+// RequiredIndex is an index the service cannot work without, by collection and index name
+type RequiredIndex struct {
+	Collection string
+	Name       string
+}
+
+// AppliedMigration records a migration applied to the database
+type AppliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Migrator applies migrations and records their versions in the schema_migrations collection.
+// A lock document in schema_migrations_lock makes concurrent processes, such as replicas starting
+// at the same time, wait for each other.
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+	required   []RequiredIndex
+	owner      string
+	renewEvery time.Duration
+	now        func() time.Time
+}
+
+// NewMigrator creates a migrator for the migrations, checking for the required indexes on Verify
+func NewMigrator(db *mongo.Database, migrations []Migration, required []RequiredIndex) *Migrator {
+	migrations = append([]Migration(nil), migrations...)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s/%d/%s", hostname, os.Getpid(), primitive.NewObjectID().Hex())
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		required:   required,
+		owner:      owner,
+		renewEvery: lockRenewInterval,
+		now:        time.Now,
+	}
+}
+
+func (m *Migrator) collection() *mongo.Collection {
+	return m.db.Collection("schema_migrations")
+}
+
+func (m *Migrator) lockCollection() *mongo.Collection {
+	return m.db.Collection("schema_migrations_lock")
+}
+
+// lock waits until this process holds the migration lock or the context is done
+func (m *Migrator) lock(ctx context.Context) error {
+	for {
+		now := m.now()
+
+		// Only a missing or expired lock matches, otherwise the upsert conflicts with the held lock
+		_, err := m.lockCollection().UpdateOne(ctx,
+			bson.M{"_id": "migrations", "expires_at": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": m.owner, "locked_at": now, "expires_at": now.Add(lockTimeout)}},
+			options.Update().SetUpsert(true))
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		slog.InfoContext(ctx, "Waiting for another process to finish migrating")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to acquire migration lock: %w", ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// hold renews the lock of this process until release is called, so that migrations running longer
+// than lockTimeout, such as backfills of a large collection, keep it. The returned context is
+// canceled if the lock is lost, stopping the migrations before they run concurrently with the
+// process that took it over. release stops renewing and releases the lock.
+func (m *Migrator) hold(ctx context.Context) (held context.Context, release func()) {
+	held, cancel := context.WithCancelCause(ctx)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(m.renewEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-held.Done():
+				return
+			case <-ticker.C:
+			}
+
+			err := m.renew(held)
+			if errors.Is(err, errLockLost) {
+				slog.ErrorContext(held, "Lost the migration lock, stopping migrations")
+				cancel(err)
+				return
+			}
+			if err != nil {
+				slog.WarnContext(held, "Failed to renew migration lock", "error", err)
+			}
+		}
+	}()
+
+	return held, func() {
+		close(stop)
+		<-stopped
+		m.unlock(context.WithoutCancel(held))
+		cancel(nil)
+	}
+}
+
+// renew extends the lock of this process by lockTimeout, or returns errLockLost if it no longer holds it
+func (m *Migrator) renew(ctx context.Context) error {
+	result, err := m.lockCollection().UpdateOne(ctx,
+		bson.M{"_id": "migrations", "owner": m.owner},
+		bson.M{"$set": bson.M{"expires_at": m.now().Add(lockTimeout)}})
+	if err != nil {
+		return fmt.Errorf("failed to renew migration lock: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return errLockLost
+	}
+	return nil
+}
+
+// unlock releases the lock if this process still holds it
+func (m *Migrator) unlock(ctx context.Context) {
+	if _, err := m.lockCollection().DeleteOne(ctx, bson.M{"_id": "migrations", "owner": m.owner}); err != nil {
+		slog.ErrorContext(ctx, "Failed to release migration lock", "error", err)
+	}
+}
+
+// lockCause returns errLockLost instead of the error of a migration stopped by losing the lock
+func lockCause(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); errors.Is(cause, errLockLost) {
+		return cause
+	}
+	return err
+}
+
+// Applied returns the migrations applied to the database, by version
+func (m *Migrator) Applied(ctx context.Context) (map[int]AppliedMigration, error) {
+	cursor, err := m.collection().Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []AppliedMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]AppliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// Status lists every migration in order with the time it was applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies the pending migrations in order and returns their versions. It stops at the first
+// failing migration, leaving the ones before it recorded as applied.
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	ctx, release := m.hold(ctx)
+	defer release()
+
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	var versions []int
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		slog.InfoContext(ctx, "Applying migration", "version", migration.Version, "description", migration.Description)
+		if err := migration.Up(ctx, m.db); err != nil {
+			return versions, fmt.Errorf("migration %d failed: %w", migration.Version, lockCause(ctx, err))
+		}
+
+		record := AppliedMigration{Version: migration.Version, Description: migration.Description, AppliedAt: m.now()}
+		if _, err := m.collection().InsertOne(ctx, record); err != nil {
+			return versions, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		versions = append(versions, migration.Version)
+	}
+
+	return versions, nil
+}
+
+// Down reverts the given number of most recently applied migrations, newest first, and returns
+// their versions
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	ctx, release := m.hold(ctx)
+	defer release()
+
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	migrations := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		migrations[migration.Version] = migration
+	}
+
+	appliedVersions := make([]int, 0, len(applied))
+	for version := range applied {
+		appliedVersions = append(appliedVersions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(appliedVersions)))
+
+	var versions []int
+	for _, version := range appliedVersions {
+		if len(versions) == steps {
+			break
+		}
+
+		migration, ok := migrations[version]
+		if !ok {
+			return versions, fmt.Errorf("migration %d was applied by a newer version of the service", version)
+		}
+
+		slog.InfoContext(ctx, "Reverting migration", "version", migration.Version, "description", migration.Description)
+		if err := migration.Down(ctx, m.db); err != nil {
+			return versions, fmt.Errorf("reverting migration %d failed: %w", migration.Version, lockCause(ctx, err))
+		}
+		if _, err := m.collection().DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+			return versions, fmt.Errorf("failed to record reverting migration %d: %w", migration.Version, err)
+		}
+		versions = append(versions, migration.Version)
+	}
+
+	return versions, nil
+}
+
+// Verify returns ErrSchemaOutdated if a migration is pending or a required index is missing
+func (m *Migrator) Verify(ctx context.Context) error {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	var problems []string
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			problems = append(problems, fmt.Sprintf("migration %d is pending", migration.Version))
+		}
+	}
+
+	existing := make(map[string]map[string]bool)
+	for _, required := range m.required {
+		if _, ok := existing[required.Collection]; !ok {
+			names, err := indexNames(ctx, m.db.Collection(required.Collection))
+			if err != nil {
+				return fmt.Errorf("failed to list indexes of %s: %w", required.Collection, err)
+			}
+			existing[required.Collection] = names
+		}
+
+		if !existing[required.Collection][required.Name] {
+			problems = append(problems, fmt.Sprintf("index %s.%s is missing", required.Collection, required.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrSchemaOutdated, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// HasIndex tells whether the collection has an index of the given name
+func HasIndex(ctx context.Context, collection *mongo.Collection, name string) (bool, error) {
+	names, err := indexNames(ctx, collection)
+	return names[name], err
+}
+
+func indexNames(ctx context.Context, collection *mongo.Collection) (map[string]bool, error) {
+	specifications, err := collection.Indexes().ListSpecifications(ctx)
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == namespaceNotFound {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(specifications))
+	for _, specification := range specifications {
+		names[specification.Name] = true
+	}
+	return names, nil
+}
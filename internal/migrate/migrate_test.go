@@ -0,0 +1,221 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository/repositorytest"
+)
+
+func testDatabase(t *testing.T) *mongo.Database {
+	uri, ok := repositorytest.MongoURI(t)
+	if !ok {
+		t.Skip("set MONGO_TEST_URI or install mongod to run against MongoDB")
+	}
+	return repositorytest.MongoDatabase(t, uri)
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	database := testDatabase(t)
+	migrator := NewMigrator(database, Migrations(24*time.Hour), RequiredIndexes)
+
+	if err := migrator.Verify(ctx); !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("expected ErrSchemaOutdated before migrating, got %v", err)
+	}
+
+	versions, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(versions) != 7 || versions[0] != 1 || versions[6] != 7 {
+		t.Errorf("expected migrations 1 to 7 to be applied in order, got %v", versions)
+	}
+	if err := migrator.Verify(ctx); err != nil {
+		t.Errorf("expected the schema to be up to date, got %v", err)
+	}
+
+	// Applied migrations are recorded and not applied again
+	if versions, err := migrator.Up(ctx); err != nil || len(versions) != 0 {
+		t.Errorf("expected nothing to apply, got %v, %v", versions, err)
+	}
+	applied, err := migrator.Applied(ctx)
+	if err != nil || len(applied) != 7 {
+		t.Errorf("expected 7 applied migrations, got %v, %v", applied, err)
+	}
+
+	// A dropped index is detected even though its migration was applied
+	if _, err := database.Collection(DriversCollection).Indexes().DropOne(ctx, DriversStatusIndex); err != nil {
+		t.Fatalf("failed to drop index: %v", err)
+	}
+	if err := migrator.Verify(ctx); !errors.Is(err, ErrSchemaOutdated) {
+		t.Errorf("expected ErrSchemaOutdated after dropping an index, got %v", err)
+	}
+}
+
+func TestMigrator_StopsAtFailure(t *testing.T) {
+	ctx := context.Background()
+	database := testDatabase(t)
+
+	var ran []int
+	step := func(version int, err error) Migration {
+		return Migration{Version: version, Description: "test", Up: func(ctx context.Context, db *mongo.Database) error {
+			ran = append(ran, version)
+			return err
+		}}
+	}
+	migrator := NewMigrator(database, []Migration{step(3, nil), step(1, nil), step(2, errors.New("boom"))}, nil)
+
+	versions, err := migrator.Up(ctx)
+	if err == nil {
+		t.Fatalf("expected the failing migration to stop Up")
+	}
+	if len(versions) != 1 || versions[0] != 1 || len(ran) != 2 {
+		t.Errorf("expected only migration 1 to be applied, got %v (ran %v)", versions, ran)
+	}
+}
+
+func TestMigrator_Down(t *testing.T) {
+	ctx := context.Background()
+	database := testDatabase(t)
+	migrator := NewMigrator(database, Migrations(24*time.Hour), RequiredIndexes)
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	// Reverting the last five migrations drops the TTL indexes, the backfills leave data in place
+	versions, err := migrator.Down(ctx, 5)
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if len(versions) != 5 || versions[0] != 7 || versions[4] != 3 {
+		t.Errorf("expected migrations 7 to 3 to be reverted, got %v", versions)
+	}
+	if err := migrator.Verify(ctx); !errors.Is(err, ErrSchemaOutdated) {
+		t.Errorf("expected ErrSchemaOutdated after reverting, got %v", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, status := range statuses {
+		if applied := status.AppliedAt != nil; applied != (status.Version <= 2) {
+			t.Errorf("migration %d: expected applied %v", status.Version, !applied)
+		}
+	}
+
+	if versions, err := migrator.Up(ctx); err != nil || len(versions) != 5 {
+		t.Errorf("expected the reverted migrations to be applied again, got %v, %v", versions, err)
+	}
+}
+
+func TestMigrator_Backfills(t *testing.T) {
+	ctx := context.Background()
+	database := testDatabase(t)
+
+	legacy := bson.M{"location": bson.M{"type": "Point", "coordinates": bson.A{29.0, 41.0}}, "distance": 0}
+	if _, err := database.Collection(DriversCollection).InsertOne(ctx, legacy); err != nil {
+		t.Fatalf("failed to insert legacy driver: %v", err)
+	}
+
+	if _, err := NewMigrator(database, Migrations(24*time.Hour), RequiredIndexes).Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	var driver bson.M
+	if err := database.Collection(DriversCollection).FindOne(ctx, bson.M{}).Decode(&driver); err != nil {
+		t.Fatalf("failed to read driver: %v", err)
+	}
+	if driver["status"] != models.DriverStatusAvailable {
+		t.Errorf("expected the status to be backfilled, got %v", driver["status"])
+	}
+	if _, ok := driver["last_seen"].(primitive.DateTime); !ok {
+		t.Errorf("expected last_seen to be backfilled, got %v", driver["last_seen"])
+	}
+	if _, ok := driver["distance"]; ok {
+		t.Errorf("expected the stored distance to be removed")
+	}
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	ctx := context.Background()
+	database := testDatabase(t)
+	holder := NewMigrator(database, nil, nil)
+	waiter := NewMigrator(database, Migrations(24*time.Hour), RequiredIndexes)
+
+	if err := holder.lock(ctx); err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 1500*time.Millisecond)
+	defer cancel()
+	if _, err := waiter.Up(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Up to wait for the lock, got %v", err)
+	}
+
+	holder.unlock(ctx)
+	if versions, err := waiter.Up(ctx); err != nil || len(versions) != 7 {
+		t.Errorf("expected Up to run once the lock is released, got %v, %v", versions, err)
+	}
+
+	// An expired lock of a crashed process is taken over
+	if err := holder.lock(ctx); err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+	waiter.now = func() time.Time { return time.Now().Add(lockTimeout + time.Minute) }
+	if _, err := waiter.Up(ctx); err != nil {
+		t.Errorf("expected the expired lock to be taken over, got %v", err)
+	}
+}
+
+func TestMigrator_RenewsLock(t *testing.T) {
+	ctx := context.Background()
+	database := testDatabase(t)
+	holder := NewMigrator(database, nil, nil)
+	holder.renewEvery = 10 * time.Millisecond
+
+	if err := holder.lock(ctx); err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+	var before struct {
+		ExpiresAt time.Time `bson:"expires_at"`
+	}
+	if err := holder.lockCollection().FindOne(ctx, bson.M{"_id": "migrations"}).Decode(&before); err != nil {
+		t.Fatalf("failed to read lock: %v", err)
+	}
+
+	holder.now = func() time.Time { return time.Now().Add(time.Hour) }
+	held, release := holder.hold(ctx)
+	defer release()
+	time.Sleep(100 * time.Millisecond)
+
+	after := before
+	if err := holder.lockCollection().FindOne(ctx, bson.M{"_id": "migrations"}).Decode(&after); err != nil {
+		t.Fatalf("failed to read lock: %v", err)
+	}
+	if !after.ExpiresAt.After(before.ExpiresAt) {
+		t.Errorf("expected the lock to be renewed past %v, got %v", before.ExpiresAt, after.ExpiresAt)
+	}
+
+	// Another process took the lock over, so the migrations of the holder stop
+	if _, err := holder.lockCollection().UpdateOne(ctx, bson.M{"_id": "migrations"}, bson.M{"$set": bson.M{"owner": "other"}}); err != nil {
+		t.Fatalf("failed to take over lock: %v", err)
+	}
+	select {
+	case <-held.Done():
+		if !errors.Is(context.Cause(held), errLockLost) {
+			t.Errorf("expected %v, got %v", errLockLost, context.Cause(held))
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the holder to stop once the lock is lost")
+	}
+}
@@ -0,0 +1,130 @@
+package migrate
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"bitaksi-go-driver/internal/models"
+)
+
+// Collections and indexes created by the migrations
+const (
+	DriversCollection      = "drivers"
+	SearchEventsCollection = "search_events"
+	RidesCollection        = "rides"
+
+	DriversLocationIndex = "location_2dsphere"
+	DriversStatusIndex   = "status_last_seen"
+	SearchEventsTTLIndex = "timestamp_ttl"
+	RidesTTLIndex        = "expires_at_ttl"
+)
+
+// Migrations returns the migrations of the service database. Search events expire after the
+// retention; changing it later requires a new migration, as TTL indexes are only created once.
+// New migrations are appended with the next version, applied ones must never change.
+func Migrations(searchEventRetention time.Duration) []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "Create the 2dsphere index on drivers.location",
+			Up: createIndex(DriversCollection, mongo.IndexModel{
+				Keys:    bson.M{"location": "2dsphere"},
+				Options: options.Index().SetName(DriversLocationIndex),
+			}),
+			Down: dropIndex(DriversCollection, DriversLocationIndex),
+		},
+		{
+			Version:     2,
+			Description: "Create the status and last_seen index on drivers",
+			Up: createIndex(DriversCollection, mongo.IndexModel{
+				Keys:    bson.D{{Key: "status", Value: 1}, {Key: "last_seen", Value: -1}},
+				Options: options.Index().SetName(DriversStatusIndex),
+			}),
+			Down: dropIndex(DriversCollection, DriversStatusIndex),
+		},
+		{
+			Version:     3,
+			Description: "Expire search events after the retention period",
+			Up: createIndex(SearchEventsCollection, mongo.IndexModel{
+				Keys:    bson.M{"timestamp": 1},
+				Options: options.Index().SetName(SearchEventsTTLIndex).SetExpireAfterSeconds(int32(searchEventRetention.Seconds())),
+			}),
+			Down: dropIndex(SearchEventsCollection, SearchEventsTTLIndex),
+		},
+		{
+			// Drivers imported before reservations existed have no status. They already count as
+			// available, so reverting leaves the backfilled status in place.
+			Version:     4,
+			Description: "Backfill the status of drivers without one",
+			Up: updateDrivers(bson.M{"status": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"status": models.DriverStatusAvailable}}),
+			Down: noop,
+		},
+		{
+			// The ObjectID holds the creation time, the best estimate of when a driver was last seen
+			Version:     5,
+			Description: "Backfill last_seen of drivers from their creation time",
+			Up: updateDrivers(bson.M{"last_seen": bson.M{"$exists": false}},
+				bson.A{bson.M{"$set": bson.M{"last_seen": bson.M{"$toDate": "$_id"}}}}),
+			Down: noop,
+		},
+		{
+			// Search distances are computed per query, stored ones were always 0 and are never read
+			Version:     6,
+			Description: "Remove the stored distance field from drivers",
+			Up: updateDrivers(bson.M{"distance": bson.M{"$exists": true}},
+				bson.M{"$unset": bson.M{"distance": ""}}),
+			Down: noop,
+		},
+		{
+			// Ride requests carry their own expiry, which covers the offers and the retention
+			Version:     7,
+			Description: "Expire ride requests at expires_at",
+			Up: createIndex(RidesCollection, mongo.IndexModel{
+				Keys:    bson.M{"expires_at": 1},
+				Options: options.Index().SetName(RidesTTLIndex).SetExpireAfterSeconds(0),
+			}),
+			Down: dropIndex(RidesCollection, RidesTTLIndex),
+		},
+	}
+}
+
+// RequiredIndexes are the indexes searches and demand reports rely on
+var RequiredIndexes = []RequiredIndex{
+	{Collection: DriversCollection, Name: DriversLocationIndex},
+	{Collection: DriversCollection, Name: DriversStatusIndex},
+	{Collection: SearchEventsCollection, Name: SearchEventsTTLIndex},
+	{Collection: RidesCollection, Name: RidesTTLIndex},
+}
+
+// createIndex returns a migration step creating the index. Creating an index that already exists
+// with the same options does nothing, so databases indexed before migrations existed are fine.
+func createIndex(collection string, index mongo.IndexModel) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).Indexes().CreateOne(ctx, index)
+		return err
+	}
+}
+
+func dropIndex(collection, name string) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).Indexes().DropOne(ctx, name)
+		return err
+	}
+}
+
+// updateDrivers returns a migration step applying the update, a document or a pipeline, to the matching drivers
+func updateDrivers(filter bson.M, update interface{}) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(DriversCollection).UpdateMany(ctx, filter, update)
+		return err
+	}
+}
+
+func noop(ctx context.Context, db *mongo.Database) error {
+	return nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+)
+
+// StoredSearchEvent is a sampled and anonymised search. The location is snapped to the center
+// of its geohash cell and nothing identifies the caller.
+type StoredSearchEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Location  Location           `bson:"location"`
+	Geohash   string             `bson:"geohash"`
+	Radius    int                `bson:"radius"`
+	Found     bool               `bson:"found"`
+	Weight    float64            `bson:"weight"` // Searches this sample stands for, the inverse of the sample rate
+	Timestamp time.Time          `bson:"timestamp"`
+}
+
+// DemandHotspot is the estimated search demand of a geohash cell during an hour of the day
+type DemandHotspot struct {
+	Geohash    string      `json:"geohash"`
+	Hour       int         `json:"hour"`     // Hour of the day in the requested time zone
+	Searches   float64     `json:"searches"` // Estimated number of searches
+	Unmet      float64     `json:"unmet"`    // Estimated number of searches that found no driver
+	UnmetRatio float64     `json:"unmet_ratio"`
+	Center     Coordinates `json:"center"`
+	Bounds     geo.Bounds  `json:"bounds"`
+}
+
+// DemandQuery selects the search events to aggregate
+type DemandQuery struct {
+	From      time.Time
+	To        time.Time
+	Precision int // Geohash precision of the aggregated cells
+	Location  *time.Location
+	Limit     int
+}
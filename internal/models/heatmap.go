@@ -0,0 +1,20 @@
+package models
+
+import "bitaksi-go-driver/internal/geo"
+
+// HeatmapCell is the number of drivers inside a geohash cell
+type HeatmapCell struct {
+	Geohash   string      `json:"geohash"`
+	Count     int         `json:"count"`     // All drivers in the cell
+	Available int         `json:"available"` // Drivers that can currently be offered a ride
+	Center    Coordinates `json:"center"`
+	Bounds    geo.Bounds  `json:"bounds"`
+}
+
+// Heatmap is the driver distribution within a bounding box
+type Heatmap struct {
+	Precision int           `json:"precision"`
+	Bounds    geo.Bounds    `json:"bounds"`
+	Total     int           `json:"total"`
+	Cells     []HeatmapCell `json:"cells"`
+}
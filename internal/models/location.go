@@ -1,16 +1,32 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Driver availability states. Documents without a status are treated as available,
+// as are reserved drivers whose reservation expired before it was confirmed.
+const (
+	DriverStatusAvailable = "available"
+	DriverStatusReserved  = "reserved"
+)
 
 type Coordinates struct {
-	Latitude  float64 `bson:"latitude"`
-	Longitude float64 `bson:"longitude"`
+	Latitude  float64 `bson:"latitude" json:"latitude"`
+	Longitude float64 `bson:"longitude" json:"longitude"`
 }
 
 type DriverWithDistance struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Location Location           `bson:"location" json:"location"`
-	Distance float64            `bson:"distance" json:"distance"` // Distance from the "near" point
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Location      Location           `bson:"location" json:"location"`
+	Status        string             `bson:"status,omitempty" json:"status,omitempty"`
+	ReservedUntil *time.Time         `bson:"reserved_until,omitempty" json:"reserved_until,omitempty"` // Unconfirmed reservations expire at this time
+	ReservationID string             `bson:"reservation_id,omitempty" json:"-"`                        // Identifies the reservation to its holder, who needs it to confirm or release it
+	LastSeen      *time.Time         `bson:"last_seen,omitempty" json:"last_seen,omitempty"`           // Time of the last location update
+	Distance      float64            `bson:"distance,omitempty" json:"distance"`                       // Distance from the "near" point, not stored
+	ETASeconds    float64            `bson:"-" json:"eta_seconds,omitempty"`                           // Estimated travel time to the "near" point
 }
 
 type Location struct {
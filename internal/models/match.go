@@ -0,0 +1,21 @@
+package models
+
+// Pickup is a pending ride request waiting to be matched with a driver
+type Pickup struct {
+	ID        string  `json:"id"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// MatchPair assigns a driver to a pickup. Driver.Distance is the pickup distance in meters.
+type MatchPair struct {
+	PickupID string             `json:"pickup_id"`
+	Driver   DriverWithDistance `json:"driver"`
+}
+
+// MatchResult is the outcome of matching a batch of pickups with drivers
+type MatchResult struct {
+	Pairs         []MatchPair `json:"pairs"`
+	Unmatched     []string    `json:"unmatched"`      // IDs of pickups without a driver within the maximum distance
+	TotalDistance float64     `json:"total_distance"` // Sum of pickup distances of all pairs in meters
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ride request states
+const (
+	RideStatusOffering    = "offering"    // Waiting for the currently offered driver to respond
+	RideStatusAccepted    = "accepted"    // A driver accepted and was reserved
+	RideStatusUnfulfilled = "unfulfilled" // Every candidate declined, timed out or was unavailable
+)
+
+// Offer states
+const (
+	OfferStatusPending     = "pending"
+	OfferStatusAccepted    = "accepted"
+	OfferStatusDeclined    = "declined"
+	OfferStatusExpired     = "expired"
+	OfferStatusUnavailable = "unavailable" // The driver was reserved by someone else before accepting
+)
+
+// RideRequest is a pickup request offered to nearby drivers one at a time
+type RideRequest struct {
+	ID            string              `bson:"_id" json:"id"`
+	Pickup        Coordinates         `bson:"pickup" json:"pickup"`
+	Radius        int                 `bson:"radius" json:"radius"`
+	Status        string              `bson:"status" json:"status"`
+	DriverID      *primitive.ObjectID `bson:"driver_id,omitempty" json:"driver_id,omitempty"`
+	ReservationID string              `bson:"reservation_id,omitempty" json:"reservation_id,omitempty"` // Releases the accepted driver once the ride is over
+	Offers        []Offer             `bson:"offers" json:"offers"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+
+	Candidates []Candidate `bson:"candidates" json:"-"` // Drivers yet to be offered the ride, nearest first
+	ExpiresAt  time.Time   `bson:"expires_at" json:"-"` // When the stored ride request is deleted
+	Version    int64       `bson:"version" json:"-"`    // Incremented by every update, to detect concurrent ones
+}
+
+// Offer is a single attempt to hand a ride request to a driver
+type Offer struct {
+	DriverID  primitive.ObjectID `bson:"driver_id" json:"driver_id"`
+	Distance  float64            `bson:"distance" json:"distance"`
+	Status    string             `bson:"status" json:"status"`
+	OfferedAt time.Time          `bson:"offered_at" json:"offered_at"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// Candidate is a driver a ride request is yet to be offered to
+type Candidate struct {
+	DriverID primitive.ObjectID `bson:"driver_id" json:"driver_id"`
+	Distance float64            `bson:"distance" json:"distance"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"bitaksi-go-driver/internal/geo"
+)
+
+// SearchEvent is a single nearest-driver search, an implicit signal of rider demand
+type SearchEvent struct {
+	Latitude  float64
+	Longitude float64
+	Radius    int
+	Found     bool // Whether a driver was found within the radius
+	Timestamp time.Time
+}
+
+// SurgeCell is the supply and demand of a geohash cell and the resulting price multiplier
+type SurgeCell struct {
+	Geohash    string      `json:"geohash"`
+	Supply     int         `json:"supply"` // Available drivers in the cell
+	Demand     int         `json:"demand"` // Searches from the cell within the sliding window
+	Ratio      float64     `json:"ratio"`  // Demand per available driver
+	Multiplier float64     `json:"multiplier"`
+	Center     Coordinates `json:"center"`
+	Bounds     geo.Bounds  `json:"bounds"`
+}
+
+// SurgeMap lists the surge cells within a bounding box
+type SurgeMap struct {
+	Precision int         `json:"precision"`
+	Window    string      `json:"window"`
+	Cells     []SurgeCell `json:"cells"`
+}
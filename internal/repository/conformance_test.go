@@ -0,0 +1,84 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"bitaksi-go-driver/internal/migrate"
+	"bitaksi-go-driver/internal/repository"
+	"bitaksi-go-driver/internal/repository/repositorytest"
+)
+
+func TestMemoryDriverRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) (repositorytest.DriverRepository, repositorytest.Setup) {
+		return repository.NewMemoryDriverRepository(), nil
+	})
+}
+
+func TestDriverRepository_Conformance(t *testing.T) {
+	uri, ok := repositorytest.MongoURI(t)
+	if !ok {
+		t.Skip("set MONGO_TEST_URI or install mongod to run against MongoDB")
+	}
+
+	repositorytest.Run(t, func(t *testing.T) (repositorytest.DriverRepository, repositorytest.Setup) {
+		database := repositorytest.MongoDatabase(t, uri)
+		repo := repository.NewDriverRepository(database, migrate.DriversCollection)
+		return &repo, func(ctx context.Context) error {
+			_, err := migrate.NewMigrator(database, migrate.Migrations(time.Hour), migrate.RequiredIndexes).Up(ctx)
+			return err
+		}
+	})
+}
+
+func TestPostgresDriverRepository_Conformance(t *testing.T) {
+	if os.Getenv("POSTGRES_TEST_URL") == "" {
+		t.Skip("set POSTGRES_TEST_URL to run against PostgreSQL with PostGIS")
+	}
+
+	repositorytest.Run(t, func(t *testing.T) (repositorytest.DriverRepository, repositorytest.Setup) {
+		pool, _ := repositorytest.PostgresPool(t)
+		repo := repository.NewPostgresDriverRepository(pool)
+		return &repo, func(ctx context.Context) error {
+			if err := repo.EnsureSchema(ctx); err != nil {
+				return err
+			}
+			return repo.EnsureIndex(ctx)
+		}
+	})
+}
+
+func TestMemoryRideRepository_Conformance(t *testing.T) {
+	repositorytest.RunRides(t, func(t *testing.T) repositorytest.RideRepository {
+		return repository.NewMemoryRideRepository()
+	})
+}
+
+func TestRideRepository_Conformance(t *testing.T) {
+	uri, ok := repositorytest.MongoURI(t)
+	if !ok {
+		t.Skip("set MONGO_TEST_URI or install mongod to run against MongoDB")
+	}
+
+	repositorytest.RunRides(t, func(t *testing.T) repositorytest.RideRepository {
+		repo := repository.NewRideRepository(repositorytest.MongoDatabase(t, uri), "rides")
+		return &repo
+	})
+}
+
+func TestPostgresRideRepository_Conformance(t *testing.T) {
+	if os.Getenv("POSTGRES_TEST_URL") == "" {
+		t.Skip("set POSTGRES_TEST_URL to run against PostgreSQL")
+	}
+
+	repositorytest.RunRides(t, func(t *testing.T) repositorytest.RideRepository {
+		pool, _ := repositorytest.PostgresPool(t)
+		repo := repository.NewPostgresRideRepository(pool)
+		if err := repo.EnsureSchema(context.Background()); err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+		return &repo
+	})
+}
@@ -1,9 +1,13 @@
 package repository
 
 import (
+	"bitaksi-go-driver/internal/geo"
 	"bitaksi-go-driver/internal/models"
 	"context"
 	"errors"
+	"math"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,6 +17,41 @@ import (
 // ErrDriverNotFound is returned when no driver is found within the specified radius
 var ErrDriverNotFound = errors.New("no drivers found within the specified radius")
 
+// ErrDriverUnavailable is returned when a driver does not exist or is already reserved
+var ErrDriverUnavailable = errors.New("driver is not available")
+
+// ErrUnknownDriver is returned when a driver with the given ID does not exist
+var ErrUnknownDriver = errors.New("driver does not exist")
+
+// ErrReservationNotFound is returned when a driver has no reservation to confirm or release
+var ErrReservationNotFound = errors.New("reservation not found or expired")
+
+// ErrInvalidLocation is returned when saving a driver whose location is not a longitude and latitude pair in range
+var ErrInvalidLocation = errors.New("invalid driver location")
+
+// IsNotFound reports whether the error means a search found no driver or the driver or reservation
+// does not exist, outcomes of normal operation rather than failures of the backend
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrDriverNotFound) || errors.Is(err, ErrUnknownDriver) || errors.Is(err, ErrReservationNotFound)
+}
+
+// availableFilter matches drivers that can be offered a ride at the given time. Unconfirmed
+// reservations count as released once they expire, so no background job is needed to free them.
+func availableFilter(now time.Time) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"status": bson.M{"$in": bson.A{nil, models.DriverStatusAvailable}}},
+		bson.M{"reserved_until": bson.M{"$lt": now}},
+	}}
+}
+
+// asAvailable shows a driver found by a search, and so available, without the expired reservation
+// they may still carry, so that searches never reveal the ID of a reservation
+func asAvailable(driver *models.DriverWithDistance) {
+	if driver.Status == models.DriverStatusReserved {
+		driver.Status, driver.ReservedUntil, driver.ReservationID = models.DriverStatusAvailable, nil, ""
+	}
+}
+
 type DriverRepository struct {
 	collection *mongo.Collection
 }
@@ -21,27 +60,87 @@ func NewDriverRepository(db *mongo.Database, collectionName string) DriverReposi
 	return DriverRepository{collection: db.Collection(collectionName)}
 }
 
+// SaveDrivers stores new drivers as available. Drivers whose ID already exists are moved to the
+// given location and keep their status, so that saving them again, e.g. by importing an export,
+// neither fails nor releases their reservation.
 func (r *DriverRepository) SaveDrivers(ctx context.Context, locations []models.DriverWithDistance) error {
-	var drivers []interface{}
+	if len(locations) == 0 {
+		return nil
+	}
 
-	for _, location := range locations {
-		drivers = append(drivers, models.DriverWithDistance{
-			ID:       primitive.NewObjectID(),
-			Location: location.Location,
-		})
+	lastSeen := time.Now()
+	writes := make([]mongo.WriteModel, len(locations))
+	for i, location := range locations {
+		// Callers may choose the IDs, e.g. to mirror the drivers elsewhere
+		id := location.ID
+		if id.IsZero() {
+			id = primitive.NewObjectID()
+		}
+
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{
+				"$set":         bson.M{"location": location.Location, "last_seen": lastSeen},
+				"$setOnInsert": bson.M{"status": models.DriverStatusAvailable},
+			}).
+			SetUpsert(true)
 	}
 
-	_, err := r.collection.InsertMany(ctx, drivers)
+	_, err := r.collection.BulkWrite(ctx, writes)
 	return err
 }
 
+// UpdateDriverLocation moves a driver to a new position
+func (r *DriverRepository) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"location":  models.Location{Type: "Point", Coordinates: []float64{longitude, latitude}},
+			"last_seen": time.Now(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrUnknownDriver
+	}
+	return nil
+}
+
+// ListDrivers returns every driver regardless of availability
+func (r *DriverRepository) ListDrivers(ctx context.Context) ([]models.DriverWithDistance, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	drivers := []models.DriverWithDistance{}
+	if err = cursor.All(ctx, &drivers); err != nil {
+		return nil, err
+	}
+	return drivers, nil
+}
+
 func (r *DriverRepository) FindNearestDriver(ctx context.Context, latitude, longitude float64, maxDistance int) (*models.DriverWithDistance, error) {
+	drivers, err := r.FindNearestDrivers(ctx, latitude, longitude, maxDistance, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the nearest driver
+	return &drivers[0], nil
+}
+
+// FindNearestDrivers returns up to limit available drivers within maxDistance meters, ordered by distance.
+func (r *DriverRepository) FindNearestDrivers(ctx context.Context, latitude, longitude float64, maxDistance, limit int) ([]models.DriverWithDistance, error) {
 	var drivers []models.DriverWithDistance
 
 	// Define the geoNear aggregation pipeline
 	aggregate := mongo.Pipeline{
 		{
-			{"$geoNear", bson.M{
+			{Key: "$geoNear", Value: bson.M{
 				"near": bson.M{
 					"type":        "Point",
 					"coordinates": []float64{longitude, latitude},
@@ -49,10 +148,11 @@ func (r *DriverRepository) FindNearestDriver(ctx context.Context, latitude, long
 				"distanceField": "distance",  // Add the calculated distance
 				"maxDistance":   maxDistance, // Maximum distance in meters
 				"spherical":     true,        // Use spherical calculations
+				"query":         availableFilter(time.Now()),
 			}},
 		},
 		{{
-			"$limit", 1, // Limit results to the nearest driver
+			Key: "$limit", Value: limit, // Limit results to the nearest drivers
 		}},
 	}
 
@@ -68,49 +168,270 @@ func (r *DriverRepository) FindNearestDriver(ctx context.Context, latitude, long
 		return nil, err
 	}
 
+	for i := range drivers {
+		asAvailable(&drivers[i])
+	}
+
 	// Check if a driver was found
 	if len(drivers) == 0 {
 		return nil, ErrDriverNotFound
 	}
 
-	// Return the nearest driver
-	return &drivers[0], nil
+	return drivers, nil
 }
 
-// EnsureIndex ensures that the collection has a 2dsphere index on the location field.
-func (r *DriverRepository) EnsureIndex(ctx context.Context) error {
-	// Check if the 2dsphere index already exists
-	indexes, err := r.collection.Indexes().List(ctx)
+// ReserveDriver marks an available driver as reserved under the reservation ID. The status
+// predicate in the filter makes the check and the update a single atomic operation, so a driver
+// can only be reserved once.
+func (r *DriverRepository) ReserveDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	filter := availableFilter(time.Now())
+	filter["_id"] = id
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{
+		"$set":   bson.M{"status": models.DriverStatusReserved, "reservation_id": reservationID},
+		"$unset": bson.M{"reserved_until": ""},
+	})
 	if err != nil {
 		return err
 	}
 
-	hasGeoIndex := false
-	for indexes.Next(ctx) {
-		var index bson.M
-		if err := indexes.Decode(&index); err != nil {
-			return err
-		}
+	if result.MatchedCount == 0 {
+		return ErrDriverUnavailable
+	}
+	return nil
+}
 
-		if key, ok := index["key"].(bson.M); ok {
-			if key["location"] == "2dsphere" {
-				hasGeoIndex = true
-				break
-			}
-		}
+// ClaimNearestDriver finds the nearest available driver and reserves them until now+ttl under the
+// reservation ID in a single findOneAndUpdate, so concurrent claims can never return the same driver.
+func (r *DriverRepository) ClaimNearestDriver(ctx context.Context, latitude, longitude float64, maxDistance int, ttl time.Duration, reservationID string) (*models.DriverWithDistance, error) {
+	now := time.Now()
+	filter := availableFilter(now)
+	filter["location"] = bson.M{
+		"$nearSphere": bson.M{
+			"$geometry": bson.M{
+				"type":        "Point",
+				"coordinates": []float64{longitude, latitude},
+			},
+			"$maxDistance": maxDistance,
+		},
 	}
 
-	// Create the 2dsphere index if it does not exist
-	if !hasGeoIndex {
-		index := mongo.IndexModel{
-			Keys:    bson.M{"location": "2dsphere"},
-			Options: options.Index().SetName("location_2dsphere"),
+	update := bson.M{"$set": bson.M{
+		"status":         models.DriverStatusReserved,
+		"reserved_until": now.Add(ttl),
+		"reservation_id": reservationID,
+	}}
+
+	var driver models.DriverWithDistance
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&driver)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDriverNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// $nearSphere sorts by distance but does not report it
+	driver.Distance = geo.Distance(latitude, longitude, driver.Location.Coordinates[1], driver.Location.Coordinates[0])
+	return &driver, nil
+}
+
+// ConfirmReservation turns an unexpired reservation into a permanent one
+func (r *DriverRepository) ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{
+		"_id":            id,
+		"status":         models.DriverStatusReserved,
+		"reservation_id": reservationID,
+		"reserved_until": bson.M{"$gte": time.Now()},
+	}, bson.M{
+		"$unset": bson.M{"reserved_until": ""},
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrReservationNotFound
+	}
+	return nil
+}
+
+// ReleaseDriver makes a driver reserved under the reservation ID available again
+func (r *DriverRepository) ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{
+		"_id":            id,
+		"status":         models.DriverStatusReserved,
+		"reservation_id": reservationID,
+	}, bson.M{
+		"$set":   bson.M{"status": models.DriverStatusAvailable},
+		"$unset": bson.M{"reserved_until": "", "reservation_id": ""},
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrReservationNotFound
+	}
+	return nil
+}
+
+// AggregateDriverCells counts drivers inside the bounding box per geohash cell of the given
+// precision. The grouping runs as an aggregation in MongoDB on integer cell rows and columns,
+// which are then encoded as geohashes, so only one document per occupied cell is returned.
+func (r *DriverRepository) AggregateDriverCells(ctx context.Context, bounds geo.Bounds, precision int) ([]models.HeatmapCell, error) {
+	cursor, err := r.collection.Aggregate(ctx, heatmapPipeline(bounds, precision, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		ID struct {
+			Lat float64 `bson:"lat"`
+			Lon float64 `bson:"lon"`
+		} `bson:"_id"`
+		Count     int `bson:"count"`
+		Available int `bson:"available"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	cells := make([]models.HeatmapCell, 0, len(groups))
+	for _, group := range groups {
+		latIndex, lonIndex := int64(group.ID.Lat), int64(group.ID.Lon)
+		cellBounds := geo.GeohashCellBounds(latIndex, lonIndex, precision)
+		latitude, longitude := cellBounds.Center()
+
+		cells = append(cells, models.HeatmapCell{
+			Geohash:   geo.GeohashFromCellIndex(latIndex, lonIndex, precision),
+			Count:     group.Count,
+			Available: group.Available,
+			Center:    models.Coordinates{Latitude: latitude, Longitude: longitude},
+			Bounds:    cellBounds,
+		})
+	}
+
+	return cells, nil
+}
+
+// heatmapPipeline groups the drivers inside the bounding box by geohash cell, counting those available at now
+func heatmapPipeline(bounds geo.Bounds, precision int, now time.Time) mongo.Pipeline {
+	latHeight, lonWidth := geo.GeohashCellSize(precision)
+	maxLatIndex, maxLonIndex := geo.GeohashCellIndex(90, 180, precision)
+
+	longitudeField := bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 0}}
+	latitudeField := bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 1}}
+	cellIndex := func(field bson.M, offset, size float64, maxIndex int64) bson.M {
+		return bson.M{"$min": bson.A{
+			bson.M{"$floor": bson.M{"$divide": bson.A{bson.M{"$add": bson.A{field, offset}}, size}}},
+			maxIndex,
+		}}
+	}
+
+	isAvailable := bson.M{"$or": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$status", models.DriverStatusAvailable}}, models.DriverStatusAvailable}},
+		bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$type": "$reserved_until"}, "date"}},
+			bson.M{"$lt": bson.A{"$reserved_until", now}},
+		}},
+	}}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: boundsFilter(bounds)}},
+		{{Key: "$project", Value: bson.M{
+			"lat":       cellIndex(latitudeField, 90, latHeight, maxLatIndex),
+			"lon":       cellIndex(longitudeField, 180, lonWidth, maxLonIndex),
+			"available": bson.M{"$cond": bson.A{isAvailable, 1, 0}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       bson.M{"lat": "$lat", "lon": "$lon"},
+			"count":     bson.M{"$sum": 1},
+			"available": bson.M{"$sum": "$available"},
+		}}},
+	}
+}
+
+// boundsPadding pads the polygons approximating a bounding box, in degrees. It exceeds by far the
+// distance by which the great-circle edges between vertices boundsVertexSpacing degrees apart on a
+// parallel stray from it, at most 0.0044 degrees.
+const (
+	boundsPadding       = 0.01
+	boundsVertexSpacing = 2.0
+)
+
+// boundsFilter matches drivers inside the bounding box, which may cross the antimeridian. Each
+// branch of the $or pairs a $geoWithin the 2dsphere index serves with the exact coordinate check.
+func boundsFilter(bounds geo.Bounds) bson.M {
+	latitude := bson.M{"location.coordinates.1": bson.M{"$gte": bounds.MinLat, "$lte": bounds.MaxLat}}
+	longitude := bson.M{"location.coordinates.0": bson.M{"$gte": bounds.MinLon, "$lte": bounds.MaxLon}}
+	if bounds.MinLon > bounds.MaxLon {
+		longitude = bson.M{"$or": bson.A{
+			bson.M{"location.coordinates.0": bson.M{"$gte": bounds.MinLon}},
+			bson.M{"location.coordinates.0": bson.M{"$lte": bounds.MaxLon}},
+		}}
+	}
+
+	var branches bson.A
+	for _, ring := range boundsRings(bounds) {
+		branches = append(branches, bson.M{
+			"location": bson.M{"$geoWithin": bson.M{"$geometry": bson.M{"type": "Polygon", "coordinates": bson.A{ring}}}},
+			"$and":     bson.A{latitude, longitude},
+		})
+	}
+	return bson.M{"$or": branches}
+}
+
+// boundsRings returns polygons covering the bounding box. GeoJSON polygons have great-circle edges
+// rather than parallels and must be smaller than a hemisphere, so the box is padded, split at the
+// antimeridian and into strips at most 90 degrees wide.
+func boundsRings(bounds geo.Bounds) []bson.A {
+	longitudes := [][2]float64{{bounds.MinLon, bounds.MaxLon}}
+	if bounds.MinLon > bounds.MaxLon {
+		longitudes = [][2]float64{{bounds.MinLon, 180}, {-180, bounds.MaxLon}}
+	}
+	south := math.Max(bounds.MinLat-boundsPadding, -90)
+	north := math.Min(bounds.MaxLat+boundsPadding, 90)
+
+	var rings []bson.A
+	for _, span := range longitudes {
+		west, east := math.Max(span[0]-boundsPadding, -180), math.Min(span[1]+boundsPadding, 180)
+		strips := int(math.Ceil((east - west) / 90))
+		for i := 0; i < strips; i++ {
+			width := (east - west) / float64(strips)
+			rings = append(rings, stripRing(south, north, west+width*float64(i), west+width*float64(i+1)))
 		}
+	}
+	return rings
+}
+
+// stripRing returns the counterclockwise ring bounded by two parallels and two meridians, with
+// vertices on the parallels at most boundsVertexSpacing degrees apart. A parallel at a pole
+// collapses into the pole, and a vertex halfway up each meridian keeps the edges short.
+func stripRing(south, north, west, east float64) bson.A {
+	steps := int(math.Ceil((east - west) / boundsVertexSpacing))
+	longitude := func(step int) float64 {
+		return west + (east-west)*float64(step)/float64(steps)
+	}
+	middle := (south + north) / 2
 
-		_, err = r.collection.Indexes().CreateOne(ctx, index)
-		if err != nil {
-			return err
+	var ring bson.A
+	if south == -90 {
+		ring = append(ring, bson.A{west, south})
+	} else {
+		for i := 0; i <= steps; i++ {
+			ring = append(ring, bson.A{longitude(i), south})
 		}
 	}
-	return nil
+	ring = append(ring, bson.A{east, middle})
+	if north == 90 {
+		ring = append(ring, bson.A{east, north})
+	} else {
+		for i := steps; i >= 0; i-- {
+			ring = append(ring, bson.A{longitude(i), north})
+		}
+	}
+	ring = append(ring, bson.A{west, middle})
+	return append(ring, ring[0])
 }
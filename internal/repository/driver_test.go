@@ -0,0 +1,103 @@
+package repository_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/migrate"
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+	"bitaksi-go-driver/internal/repository/repositorytest"
+)
+
+// migratedDatabase returns an empty MongoDB database with the migrations applied, skipping the test without MongoDB
+func migratedDatabase(t *testing.T) *mongo.Database {
+	uri, ok := repositorytest.MongoURI(t)
+	if !ok {
+		t.Skip("set MONGO_TEST_URI or install mongod to run against MongoDB")
+	}
+
+	database := repositorytest.MongoDatabase(t, uri)
+	if _, err := migrate.NewMigrator(database, migrate.Migrations(time.Hour), migrate.RequiredIndexes).Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return database
+}
+
+func TestDriverRepository_AggregateDriverCells(t *testing.T) {
+	ctx := context.Background()
+	database := migratedDatabase(t)
+	repo := repository.NewDriverRepository(database, migrate.DriversCollection)
+
+	drivers := []models.DriverWithDistance{
+		{Location: models.Location{Type: "Point", Coordinates: []float64{179.9, 10}}},
+		{Location: models.Location{Type: "Point", Coordinates: []float64{-179.9, 10}}},
+		{Location: models.Location{Type: "Point", Coordinates: []float64{0, 10}}},
+		{Location: models.Location{Type: "Point", Coordinates: []float64{100, 89.99}}},
+		{Location: models.Location{Type: "Point", Coordinates: []float64{100, -89.99}}},
+	}
+	if err := repo.SaveDrivers(ctx, drivers); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		bounds geo.Bounds
+		want   int
+	}{
+		{name: "Antimeridian", bounds: geo.Bounds{MinLat: 0, MinLon: 170, MaxLat: 20, MaxLon: -170}, want: 2},
+		{name: "Edges", bounds: geo.Bounds{MinLat: 10, MinLon: 179.9, MaxLat: 10, MaxLon: 179.9}, want: 1},
+		{name: "World", bounds: geo.Bounds{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180}, want: 5},
+		{name: "Outside", bounds: geo.Bounds{MinLat: 10.001, MinLon: -1, MaxLat: 20, MaxLon: 1}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cells, err := repo.AggregateDriverCells(ctx, tt.bounds, 1)
+			if err != nil {
+				t.Fatalf("AggregateDriverCells failed: %v", err)
+			}
+			var count int
+			for _, cell := range cells {
+				count += cell.Count
+			}
+			if count != tt.want {
+				t.Errorf("expected %d drivers, got %d in %+v", tt.want, count, cells)
+			}
+		})
+	}
+}
+
+func TestDriverRepository_AggregateDriverCellsUsesIndex(t *testing.T) {
+	database := migratedDatabase(t)
+
+	for _, bounds := range []geo.Bounds{
+		{MinLat: 40.9, MinLon: 28.9, MaxLat: 41.1, MaxLon: 29.1},
+		{MinLat: 0, MinLon: 170, MaxLat: 20, MaxLon: -170},
+		{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180},
+	} {
+		var explained bson.Raw
+		err := database.RunCommand(context.Background(), bson.D{
+			{Key: "explain", Value: bson.D{
+				{Key: "aggregate", Value: migrate.DriversCollection},
+				{Key: "pipeline", Value: repository.HeatmapPipeline(bounds, 5, time.Now())},
+				{Key: "cursor", Value: bson.M{}},
+			}},
+			{Key: "verbosity", Value: "queryPlanner"},
+		}).Decode(&explained)
+		if err != nil {
+			t.Fatalf("explain failed: %v", err)
+		}
+
+		plan := explained.String()
+		if !strings.Contains(plan, "IXSCAN") || !strings.Contains(plan, migrate.DriversLocationIndex) || strings.Contains(plan, "COLLSCAN") {
+			t.Errorf("expected the heatmap of %+v to scan the %s index, got %s", bounds, migrate.DriversLocationIndex, plan)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+package repository
+
+// HeatmapPipeline exposes the heatmap aggregation to the tests explaining it
+var HeatmapPipeline = heatmapPipeline
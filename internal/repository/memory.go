@@ -0,0 +1,412 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// memoryIndexPrecision is the geohash precision of the grid cells drivers are indexed by.
+// Precision 5 cells are about 4.9km x 4.9km, so typical searches only visit a few cells.
+const memoryIndexPrecision = 5
+
+// gridCell is the row and column of a geohash cell, see geo.GeohashCellIndex
+type gridCell struct {
+	lat, lon int64
+}
+
+// MemoryDriverRepository keeps drivers in memory, indexed by a grid of geohash cells. It behaves
+// like DriverRepository, including distances, reservations and their expiry, but needs no database.
+type MemoryDriverRepository struct {
+	mu      sync.RWMutex
+	drivers map[primitive.ObjectID]*models.DriverWithDistance
+	cells   map[gridCell]map[primitive.ObjectID]struct{}
+	now     func() time.Time
+}
+
+func NewMemoryDriverRepository() *MemoryDriverRepository {
+	return &MemoryDriverRepository{
+		drivers: make(map[primitive.ObjectID]*models.DriverWithDistance),
+		cells:   make(map[gridCell]map[primitive.ObjectID]struct{}),
+		now:     time.Now,
+	}
+}
+
+// SaveDrivers stores new drivers as available. Drivers whose ID already exists are moved to the
+// given location and keep their status. Nothing is saved if any location is invalid.
+func (r *MemoryDriverRepository) SaveDrivers(ctx context.Context, locations []models.DriverWithDistance) error {
+	for i, location := range locations {
+		if !validCoordinates(location.Location.Coordinates) {
+			return fmt.Errorf("%w: driver %d has coordinates %v", ErrInvalidLocation, i, location.Location.Coordinates)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lastSeen := r.now()
+	for _, location := range locations {
+		driver := &models.DriverWithDistance{
+			ID: location.ID,
+			Location: models.Location{
+				Type:        location.Location.Type,
+				Coordinates: append([]float64(nil), location.Location.Coordinates...),
+			},
+			Status:   models.DriverStatusAvailable,
+			LastSeen: &lastSeen,
+		}
+		if driver.ID.IsZero() {
+			driver.ID = primitive.NewObjectID()
+		}
+		if existing, ok := r.drivers[driver.ID]; ok {
+			r.unindex(existing)
+			driver.Status, driver.ReservedUntil, driver.ReservationID = existing.Status, existing.ReservedUntil, existing.ReservationID
+		}
+		r.drivers[driver.ID] = driver
+		r.index(driver)
+	}
+
+	return nil
+}
+
+// UpdateDriverLocation moves a driver to a new position, re-indexing them if they changed cells
+func (r *MemoryDriverRepository) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	driver, ok := r.drivers[id]
+	if !ok {
+		return ErrUnknownDriver
+	}
+
+	lastSeen := r.now()
+	r.unindex(driver)
+	driver.Location = models.Location{Type: "Point", Coordinates: []float64{longitude, latitude}}
+	driver.LastSeen = &lastSeen
+	r.index(driver)
+	return nil
+}
+
+// ListDrivers returns every driver regardless of availability, ordered by ID
+func (r *MemoryDriverRepository) ListDrivers(ctx context.Context) ([]models.DriverWithDistance, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	drivers := make([]models.DriverWithDistance, 0, len(r.drivers))
+	for _, driver := range r.drivers {
+		drivers = append(drivers, copyDriver(driver))
+	}
+
+	sort.Slice(drivers, func(i, j int) bool {
+		return drivers[i].ID.Hex() < drivers[j].ID.Hex()
+	})
+	return drivers, nil
+}
+
+func (r *MemoryDriverRepository) FindNearestDriver(ctx context.Context, latitude, longitude float64, maxDistance int) (*models.DriverWithDistance, error) {
+	drivers, err := r.FindNearestDrivers(ctx, latitude, longitude, maxDistance, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &drivers[0], nil
+}
+
+// FindNearestDrivers returns up to limit available drivers within maxDistance meters, ordered by distance.
+func (r *MemoryDriverRepository) FindNearestDrivers(ctx context.Context, latitude, longitude float64, maxDistance, limit int) ([]models.DriverWithDistance, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	drivers := r.nearestAvailable(latitude, longitude, maxDistance, limit)
+	if len(drivers) == 0 {
+		return nil, ErrDriverNotFound
+	}
+
+	result := make([]models.DriverWithDistance, len(drivers))
+	for i, driver := range drivers {
+		result[i] = copyDriver(driver.driver)
+		result[i].Distance = driver.distance
+		asAvailable(&result[i])
+	}
+	return result, nil
+}
+
+// ReserveDriver marks an available driver as reserved under the reservation ID
+func (r *MemoryDriverRepository) ReserveDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	driver, ok := r.drivers[id]
+	if !ok || !isAvailable(driver, r.now()) {
+		return ErrDriverUnavailable
+	}
+
+	driver.Status = models.DriverStatusReserved
+	driver.ReservedUntil = nil
+	driver.ReservationID = reservationID
+	return nil
+}
+
+// ClaimNearestDriver finds the nearest available driver and reserves them until now+ttl under the
+// reservation ID. The write lock is held for the whole lookup, so concurrent claims can never
+// return the same driver.
+func (r *MemoryDriverRepository) ClaimNearestDriver(ctx context.Context, latitude, longitude float64, maxDistance int, ttl time.Duration, reservationID string) (*models.DriverWithDistance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	drivers := r.nearestAvailable(latitude, longitude, maxDistance, 1)
+	if len(drivers) == 0 {
+		return nil, ErrDriverNotFound
+	}
+
+	reservedUntil := r.now().Add(ttl)
+	driver := drivers[0].driver
+	driver.Status = models.DriverStatusReserved
+	driver.ReservedUntil = &reservedUntil
+	driver.ReservationID = reservationID
+
+	claimed := copyDriver(driver)
+	claimed.Distance = drivers[0].distance
+	return &claimed, nil
+}
+
+// ConfirmReservation turns an unexpired reservation into a permanent one
+func (r *MemoryDriverRepository) ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	driver, ok := r.drivers[id]
+	if !ok || !isReservedAs(driver, reservationID) || driver.ReservedUntil == nil || driver.ReservedUntil.Before(r.now()) {
+		return ErrReservationNotFound
+	}
+
+	driver.ReservedUntil = nil
+	return nil
+}
+
+// ReleaseDriver makes a driver reserved under the reservation ID available again
+func (r *MemoryDriverRepository) ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	driver, ok := r.drivers[id]
+	if !ok || !isReservedAs(driver, reservationID) {
+		return ErrReservationNotFound
+	}
+
+	driver.Status = models.DriverStatusAvailable
+	driver.ReservedUntil = nil
+	driver.ReservationID = ""
+	return nil
+}
+
+// isReservedAs tells whether the driver is reserved under the reservation ID
+func isReservedAs(driver *models.DriverWithDistance, reservationID string) bool {
+	return driver.Status == models.DriverStatusReserved && driver.ReservationID == reservationID
+}
+
+// AggregateDriverCells counts drivers inside the bounding box per geohash cell of the given precision
+func (r *MemoryDriverRepository) AggregateDriverCells(ctx context.Context, bounds geo.Bounds, precision int) ([]models.HeatmapCell, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := r.now()
+	counts := make(map[gridCell]*models.HeatmapCell)
+	for _, driver := range r.drivers {
+		latitude, longitude := driver.Location.Coordinates[1], driver.Location.Coordinates[0]
+		if !bounds.Contains(latitude, longitude) {
+			continue
+		}
+
+		latIndex, lonIndex := geo.GeohashCellIndex(latitude, longitude, precision)
+		cell, ok := counts[gridCell{latIndex, lonIndex}]
+		if !ok {
+			cellBounds := geo.GeohashCellBounds(latIndex, lonIndex, precision)
+			centerLat, centerLon := cellBounds.Center()
+			cell = &models.HeatmapCell{
+				Geohash: geo.GeohashFromCellIndex(latIndex, lonIndex, precision),
+				Center:  models.Coordinates{Latitude: centerLat, Longitude: centerLon},
+				Bounds:  cellBounds,
+			}
+			counts[gridCell{latIndex, lonIndex}] = cell
+		}
+
+		cell.Count++
+		if isAvailable(driver, now) {
+			cell.Available++
+		}
+	}
+
+	cells := make([]models.HeatmapCell, 0, len(counts))
+	for _, cell := range counts {
+		cells = append(cells, *cell)
+	}
+	return cells, nil
+}
+
+// driverDistance is a driver found by a search together with its distance from the search point
+type driverDistance struct {
+	driver   *models.DriverWithDistance
+	distance float64
+}
+
+// nearestAvailable returns up to limit available drivers within maxDistance meters of the point,
+// nearest first. Only the grid cells overlapping the search circle are visited. The caller holds r.mu.
+func (r *MemoryDriverRepository) nearestAvailable(latitude, longitude float64, maxDistance, limit int) []driverDistance {
+	now := r.now()
+	var found []driverDistance
+
+	r.forEachCandidateCell(latitude, longitude, float64(maxDistance), func(ids map[primitive.ObjectID]struct{}) {
+		for id := range ids {
+			driver := r.drivers[id]
+			if !isAvailable(driver, now) {
+				continue
+			}
+
+			distance := geo.Distance(latitude, longitude, driver.Location.Coordinates[1], driver.Location.Coordinates[0])
+			if distance <= float64(maxDistance) {
+				found = append(found, driverDistance{driver: driver, distance: distance})
+			}
+		}
+	})
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].distance != found[j].distance {
+			return found[i].distance < found[j].distance
+		}
+		return found[i].driver.ID.Hex() < found[j].driver.ID.Hex()
+	})
+
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found
+}
+
+// forEachCandidateCell calls visit for every occupied grid cell that may contain points within
+// radius meters of the given point. When the circle covers more cells than are occupied, the
+// occupied cells are checked against the covered ranges instead of enumerating the ranges.
+func (r *MemoryDriverRepository) forEachCandidateCell(latitude, longitude, radius float64, visit func(ids map[primitive.ObjectID]struct{})) {
+	_, lonWidth := geo.GeohashCellSize(memoryIndexPrecision)
+	_, maxLonIndex := geo.GeohashCellIndex(90, 180, memoryIndexPrecision)
+
+	// Latitude span of the circle, reaching over a pole covers every longitude. The spans are
+	// widened by a tiny margin so rounding never excludes a cell touching the circle.
+	const margin = 1e-9
+	angular := radius/geo.EarthRadius*180/math.Pi + margin
+	minLat, maxLat := latitude-angular, latitude+angular
+	allLongitudes := minLat <= -90 || maxLat >= 90
+
+	// Longitude span of a spherical cap, the widest point lies north or south of the center
+	var lonSpan float64
+	if !allLongitudes {
+		ratio := math.Sin(radius/geo.EarthRadius) / math.Cos(latitude*math.Pi/180)
+		if ratio >= 1 {
+			allLongitudes = true
+		} else {
+			lonSpan = math.Asin(ratio)*180/math.Pi + margin
+		}
+	}
+
+	minRow, _ := geo.GeohashCellIndex(math.Max(minLat, -90), 0, memoryIndexPrecision)
+	maxRow, _ := geo.GeohashCellIndex(math.Min(maxLat, 90), 0, memoryIndexPrecision)
+
+	columns := maxLonIndex + 1
+	var firstColumn, columnCount int64
+	if allLongitudes {
+		columnCount = columns
+	} else {
+		firstColumn = int64(math.Floor((longitude - lonSpan + 180) / lonWidth))
+		lastColumn := int64(math.Floor((longitude + lonSpan + 180) / lonWidth))
+		columnCount = min(lastColumn-firstColumn+1, columns)
+	}
+	firstColumn = (firstColumn%columns + columns) % columns
+
+	inRange := func(cell gridCell) bool {
+		if cell.lat < minRow || cell.lat > maxRow {
+			return false
+		}
+		offset := ((cell.lon-firstColumn)%columns + columns) % columns
+		return offset < columnCount
+	}
+
+	if (maxRow-minRow+1)*columnCount > int64(len(r.cells)) {
+		for cell, ids := range r.cells {
+			if inRange(cell) {
+				visit(ids)
+			}
+		}
+		return
+	}
+
+	for row := minRow; row <= maxRow; row++ {
+		for offset := int64(0); offset < columnCount; offset++ {
+			if ids, ok := r.cells[gridCell{row, (firstColumn + offset) % columns}]; ok {
+				visit(ids)
+			}
+		}
+	}
+}
+
+// index adds the driver to the grid cell of their location. The caller holds r.mu.
+func (r *MemoryDriverRepository) index(driver *models.DriverWithDistance) {
+	cell := cellOf(driver)
+	if r.cells[cell] == nil {
+		r.cells[cell] = make(map[primitive.ObjectID]struct{})
+	}
+	r.cells[cell][driver.ID] = struct{}{}
+}
+
+// unindex removes the driver from the grid cell of their location. The caller holds r.mu.
+func (r *MemoryDriverRepository) unindex(driver *models.DriverWithDistance) {
+	cell := cellOf(driver)
+	delete(r.cells[cell], driver.ID)
+	if len(r.cells[cell]) == 0 {
+		delete(r.cells, cell)
+	}
+}
+
+// validCoordinates reports whether the coordinates are a longitude and latitude in range, as the
+// 2dsphere index of MongoDB requires
+func validCoordinates(coordinates []float64) bool {
+	return len(coordinates) == 2 &&
+		coordinates[0] >= -180 && coordinates[0] <= 180 &&
+		coordinates[1] >= -90 && coordinates[1] <= 90
+}
+
+// cellOf returns the grid cell a driver is indexed in
+func cellOf(driver *models.DriverWithDistance) gridCell {
+	latIndex, lonIndex := geo.GeohashCellIndex(driver.Location.Coordinates[1], driver.Location.Coordinates[0], memoryIndexPrecision)
+	return gridCell{latIndex, lonIndex}
+}
+
+// isAvailable mirrors availableFilter for a driver held in memory
+func isAvailable(driver *models.DriverWithDistance, now time.Time) bool {
+	if driver.Status == "" || driver.Status == models.DriverStatusAvailable {
+		return true
+	}
+	return driver.ReservedUntil != nil && driver.ReservedUntil.Before(now)
+}
+
+// copyDriver returns a copy that does not share memory with the stored driver
+func copyDriver(driver *models.DriverWithDistance) models.DriverWithDistance {
+	copied := *driver
+	copied.Location.Coordinates = append([]float64(nil), driver.Location.Coordinates...)
+	if driver.ReservedUntil != nil {
+		reservedUntil := *driver.ReservedUntil
+		copied.ReservedUntil = &reservedUntil
+	}
+	if driver.LastSeen != nil {
+		lastSeen := *driver.LastSeen
+		copied.LastSeen = &lastSeen
+	}
+	return copied
+}
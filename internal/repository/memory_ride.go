@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"bitaksi-go-driver/internal/models"
+)
+
+// MemoryRideRepository keeps ride requests in memory. It behaves like RideRepository but forgets
+// everything on restart, and is only shared by the services of one process.
+type MemoryRideRepository struct {
+	mu    sync.Mutex
+	rides map[string]models.RideRequest
+	now   func() time.Time
+}
+
+func NewMemoryRideRepository() *MemoryRideRepository {
+	return &MemoryRideRepository{rides: make(map[string]models.RideRequest), now: time.Now}
+}
+
+func (r *MemoryRideRepository) CreateRide(ctx context.Context, ride *models.RideRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Expired ride requests are dropped here, like the TTL index does in MongoDB
+	now := r.now()
+	for id, stored := range r.rides {
+		if !stored.ExpiresAt.After(now) {
+			delete(r.rides, id)
+		}
+	}
+
+	ride.Version = 1
+	r.rides[ride.ID] = copyRide(*ride)
+	return nil
+}
+
+func (r *MemoryRideRepository) GetRide(ctx context.Context, id string) (*models.RideRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ride, ok := r.rides[id]
+	if !ok || !ride.ExpiresAt.After(r.now()) {
+		return nil, ErrRideNotFound
+	}
+	ride = copyRide(ride)
+	return &ride, nil
+}
+
+func (r *MemoryRideRepository) UpdateRide(ctx context.Context, ride *models.RideRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.rides[ride.ID]
+	if !ok || stored.Version != ride.Version {
+		return ErrRideConflict
+	}
+	ride.Version++
+	r.rides[ride.ID] = copyRide(*ride)
+	return nil
+}
+
+// copyRide returns a ride request that shares no slice with the given one
+func copyRide(ride models.RideRequest) models.RideRequest {
+	ride.Offers = append([]models.Offer{}, ride.Offers...)
+	ride.Candidates = append([]models.Candidate{}, ride.Candidates...)
+	if ride.DriverID != nil {
+		driverID := *ride.DriverID
+		ride.DriverID = &driverID
+	}
+	return ride
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// MemorySearchEventRepository keeps search events in memory. It behaves like SearchEventRepository
+// but forgets everything on restart.
+type MemorySearchEventRepository struct {
+	mu     sync.RWMutex
+	events []models.StoredSearchEvent
+}
+
+func NewMemorySearchEventRepository() *MemorySearchEventRepository {
+	return &MemorySearchEventRepository{}
+}
+
+func (r *MemorySearchEventRepository) SaveSearchEvents(ctx context.Context, events []models.StoredSearchEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range events {
+		event.ID = primitive.NewObjectID()
+		r.events = append(r.events, event)
+	}
+	return nil
+}
+
+// CountSearches returns the weighted searches per geohash cell of the given precision made within
+// the bounding box since the given time
+func (r *MemorySearchEventRepository) CountSearches(ctx context.Context, bounds geo.Bounds, precision int, since time.Time) (map[string]float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]float64)
+	for _, event := range r.events {
+		if event.Timestamp.Before(since) || !bounds.Contains(event.Location.Coordinates[1], event.Location.Coordinates[0]) {
+			continue
+		}
+
+		cell := event.Geohash
+		if len(cell) > precision {
+			cell = cell[:precision]
+		}
+		counts[cell] += event.Weight
+	}
+	return counts, nil
+}
+
+// AggregateUnmetDemand groups the search events of the query by geohash cell and hour of the day
+// and returns the cells with unmet demand, largest first.
+func (r *MemorySearchEventRepository) AggregateUnmetDemand(ctx context.Context, query models.DemandQuery) ([]models.DemandHotspot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type groupKey struct {
+		geohash string
+		hour    int
+	}
+	groups := make(map[groupKey]*models.DemandHotspot)
+
+	for _, event := range r.events {
+		if event.Timestamp.Before(query.From) || !event.Timestamp.Before(query.To) {
+			continue
+		}
+
+		key := groupKey{geohash: event.Geohash, hour: event.Timestamp.In(query.Location).Hour()}
+		if len(key.geohash) > query.Precision {
+			key.geohash = key.geohash[:query.Precision]
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			group = &models.DemandHotspot{Geohash: key.geohash, Hour: key.hour}
+			groups[key] = group
+		}
+		group.Searches += event.Weight
+		if !event.Found {
+			group.Unmet += event.Weight
+		}
+	}
+
+	hotspots := make([]models.DemandHotspot, 0, len(groups))
+	for _, group := range groups {
+		if group.Unmet <= 0 {
+			continue
+		}
+
+		bounds, err := geo.DecodeGeohash(group.Geohash)
+		if err != nil {
+			continue
+		}
+		latitude, longitude := bounds.Center()
+
+		group.UnmetRatio = group.Unmet / group.Searches
+		group.Center = models.Coordinates{Latitude: latitude, Longitude: longitude}
+		group.Bounds = bounds
+		hotspots = append(hotspots, *group)
+	}
+
+	sort.Slice(hotspots, func(i, j int) bool {
+		if hotspots[i].Unmet != hotspots[j].Unmet {
+			return hotspots[i].Unmet > hotspots[j].Unmet
+		}
+		if hotspots[i].Geohash != hotspots[j].Geohash {
+			return hotspots[i].Geohash < hotspots[j].Geohash
+		}
+		return hotspots[i].Hour < hotspots[j].Hour
+	})
+
+	if query.Limit > 0 && len(hotspots) > query.Limit {
+		hotspots = hotspots[:query.Limit]
+	}
+	return hotspots, nil
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+func point(latitude, longitude float64) models.DriverWithDistance {
+	return models.DriverWithDistance{Location: models.Location{Type: "Point", Coordinates: []float64{longitude, latitude}}}
+}
+
+func TestMemoryDriverRepository_MatchesBruteForce(t *testing.T) {
+	ctx := context.Background()
+	random := rand.New(rand.NewSource(1))
+	repo := NewMemoryDriverRepository()
+
+	var locations []models.DriverWithDistance
+	for i := 0; i < 2000; i++ {
+		// Dense around Istanbul, plus a sprinkle over the whole globe including the poles and the antimeridian
+		if i%4 == 0 {
+			locations = append(locations, point(random.Float64()*180-90, random.Float64()*360-180))
+		} else {
+			locations = append(locations, point(41+random.Float64()*0.2, 28.9+random.Float64()*0.2))
+		}
+	}
+	locations = append(locations, point(89.99, 10), point(89.99, -170), point(0.5, 179.99), point(0.5, -179.99))
+	if err := repo.SaveDrivers(ctx, locations); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	searches := []struct {
+		latitude, longitude float64
+		radius              int
+	}{
+		{41.1, 29, 1000},
+		{41.1, 29, 10000},
+		{89.9, 100, 50000},      // Circle over the north pole
+		{0.5, 179.999, 5000},    // Circle across the antimeridian
+		{-45, -120, 5000000},    // Circle covering a large part of the globe
+		{10, 10, 1},             // Nothing in range
+		{-89.999, 0, 100000000}, // Whole globe
+	}
+
+	for _, search := range searches {
+		var expected []float64
+		for _, location := range locations {
+			distance := geo.Distance(search.latitude, search.longitude, location.Location.Coordinates[1], location.Location.Coordinates[0])
+			if distance <= float64(search.radius) {
+				expected = append(expected, distance)
+			}
+		}
+		sort.Float64s(expected)
+		if len(expected) > 20 {
+			expected = expected[:20]
+		}
+
+		drivers, err := repo.FindNearestDrivers(ctx, search.latitude, search.longitude, search.radius, 20)
+		if len(expected) == 0 {
+			if !errors.Is(err, ErrDriverNotFound) {
+				t.Errorf("search %+v: expected ErrDriverNotFound, got %v", search, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("search %+v: expected no error, got %v", search, err)
+		}
+
+		if len(drivers) != len(expected) {
+			t.Fatalf("search %+v: expected %d drivers, got %d", search, len(expected), len(drivers))
+		}
+		for i, driver := range drivers {
+			if driver.Distance != expected[i] {
+				t.Errorf("search %+v: expected driver %d at %v, got %v", search, i, expected[i], driver.Distance)
+			}
+		}
+	}
+}
+
+func TestMemoryDriverRepository_Reservations(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := NewMemoryDriverRepository()
+	repo.now = func() time.Time { return now }
+
+	repo.SaveDrivers(ctx, []models.DriverWithDistance{point(41, 29), point(41.001, 29)})
+
+	claimed, err := repo.ClaimNearestDriver(ctx, 41, 29, 1000, 30*time.Second, "r1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if claimed.Distance != 0 || claimed.Status != models.DriverStatusReserved || claimed.ReservationID != "r1" {
+		t.Errorf("expected the nearest driver to be reserved, got %+v", claimed)
+	}
+
+	nearest, _ := repo.FindNearestDriver(ctx, 41, 29, 1000)
+	if nearest.ID == claimed.ID {
+		t.Errorf("expected the claimed driver to be skipped by searches")
+	}
+	if err := repo.ReserveDriver(ctx, claimed.ID, "r2"); !errors.Is(err, ErrDriverUnavailable) {
+		t.Errorf("expected ErrDriverUnavailable for a claimed driver, got %v", err)
+	}
+
+	// Unconfirmed claims expire
+	now = now.Add(time.Minute)
+	if err := repo.ConfirmReservation(ctx, claimed.ID, "r1"); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("expected ErrReservationNotFound for an expired claim, got %v", err)
+	}
+	if nearest, _ := repo.FindNearestDriver(ctx, 41, 29, 1000); nearest.ID != claimed.ID {
+		t.Errorf("expected the expired claim to be available again")
+	} else if nearest.Status != models.DriverStatusAvailable || nearest.ReservedUntil != nil || nearest.ReservationID != "" {
+		t.Errorf("expected the expired claim to be found without its reservation, got %+v", nearest)
+	}
+
+	// Confirmed reservations do not expire until released
+	claimed, _ = repo.ClaimNearestDriver(ctx, 41, 29, 1000, 30*time.Second, "r3")
+	if err := repo.ConfirmReservation(ctx, claimed.ID, "r1"); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("expected ErrReservationNotFound for the ID of an earlier claim, got %v", err)
+	}
+	if err := repo.ConfirmReservation(ctx, claimed.ID, "r3"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	now = now.Add(time.Hour)
+	if nearest, _ := repo.FindNearestDriver(ctx, 41, 29, 1000); nearest.ID == claimed.ID {
+		t.Errorf("expected the confirmed driver to stay reserved")
+	}
+
+	cells, _ := repo.AggregateDriverCells(ctx, geo.Bounds{MinLat: 40, MinLon: 28, MaxLat: 42, MaxLon: 30}, 4)
+	if len(cells) != 1 || cells[0].Count != 2 || cells[0].Available != 1 {
+		t.Errorf("expected one cell with one of two drivers available, got %+v", cells)
+	}
+
+	// Only the holder of the reservation can release the driver
+	if err := repo.ReleaseDriver(ctx, claimed.ID, "r1"); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("expected ErrReservationNotFound for another reservation ID, got %v", err)
+	}
+	if err := repo.ReleaseDriver(ctx, claimed.ID, "r3"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := repo.ReleaseDriver(ctx, claimed.ID, "r3"); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("expected ErrReservationNotFound when releasing twice, got %v", err)
+	}
+}
+
+func TestMemoryDriverRepository_InvalidLocations(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryDriverRepository()
+
+	invalid := []models.DriverWithDistance{
+		{Location: models.Location{Type: "Point"}},
+		{Location: models.Location{Type: "Point", Coordinates: []float64{29}}},
+		{Location: models.Location{Type: "Point", Coordinates: []float64{29, 41, 0}}},
+		point(91, 29),
+		point(41, -181),
+	}
+	for _, driver := range invalid {
+		if err := repo.SaveDrivers(ctx, []models.DriverWithDistance{point(41, 29), driver}); !errors.Is(err, ErrInvalidLocation) {
+			t.Errorf("expected ErrInvalidLocation for %v, got %v", driver.Location.Coordinates, err)
+		}
+	}
+
+	// Nothing of a batch with an invalid location is saved
+	if drivers, err := repo.ListDrivers(ctx); err != nil || len(drivers) != 0 {
+		t.Errorf("expected no drivers, got %+v, %v", drivers, err)
+	}
+}
+
+func TestMemorySearchEventRepository(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 8, 30, 0, 0, time.UTC)
+	repo := NewMemorySearchEventRepository()
+
+	repo.SaveSearchEvents(ctx, []models.StoredSearchEvent{
+		{Geohash: "sxk9730", Found: false, Weight: 2, Timestamp: at},
+		{Geohash: "sxk9731", Found: true, Weight: 2, Timestamp: at},
+		{Geohash: "sxk9732", Found: false, Weight: 2, Timestamp: at.Add(time.Hour)},
+		{Geohash: "u33db00", Found: true, Weight: 2, Timestamp: at},
+		{Geohash: "sxk9730", Found: false, Weight: 2, Timestamp: at.Add(-24 * time.Hour)},
+	})
+
+	istanbul, _ := time.LoadLocation("Europe/Istanbul")
+	hotspots, err := repo.AggregateUnmetDemand(ctx, models.DemandQuery{
+		From: at.Add(-time.Hour), To: at.Add(2 * time.Hour), Precision: 6, Location: istanbul, Limit: 10,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(hotspots) != 2 {
+		t.Fatalf("expected 2 hotspots, got %+v", hotspots)
+	}
+	if hotspots[0].Geohash != "sxk973" || hotspots[0].Hour != 11 || hotspots[0].Searches != 4 || hotspots[0].Unmet != 2 || hotspots[0].UnmetRatio != 0.5 {
+		t.Errorf("unexpected first hotspot %+v", hotspots[0])
+	}
+	if hotspots[1].Hour != 12 || hotspots[1].Unmet != 2 {
+		t.Errorf("unexpected second hotspot %+v", hotspots[1])
+	}
+}
+
+func TestMemorySearchEventRepository_CountSearches(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := NewMemorySearchEventRepository()
+
+	event := func(latitude, longitude float64, weight float64, at time.Time) models.StoredSearchEvent {
+		return models.StoredSearchEvent{
+			Location:  models.Location{Type: "Point", Coordinates: []float64{longitude, latitude}},
+			Geohash:   geo.EncodeGeohash(latitude, longitude, 7),
+			Weight:    weight,
+			Timestamp: at,
+		}
+	}
+	repo.SaveSearchEvents(ctx, []models.StoredSearchEvent{
+		event(41.0082, 28.9784, 10, now),
+		event(41.0083, 28.9785, 10, now.Add(-time.Minute)),
+		event(41.0082, 28.9784, 10, now.Add(-time.Hour)), // Before the window
+		event(40, 29, 10, now),                           // Outside the bounds
+	})
+
+	counts, err := repo.CountSearches(ctx, geo.Bounds{MinLat: 41, MinLon: 28.9, MaxLat: 41.1, MaxLon: 29}, 6, now.Add(-5*time.Minute))
+	if err != nil {
+		t.Fatalf("CountSearches failed: %v", err)
+	}
+	if hash := geo.EncodeGeohash(41.0082, 28.9784, 6); len(counts) != 1 || counts[hash] != 20 {
+		t.Errorf("expected 20 searches in %s, got %v", hash, counts)
+	}
+}
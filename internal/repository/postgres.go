@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// postgresDistance is the haversine distance in meters between the driver and the point ($1, $2).
+// It uses the same Earth radius as MongoDB rather than PostGIS' own, so every backend reports the
+// same distances and applies the same radius.
+var postgresDistance = fmt.Sprintf(`(2 * %[1]f * asin(least(1, sqrt(
+	power(sin(radians(latitude - $1) / 2), 2) +
+	cos(radians($1)) * cos(radians(latitude)) * power(sin(radians(longitude - $2) / 2), 2)))))`, geo.EarthRadius)
+
+// postgresNear matches drivers within $3 meters of the point ($1, $2). ST_DWithin uses the GiST index;
+// as PostGIS' sphere is smaller than geo.EarthRadius it matches a superset, which the exact distance trims.
+var postgresNear = fmt.Sprintf(`ST_DWithin(location, ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography, $3, false) AND %s <= $3`, postgresDistance)
+
+// postgresAvailable mirrors availableFilter, $4 being the current time
+const postgresAvailable = `(status IS NULL OR status = 'available' OR reserved_until < $4)`
+
+const postgresDriverSchema = `
+CREATE EXTENSION IF NOT EXISTS postgis;
+CREATE TABLE IF NOT EXISTS drivers (
+	id             text PRIMARY KEY,
+	latitude       double precision NOT NULL,
+	longitude      double precision NOT NULL,
+	location       geography(Point, 4326) GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography) STORED,
+	status         text,
+	reserved_until timestamptz,
+	reservation_id text,
+	last_seen      timestamptz
+);
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS last_seen timestamptz;
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS reservation_id text;`
+
+const postgresDriverIndex = `CREATE INDEX IF NOT EXISTS drivers_location_gist ON drivers USING gist (location)`
+
+// PostgresDriverRepository stores drivers in PostgreSQL with PostGIS. It behaves like DriverRepository.
+type PostgresDriverRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresDriverRepository(pool *pgxpool.Pool) PostgresDriverRepository {
+	return PostgresDriverRepository{pool: pool}
+}
+
+// EnsureSchema creates the PostGIS extension and the drivers table if they do not exist
+func (r *PostgresDriverRepository) EnsureSchema(ctx context.Context) error {
+	_, err := r.pool.Exec(ctx, postgresDriverSchema)
+	return err
+}
+
+// SaveDrivers stores new drivers as available. Drivers whose ID already exists are moved to the
+// given location and keep their status.
+func (r *PostgresDriverRepository) SaveDrivers(ctx context.Context, locations []models.DriverWithDistance) error {
+	// A row can only be upserted once per statement, so the last location of each ID wins
+	positions := make(map[string]int, len(locations))
+	var ids []string
+	var latitudes, longitudes []float64
+	for _, location := range locations {
+		id := location.ID
+		if id.IsZero() {
+			id = primitive.NewObjectID()
+		}
+
+		latitude, longitude := location.Location.Coordinates[1], location.Location.Coordinates[0]
+		if i, ok := positions[id.Hex()]; ok {
+			latitudes[i], longitudes[i] = latitude, longitude
+			continue
+		}
+		positions[id.Hex()] = len(ids)
+		ids = append(ids, id.Hex())
+		latitudes = append(latitudes, latitude)
+		longitudes = append(longitudes, longitude)
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO drivers (id, latitude, longitude, status, last_seen)
+		SELECT id, latitude, longitude, 'available', $4
+		FROM unnest($1::text[], $2::double precision[], $3::double precision[]) AS saved (id, latitude, longitude)
+		ON CONFLICT (id) DO UPDATE SET latitude = excluded.latitude, longitude = excluded.longitude, last_seen = excluded.last_seen`,
+		ids, latitudes, longitudes, time.Now())
+	return err
+}
+
+// UpdateDriverLocation moves a driver to a new position
+func (r *PostgresDriverRepository) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	result, err := r.pool.Exec(ctx, `UPDATE drivers SET latitude = $2, longitude = $3, last_seen = $4 WHERE id = $1`, id.Hex(), latitude, longitude, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUnknownDriver
+	}
+	return nil
+}
+
+// ListDrivers returns every driver regardless of availability, ordered by ID
+func (r *PostgresDriverRepository) ListDrivers(ctx context.Context) ([]models.DriverWithDistance, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id, latitude, longitude, status, reserved_until, last_seen, 0::double precision FROM drivers ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanPostgresDriver)
+}
+
+func (r *PostgresDriverRepository) FindNearestDriver(ctx context.Context, latitude, longitude float64, maxDistance int) (*models.DriverWithDistance, error) {
+	drivers, err := r.FindNearestDrivers(ctx, latitude, longitude, maxDistance, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &drivers[0], nil
+}
+
+// FindNearestDrivers returns up to limit available drivers within maxDistance meters, ordered by distance.
+func (r *PostgresDriverRepository) FindNearestDrivers(ctx context.Context, latitude, longitude float64, maxDistance, limit int) ([]models.DriverWithDistance, error) {
+	query := fmt.Sprintf(`
+		SELECT id, latitude, longitude, status, reserved_until, last_seen, %[1]s AS distance
+		FROM drivers
+		WHERE %[2]s AND %[3]s
+		ORDER BY distance, id
+		LIMIT $5`, postgresDistance, postgresNear, postgresAvailable)
+
+	rows, err := r.pool.Query(ctx, query, latitude, longitude, float64(maxDistance), time.Now(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	drivers, err := pgx.CollectRows(rows, scanPostgresDriver)
+	if err != nil {
+		return nil, err
+	}
+	for i := range drivers {
+		asAvailable(&drivers[i])
+	}
+
+	if len(drivers) == 0 {
+		return nil, ErrDriverNotFound
+	}
+	return drivers, nil
+}
+
+// ReserveDriver marks an available driver as reserved under the reservation ID. The availability
+// check and the update are a single statement, so a driver can only be reserved once.
+func (r *PostgresDriverRepository) ReserveDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE drivers SET status = 'reserved', reserved_until = NULL, reservation_id = $3
+		WHERE id = $1 AND (status IS NULL OR status = 'available' OR reserved_until < $2)`,
+		id.Hex(), time.Now(), reservationID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDriverUnavailable
+	}
+	return nil
+}
+
+// ClaimNearestDriver finds the nearest available driver and reserves them until now+ttl under the
+// reservation ID in a single statement. The row is locked while it is claimed, so concurrent claims
+// can never return the same driver.
+func (r *PostgresDriverRepository) ClaimNearestDriver(ctx context.Context, latitude, longitude float64, maxDistance int, ttl time.Duration, reservationID string) (*models.DriverWithDistance, error) {
+	query := fmt.Sprintf(`
+		UPDATE drivers SET status = 'reserved', reserved_until = $5, reservation_id = $6
+		WHERE id = (
+			SELECT id FROM drivers
+			WHERE %[2]s AND %[3]s
+			ORDER BY %[1]s, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, latitude, longitude, status, reserved_until, last_seen, %[1]s AS distance`, postgresDistance, postgresNear, postgresAvailable)
+
+	now := time.Now()
+	rows, err := r.pool.Query(ctx, query, latitude, longitude, float64(maxDistance), now, now.Add(ttl), reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := pgx.CollectOneRow(rows, scanPostgresDriver)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDriverNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	driver.ReservationID = reservationID
+	return &driver, nil
+}
+
+// ConfirmReservation turns an unexpired reservation into a permanent one
+func (r *PostgresDriverRepository) ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE drivers SET reserved_until = NULL
+		WHERE id = $1 AND status = 'reserved' AND reservation_id = $3 AND reserved_until >= $2`,
+		id.Hex(), time.Now(), reservationID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrReservationNotFound
+	}
+	return nil
+}
+
+// ReleaseDriver makes a driver reserved under the reservation ID available again
+func (r *PostgresDriverRepository) ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE drivers SET status = 'available', reserved_until = NULL, reservation_id = NULL
+		WHERE id = $1 AND status = 'reserved' AND reservation_id = $2`,
+		id.Hex(), reservationID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrReservationNotFound
+	}
+	return nil
+}
+
+// AggregateDriverCells counts drivers inside the bounding box per geohash cell of the given
+// precision, grouping on integer cell rows and columns like the MongoDB aggregation.
+func (r *PostgresDriverRepository) AggregateDriverCells(ctx context.Context, bounds geo.Bounds, precision int) ([]models.HeatmapCell, error) {
+	latHeight, lonWidth := geo.GeohashCellSize(precision)
+	maxLatIndex, maxLonIndex := geo.GeohashCellIndex(90, 180, precision)
+
+	longitudeFilter := `longitude BETWEEN $3 AND $4`
+	if bounds.MinLon > bounds.MaxLon {
+		longitudeFilter = `(longitude >= $3 OR longitude <= $4)`
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			least(floor((latitude + 90) / $5), $7)::bigint AS lat_index,
+			least(floor((longitude + 180) / $6), $8)::bigint AS lon_index,
+			count(*) AS count,
+			count(*) FILTER (WHERE status IS NULL OR status = 'available' OR reserved_until < $9) AS available
+		FROM drivers
+		WHERE latitude BETWEEN $1 AND $2 AND %s
+		GROUP BY lat_index, lon_index`, longitudeFilter)
+
+	rows, err := r.pool.Query(ctx, query,
+		bounds.MinLat, bounds.MaxLat, bounds.MinLon, bounds.MaxLon,
+		latHeight, lonWidth, float64(maxLatIndex), float64(maxLonIndex), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.HeatmapCell, error) {
+		var latIndex, lonIndex, count, available int64
+		if err := row.Scan(&latIndex, &lonIndex, &count, &available); err != nil {
+			return models.HeatmapCell{}, err
+		}
+
+		cellBounds := geo.GeohashCellBounds(latIndex, lonIndex, precision)
+		latitude, longitude := cellBounds.Center()
+
+		return models.HeatmapCell{
+			Geohash:   geo.GeohashFromCellIndex(latIndex, lonIndex, precision),
+			Count:     int(count),
+			Available: int(available),
+			Center:    models.Coordinates{Latitude: latitude, Longitude: longitude},
+			Bounds:    cellBounds,
+		}, nil
+	})
+}
+
+// EnsureIndex ensures that the drivers table has a GiST index on the location column.
+func (r *PostgresDriverRepository) EnsureIndex(ctx context.Context) error {
+	exists, err := r.HasIndex(ctx)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = r.pool.Exec(ctx, postgresDriverIndex)
+	return err
+}
+
+// HasIndex tells whether the geospatial index of the drivers table exists
+func (r *PostgresDriverRepository) HasIndex(ctx context.Context) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE tablename = 'drivers' AND indexname = 'drivers_location_gist')`).Scan(&exists)
+	return exists, err
+}
+
+// scanPostgresDriver reads a driver row selected as id, latitude, longitude, status, reserved_until, last_seen, distance
+func scanPostgresDriver(row pgx.CollectableRow) (models.DriverWithDistance, error) {
+	var (
+		id                  string
+		latitude, longitude float64
+		status              *string
+		driver              models.DriverWithDistance
+	)
+	if err := row.Scan(&id, &latitude, &longitude, &status, &driver.ReservedUntil, &driver.LastSeen, &driver.Distance); err != nil {
+		return models.DriverWithDistance{}, err
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.DriverWithDistance{}, err
+	}
+
+	driver.ID = objectID
+	driver.Location = models.Location{Type: "Point", Coordinates: []float64{longitude, latitude}}
+	if status != nil {
+		driver.Status = *status
+	}
+	return driver, nil
+}
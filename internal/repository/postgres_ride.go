@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+)
+
+const postgresRideSchema = `
+CREATE TABLE IF NOT EXISTS rides (
+	id               text PRIMARY KEY,
+	pickup_latitude  double precision NOT NULL,
+	pickup_longitude double precision NOT NULL,
+	radius           integer NOT NULL,
+	status           text NOT NULL,
+	driver_id        text,
+	reservation_id   text,
+	offers           jsonb NOT NULL,
+	candidates       jsonb NOT NULL,
+	created_at       timestamptz NOT NULL,
+	updated_at       timestamptz NOT NULL,
+	expires_at       timestamptz NOT NULL,
+	version          bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rides_expires_at ON rides (expires_at);`
+
+// PostgresRideRepository stores ride requests in PostgreSQL. It behaves like RideRepository,
+// deleting expired ride requests when new ones are created.
+type PostgresRideRepository struct {
+	pool *pgxpool.Pool
+	now  func() time.Time
+}
+
+func NewPostgresRideRepository(pool *pgxpool.Pool) PostgresRideRepository {
+	return PostgresRideRepository{pool: pool, now: time.Now}
+}
+
+// EnsureSchema creates the rides table if it does not exist
+func (r *PostgresRideRepository) EnsureSchema(ctx context.Context) error {
+	_, err := r.pool.Exec(ctx, postgresRideSchema)
+	return err
+}
+
+func (r *PostgresRideRepository) CreateRide(ctx context.Context, ride *models.RideRequest) error {
+	offers, candidates, err := postgresRideState(ride)
+	if err != nil {
+		return err
+	}
+	if _, err := r.pool.Exec(ctx, `DELETE FROM rides WHERE expires_at <= $1`, r.now()); err != nil {
+		return err
+	}
+
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO rides (id, pickup_latitude, pickup_longitude, radius, status, driver_id, reservation_id, offers, candidates, created_at, updated_at, expires_at, version)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $12, 1)`,
+		ride.ID, ride.Pickup.Latitude, ride.Pickup.Longitude, ride.Radius, ride.Status, postgresDriverID(ride.DriverID), ride.ReservationID,
+		offers, candidates, ride.CreatedAt, ride.UpdatedAt, ride.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	ride.Version = 1
+	return nil
+}
+
+func (r *PostgresRideRepository) GetRide(ctx context.Context, id string) (*models.RideRequest, error) {
+	var ride models.RideRequest
+	var driverID *string
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, pickup_latitude, pickup_longitude, radius, status, driver_id, coalesce(reservation_id, ''), offers, candidates, created_at, updated_at, expires_at, version
+		FROM rides
+		WHERE id = $1 AND expires_at > $2`, id, r.now()).Scan(
+		&ride.ID, &ride.Pickup.Latitude, &ride.Pickup.Longitude, &ride.Radius, &ride.Status, &driverID, &ride.ReservationID,
+		&ride.Offers, &ride.Candidates, &ride.CreatedAt, &ride.UpdatedAt, &ride.ExpiresAt, &ride.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRideNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if driverID != nil {
+		objectID, err := primitive.ObjectIDFromHex(*driverID)
+		if err != nil {
+			return nil, err
+		}
+		ride.DriverID = &objectID
+	}
+	return &ride, nil
+}
+
+// UpdateRide replaces the ride request if it is still at the version it was read at, and
+// increments its version
+func (r *PostgresRideRepository) UpdateRide(ctx context.Context, ride *models.RideRequest) error {
+	offers, candidates, err := postgresRideState(ride)
+	if err != nil {
+		return err
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE rides
+		SET status = $3, driver_id = $4, reservation_id = NULLIF($5, ''), offers = $6, candidates = $7, updated_at = $8, version = version + 1
+		WHERE id = $1 AND version = $2`,
+		ride.ID, ride.Version, ride.Status, postgresDriverID(ride.DriverID), ride.ReservationID, offers, candidates, ride.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRideConflict
+	}
+	ride.Version++
+	return nil
+}
+
+// postgresRideState encodes the offers and candidates of a ride request for their jsonb columns
+func postgresRideState(ride *models.RideRequest) (offers, candidates []byte, err error) {
+	if offers, err = json.Marshal(ride.Offers); err != nil {
+		return nil, nil, err
+	}
+	if candidates, err = json.Marshal(ride.Candidates); err != nil {
+		return nil, nil, err
+	}
+	return offers, candidates, nil
+}
+
+func postgresDriverID(id *primitive.ObjectID) *string {
+	if id == nil {
+		return nil
+	}
+	hex := id.Hex()
+	return &hex
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+const postgresSearchEventSchema = `
+CREATE TABLE IF NOT EXISTS search_events (
+	id          text PRIMARY KEY,
+	latitude    double precision NOT NULL,
+	longitude   double precision NOT NULL,
+	geohash     text NOT NULL,
+	radius      integer NOT NULL,
+	found       boolean NOT NULL,
+	weight      double precision NOT NULL,
+	searched_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS search_events_searched_at ON search_events (searched_at);`
+
+// PostgresSearchEventRepository stores search events in PostgreSQL. It behaves like SearchEventRepository.
+type PostgresSearchEventRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresSearchEventRepository(pool *pgxpool.Pool) PostgresSearchEventRepository {
+	return PostgresSearchEventRepository{pool: pool}
+}
+
+// EnsureSchema creates the search_events table if it does not exist
+func (r *PostgresSearchEventRepository) EnsureSchema(ctx context.Context) error {
+	_, err := r.pool.Exec(ctx, postgresSearchEventSchema)
+	return err
+}
+
+func (r *PostgresSearchEventRepository) SaveSearchEvents(ctx context.Context, events []models.StoredSearchEvent) error {
+	rows := make([][]interface{}, len(events))
+	for i, event := range events {
+		rows[i] = []interface{}{
+			primitive.NewObjectID().Hex(),
+			event.Location.Coordinates[1],
+			event.Location.Coordinates[0],
+			event.Geohash,
+			event.Radius,
+			event.Found,
+			event.Weight,
+			event.Timestamp,
+		}
+	}
+
+	_, err := r.pool.CopyFrom(ctx, pgx.Identifier{"search_events"},
+		[]string{"id", "latitude", "longitude", "geohash", "radius", "found", "weight", "searched_at"},
+		pgx.CopyFromRows(rows))
+	return err
+}
+
+// CountSearches returns the weighted searches per geohash cell of the given precision made within
+// the bounding box since the given time
+func (r *PostgresSearchEventRepository) CountSearches(ctx context.Context, bounds geo.Bounds, precision int, since time.Time) (map[string]float64, error) {
+	longitudeFilter := `longitude BETWEEN $5 AND $6`
+	if bounds.MinLon > bounds.MaxLon {
+		longitudeFilter = `(longitude >= $5 OR longitude <= $6)`
+	}
+
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT substr(geohash, 1, $1) AS cell, sum(weight) AS searches
+		FROM search_events
+		WHERE searched_at >= $2 AND latitude BETWEEN $3 AND $4 AND %s
+		GROUP BY cell`, longitudeFilter),
+		precision, since, bounds.MinLat, bounds.MaxLat, bounds.MinLon, bounds.MaxLon)
+	if err != nil {
+		return nil, err
+	}
+
+	type cellSearches struct {
+		cell     string
+		searches float64
+	}
+	groups, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (cellSearches, error) {
+		var group cellSearches
+		err := row.Scan(&group.cell, &group.searches)
+		return group, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]float64, len(groups))
+	for _, group := range groups {
+		counts[group.cell] = group.searches
+	}
+	return counts, nil
+}
+
+// AggregateUnmetDemand groups the search events of the query by geohash cell and hour of the day
+// and returns the cells with unmet demand, largest first.
+func (r *PostgresSearchEventRepository) AggregateUnmetDemand(ctx context.Context, query models.DemandQuery) ([]models.DemandHotspot, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			substr(geohash, 1, $1) AS cell,
+			extract(hour FROM searched_at AT TIME ZONE $2)::integer AS hour,
+			sum(weight) AS searches,
+			sum(CASE WHEN found THEN 0 ELSE weight END) AS unmet
+		FROM search_events
+		WHERE searched_at >= $3 AND searched_at < $4
+		GROUP BY cell, hour
+		HAVING sum(CASE WHEN found THEN 0 ELSE weight END) > 0
+		ORDER BY unmet DESC, cell, hour
+		LIMIT $5`,
+		query.Precision, query.Location.String(), query.From, query.To, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.DemandHotspot, error) {
+		var hotspot models.DemandHotspot
+		err := row.Scan(&hotspot.Geohash, &hotspot.Hour, &hotspot.Searches, &hotspot.Unmet)
+		return hotspot, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hotspots := make([]models.DemandHotspot, 0, len(groups))
+	for _, hotspot := range groups {
+		bounds, err := geo.DecodeGeohash(hotspot.Geohash)
+		if err != nil {
+			continue
+		}
+		latitude, longitude := bounds.Center()
+
+		hotspot.UnmetRatio = hotspot.Unmet / hotspot.Searches
+		hotspot.Center = models.Coordinates{Latitude: latitude, Longitude: longitude}
+		hotspot.Bounds = bounds
+		hotspots = append(hotspots, hotspot)
+	}
+
+	return hotspots, nil
+}
@@ -0,0 +1,120 @@
+package repositorytest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoURI returns MONGO_TEST_URI if set, otherwise starts an ephemeral mongod from PATH that is
+// stopped when the test ends. The second result is false if neither is available.
+func MongoURI(t *testing.T) (string, bool) {
+	t.Helper()
+
+	if uri := os.Getenv("MONGO_TEST_URI"); uri != "" {
+		return uri, true
+	}
+	return startMongod(t)
+}
+
+// MongoDatabase returns an empty database on the server at uri that is dropped when the test ends
+func MongoDatabase(t *testing.T, uri string) *mongo.Database {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatalf("failed to ping MongoDB: %v", err)
+	}
+
+	database := client.Database(fmt.Sprintf("driver_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		database.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return database
+}
+
+// startMongod starts a mongod with a temporary data directory that is stopped when the test ends
+func startMongod(t *testing.T) (string, bool) {
+	t.Helper()
+
+	path, err := exec.LookPath("mongod")
+	if err != nil {
+		return "", false
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cmd := exec.Command(path, "--dbpath", t.TempDir(), "--port", fmt.Sprint(port), "--bind_ip", "127.0.0.1", "--quiet")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start mongod: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	// Wait until mongod accepts connections
+	address := fmt.Sprintf("127.0.0.1:%d", port)
+	for deadline := time.Now().Add(20 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if conn, err := net.Dial("tcp", address); err == nil {
+			conn.Close()
+			return "mongodb://" + address, true
+		}
+	}
+
+	t.Fatalf("mongod did not start listening on %s", address)
+	return "", false
+}
+
+// PostgresPool returns a connection pool to POSTGRES_TEST_URL whose search path is a new schema,
+// dropped when the test ends. The second result is false if POSTGRES_TEST_URL is not set.
+func PostgresPool(t *testing.T) (*pgxpool.Pool, bool) {
+	t.Helper()
+
+	url := os.Getenv("POSTGRES_TEST_URL")
+	if url == "" {
+		return nil, false
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("invalid POSTGRES_TEST_URL: %v", err)
+	}
+	schema := fmt.Sprintf("driver_test_%d", time.Now().UnixNano())
+	poolConfig.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		t.Fatalf("failed to connect to PostgreSQL: %v", err)
+	}
+	if _, err := pool.Exec(context.Background(), "CREATE SCHEMA "+schema); err != nil {
+		pool.Close()
+		t.Fatalf("failed to create schema: %v", err)
+	}
+
+	t.Cleanup(func() {
+		pool.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+		pool.Close()
+	})
+	return pool, true
+}
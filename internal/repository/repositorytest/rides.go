@@ -0,0 +1,97 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+// RideRepository is the ride request contract checked by RunRides
+type RideRepository interface {
+	CreateRide(ctx context.Context, ride *models.RideRequest) error
+	GetRide(ctx context.Context, id string) (*models.RideRequest, error)
+	UpdateRide(ctx context.Context, ride *models.RideRequest) error
+}
+
+// RideFactory returns a new, empty ride repository. Cleanup should be registered on t.
+type RideFactory func(t *testing.T) RideRepository
+
+// RunRides checks that ride requests round-trip, expire and are only updated at the version read
+func RunRides(t *testing.T, newRepository RideFactory) {
+	ctx := context.Background()
+	repo := newRepository(t)
+
+	// Stored times are truncated to milliseconds by MongoDB and to microseconds by PostgreSQL
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	driverID := primitive.NewObjectID()
+	ride := &models.RideRequest{
+		ID:         primitive.NewObjectID().Hex(),
+		Pickup:     models.Coordinates{Latitude: 41, Longitude: 29},
+		Radius:     5000,
+		Status:     models.RideStatusOffering,
+		Offers:     []models.Offer{{DriverID: driverID, Distance: 120.5, Status: models.OfferStatusPending, OfferedAt: now, ExpiresAt: now.Add(time.Minute)}},
+		Candidates: []models.Candidate{{DriverID: primitive.NewObjectID(), Distance: 340}},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		ExpiresAt:  now.Add(time.Hour),
+	}
+	if err := repo.CreateRide(ctx, ride); err != nil {
+		t.Fatalf("CreateRide failed: %v", err)
+	}
+
+	stored, err := repo.GetRide(ctx, ride.ID)
+	if err != nil {
+		t.Fatalf("GetRide failed: %v", err)
+	}
+	if stored.Status != ride.Status || stored.Pickup != ride.Pickup || len(stored.Offers) != 1 || len(stored.Candidates) != 1 ||
+		stored.Offers[0].DriverID != driverID || !stored.Offers[0].ExpiresAt.Equal(ride.Offers[0].ExpiresAt) ||
+		stored.Candidates[0] != ride.Candidates[0] || !stored.CreatedAt.Equal(now) {
+		t.Errorf("expected the stored ride request %+v, got %+v", ride, stored)
+	}
+
+	// The first update wins, the second was made to the same version and is rejected
+	stale := *stored
+	stored.Status = models.RideStatusAccepted
+	stored.DriverID = &driverID
+	stored.ReservationID = "reservation"
+	stored.Offers[0].Status = models.OfferStatusAccepted
+	if err := repo.UpdateRide(ctx, stored); err != nil {
+		t.Fatalf("UpdateRide failed: %v", err)
+	}
+	stale.Status = models.RideStatusUnfulfilled
+	if err := repo.UpdateRide(ctx, &stale); !errors.Is(err, repository.ErrRideConflict) {
+		t.Errorf("expected ErrRideConflict, got %v", err)
+	}
+
+	updated, err := repo.GetRide(ctx, ride.ID)
+	if err != nil {
+		t.Fatalf("GetRide failed: %v", err)
+	}
+	if updated.Status != models.RideStatusAccepted || updated.DriverID == nil || *updated.DriverID != driverID ||
+		updated.ReservationID != "reservation" || updated.Offers[0].Status != models.OfferStatusAccepted {
+		t.Errorf("expected the accepted ride request, got %+v", updated)
+	}
+	if err := repo.UpdateRide(ctx, updated); err != nil {
+		t.Errorf("expected an update at the current version to succeed, got %v", err)
+	}
+
+	if _, err := repo.GetRide(ctx, primitive.NewObjectID().Hex()); !errors.Is(err, repository.ErrRideNotFound) {
+		t.Errorf("expected ErrRideNotFound, got %v", err)
+	}
+
+	expired := *ride
+	expired.ID = primitive.NewObjectID().Hex()
+	expired.ExpiresAt = now.Add(-time.Second)
+	if err := repo.CreateRide(ctx, &expired); err != nil {
+		t.Fatalf("CreateRide failed: %v", err)
+	}
+	if _, err := repo.GetRide(ctx, expired.ID); !errors.Is(err, repository.ErrRideNotFound) {
+		t.Errorf("expected an expired ride request to be gone, got %v", err)
+	}
+}
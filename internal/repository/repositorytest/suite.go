@@ -0,0 +1,236 @@
+// Package repositorytest provides a conformance suite that every driver repository backend must pass,
+// so the backends stay interchangeable.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+// DriverRepository is the repository contract checked by the suite
+type DriverRepository interface {
+	SaveDrivers(ctx context.Context, locations []models.DriverWithDistance) error
+	FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error)
+	FindNearestDrivers(ctx context.Context, latitude, longitude float64, radius, limit int) ([]models.DriverWithDistance, error)
+	UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error
+	ListDrivers(ctx context.Context) ([]models.DriverWithDistance, error)
+}
+
+// Setup prepares the storage of a repository the way startup does, e.g. by running the migrations. It must be
+// idempotent.
+type Setup func(ctx context.Context) error
+
+// Factory returns a new, empty repository and the setup of its storage, nil if it needs none. Cleanup should be
+// registered on t.
+type Factory func(t *testing.T) (DriverRepository, Setup)
+
+// distanceTolerance allows for backends computing great-circle distances with a different formula
+const distanceTolerance = 0.01 // meters
+
+// Run runs the conformance suite, creating a fresh repository for each test
+func Run(t *testing.T, newRepository Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo DriverRepository)
+	}{
+		{"EmptyCollection", testEmptyCollection},
+		{"OrderingByDistance", testOrderingByDistance},
+		{"Limit", testLimit},
+		{"RadiusBoundary", testRadiusBoundary},
+		{"Poles", testPoles},
+		{"Antimeridian", testAntimeridian},
+		{"UpdateLocation", testUpdateLocation},
+		{"SaveExisting", testSaveExisting},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, _ := setUp(t, newRepository)
+			tt.test(t, repo)
+		})
+	}
+
+	t.Run("SetupIdempotence", func(t *testing.T) {
+		repo, setup := setUp(t, newRepository)
+		save(t, repo, driverAt(41, 29))
+
+		for i := 0; i < 3; i++ {
+			if err := setup(context.Background()); err != nil {
+				t.Fatalf("setup call %d failed: %v", i+2, err)
+			}
+		}
+
+		expectDrivers(t, repo, 41, 29, 100, 5, [2]float64{41, 29})
+	})
+}
+
+// setUp creates a repository and runs its setup, returning the setup so it can be run again
+func setUp(t *testing.T, newRepository Factory) (DriverRepository, Setup) {
+	t.Helper()
+	repo, setup := newRepository(t)
+	if setup == nil {
+		setup = func(ctx context.Context) error { return nil }
+	}
+	if err := setup(context.Background()); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	return repo, setup
+}
+
+func driverAt(latitude, longitude float64) models.DriverWithDistance {
+	return models.DriverWithDistance{Location: models.Location{Type: "Point", Coordinates: []float64{longitude, latitude}}}
+}
+
+func save(t *testing.T, repo DriverRepository, drivers ...models.DriverWithDistance) {
+	t.Helper()
+	if err := repo.SaveDrivers(context.Background(), drivers); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+}
+
+// expectDrivers checks the search returns drivers at exactly the expected points, in order, with correct distances
+func expectDrivers(t *testing.T, repo DriverRepository, latitude, longitude float64, radius, limit int, expected ...[2]float64) {
+	t.Helper()
+
+	drivers, err := repo.FindNearestDrivers(context.Background(), latitude, longitude, radius, limit)
+	if len(expected) == 0 {
+		if !errors.Is(err, repository.ErrDriverNotFound) {
+			t.Fatalf("expected ErrDriverNotFound, got %v (%d drivers)", err, len(drivers))
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("FindNearestDrivers failed: %v", err)
+	}
+
+	if len(drivers) != len(expected) {
+		t.Fatalf("expected %d drivers, got %d: %+v", len(expected), len(drivers), drivers)
+	}
+	for i, driver := range drivers {
+		if driver.ID.IsZero() {
+			t.Errorf("driver %d has no ID", i)
+		}
+
+		gotLat, gotLon := driver.Location.Coordinates[1], driver.Location.Coordinates[0]
+		if gotLat != expected[i][0] || gotLon != expected[i][1] {
+			t.Errorf("driver %d: expected (%v, %v), got (%v, %v)", i, expected[i][0], expected[i][1], gotLat, gotLon)
+		}
+
+		distance := geo.Distance(latitude, longitude, gotLat, gotLon)
+		if math.Abs(driver.Distance-distance) > distanceTolerance {
+			t.Errorf("driver %d: expected distance %v, got %v", i, distance, driver.Distance)
+		}
+	}
+}
+
+func testEmptyCollection(t *testing.T, repo DriverRepository) {
+	if _, err := repo.FindNearestDriver(context.Background(), 41, 29, 10000); !errors.Is(err, repository.ErrDriverNotFound) {
+		t.Errorf("expected ErrDriverNotFound, got %v", err)
+	}
+	expectDrivers(t, repo, 41, 29, 10000, 5)
+}
+
+func testOrderingByDistance(t *testing.T, repo DriverRepository) {
+	// Saved out of order, each about 111m further north
+	save(t, repo, driverAt(41.003, 29), driverAt(41, 29), driverAt(41.004, 29), driverAt(41.001, 29), driverAt(41.002, 29))
+
+	driver, err := repo.FindNearestDriver(context.Background(), 41, 29, 1000)
+	if err != nil {
+		t.Fatalf("FindNearestDriver failed: %v", err)
+	}
+	if driver.Location.Coordinates[1] != 41 || driver.Distance > distanceTolerance {
+		t.Errorf("expected the driver at the search point, got %+v", driver)
+	}
+
+	expectDrivers(t, repo, 41, 29, 1000, 10,
+		[2]float64{41, 29}, [2]float64{41.001, 29}, [2]float64{41.002, 29}, [2]float64{41.003, 29}, [2]float64{41.004, 29})
+}
+
+func testLimit(t *testing.T, repo DriverRepository) {
+	save(t, repo, driverAt(41.002, 29), driverAt(41.001, 29), driverAt(41.003, 29))
+
+	expectDrivers(t, repo, 41, 29, 1000, 2, [2]float64{41.001, 29}, [2]float64{41.002, 29})
+}
+
+func testRadiusBoundary(t *testing.T, repo DriverRepository) {
+	// 0.009 degrees of latitude are 1001.85m
+	save(t, repo, driverAt(41.009, 29))
+
+	expectDrivers(t, repo, 41, 29, 1001, 5)
+	expectDrivers(t, repo, 41, 29, 1002, 5, [2]float64{41.009, 29})
+}
+
+func testPoles(t *testing.T, repo DriverRepository) {
+	// Opposite sides of the north pole are close together, though their longitudes are 180 degrees apart
+	save(t, repo, driverAt(89.9999, 0), driverAt(89.9998, 180), driverAt(-89.9999, 90))
+
+	expectDrivers(t, repo, 90, 0, 100, 5, [2]float64{89.9999, 0}, [2]float64{89.9998, 180})
+	expectDrivers(t, repo, 89.9999, 90, 100, 5, [2]float64{89.9999, 0}, [2]float64{89.9998, 180})
+	expectDrivers(t, repo, -90, 0, 100, 5, [2]float64{-89.9999, 90})
+}
+
+func testAntimeridian(t *testing.T, repo DriverRepository) {
+	save(t, repo, driverAt(0, 179.9995), driverAt(0, -179.999), driverAt(0, 179.99))
+
+	// Searching from just east of the antimeridian finds drivers on both sides, nearest first
+	expectDrivers(t, repo, 0, -179.9995, 1000, 5, [2]float64{0, -179.999}, [2]float64{0, 179.9995})
+	expectDrivers(t, repo, 0, 180, 2000, 5, [2]float64{0, 179.9995}, [2]float64{0, -179.999}, [2]float64{0, 179.99})
+}
+
+func testUpdateLocation(t *testing.T, repo DriverRepository) {
+	save(t, repo, driverAt(41, 29), driverAt(41.001, 29))
+
+	drivers, err := repo.ListDrivers(context.Background())
+	if err != nil {
+		t.Fatalf("ListDrivers failed: %v", err)
+	}
+	if len(drivers) != 2 {
+		t.Fatalf("expected 2 drivers, got %d", len(drivers))
+	}
+
+	var moved primitive.ObjectID
+	for _, driver := range drivers {
+		if driver.Location.Coordinates[1] == 41 {
+			moved = driver.ID
+		}
+	}
+
+	// Moving the nearest driver away makes the other one the nearest, even across index cells
+	if err := repo.UpdateDriverLocation(context.Background(), moved, 41.1, 29.1); err != nil {
+		t.Fatalf("UpdateDriverLocation failed: %v", err)
+	}
+	expectDrivers(t, repo, 41, 29, 1000, 5, [2]float64{41.001, 29})
+	expectDrivers(t, repo, 41.1, 29.1, 1000, 5, [2]float64{41.1, 29.1})
+
+	if err := repo.UpdateDriverLocation(context.Background(), primitive.NewObjectID(), 41, 29); !errors.Is(err, repository.ErrUnknownDriver) {
+		t.Errorf("expected ErrUnknownDriver, got %v", err)
+	}
+}
+
+func testSaveExisting(t *testing.T, repo DriverRepository) {
+	driver := driverAt(41, 29)
+	driver.ID = primitive.NewObjectID()
+	save(t, repo, driver)
+
+	// Saving a driver again, e.g. by importing an export, moves them instead of duplicating them
+	driver.Location.Coordinates = []float64{29.1, 41.1}
+	save(t, repo, driver, driverAt(41.001, 29))
+
+	drivers, err := repo.ListDrivers(context.Background())
+	if err != nil {
+		t.Fatalf("ListDrivers failed: %v", err)
+	}
+	if len(drivers) != 2 {
+		t.Fatalf("expected 2 drivers, got %d: %+v", len(drivers), drivers)
+	}
+	expectDrivers(t, repo, 41.1, 29.1, 1000, 5, [2]float64{41.1, 29.1})
+	expectDrivers(t, repo, 41, 29, 1000, 5, [2]float64{41.001, 29})
+}
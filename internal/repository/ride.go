@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"bitaksi-go-driver/internal/models"
+)
+
+// ErrRideNotFound is returned when a ride request does not exist or expired
+var ErrRideNotFound = errors.New("ride request not found")
+
+// ErrRideConflict is returned when a ride request changed since it was read
+var ErrRideConflict = errors.New("ride request was changed concurrently")
+
+// RideRepository stores ride requests in MongoDB. The collection expires them with a TTL index on
+// expires_at, which runs about once a minute, so expired ones are also filtered out when read.
+type RideRepository struct {
+	collection *mongo.Collection
+	now        func() time.Time
+}
+
+func NewRideRepository(db *mongo.Database, collectionName string) RideRepository {
+	return RideRepository{collection: db.Collection(collectionName), now: time.Now}
+}
+
+func (r *RideRepository) CreateRide(ctx context.Context, ride *models.RideRequest) error {
+	ride.Version = 1
+	_, err := r.collection.InsertOne(ctx, ride)
+	return err
+}
+
+func (r *RideRepository) GetRide(ctx context.Context, id string) (*models.RideRequest, error) {
+	var ride models.RideRequest
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "expires_at": bson.M{"$gt": r.now()}}).Decode(&ride)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRideNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ride, nil
+}
+
+// UpdateRide replaces the ride request if it is still at the version it was read at, and
+// increments its version
+func (r *RideRepository) UpdateRide(ctx context.Context, ride *models.RideRequest) error {
+	updated := *ride
+	updated.Version++
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": ride.ID, "version": ride.Version}, updated)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrRideConflict
+	}
+	ride.Version = updated.Version
+	return nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+type SearchEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSearchEventRepository(db *mongo.Database, collectionName string) SearchEventRepository {
+	return SearchEventRepository{collection: db.Collection(collectionName)}
+}
+
+func (r *SearchEventRepository) SaveSearchEvents(ctx context.Context, events []models.StoredSearchEvent) error {
+	documents := make([]interface{}, len(events))
+	for i, event := range events {
+		documents[i] = event
+	}
+
+	_, err := r.collection.InsertMany(ctx, documents)
+	return err
+}
+
+// CountSearches returns the weighted searches per geohash cell of the given precision made within
+// the bounding box since the given time. The timestamp index limits the scan to recent events.
+func (r *SearchEventRepository) CountSearches(ctx context.Context, bounds geo.Bounds, precision int, since time.Time) (map[string]float64, error) {
+	filter := boundsFilter(bounds)
+	filter["timestamp"] = bson.M{"$gte": since}
+
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"$substrCP": bson.A{"$geohash", 0, precision}},
+			"searches": bson.M{"$sum": "$weight"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Geohash  string  `bson:"_id"`
+		Searches float64 `bson:"searches"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]float64, len(groups))
+	for _, group := range groups {
+		counts[group.Geohash] = group.Searches
+	}
+	return counts, nil
+}
+
+// AggregateUnmetDemand groups the search events of the query by geohash cell and hour of the day
+// and returns the cells with unmet demand, largest first.
+func (r *SearchEventRepository) AggregateUnmetDemand(ctx context.Context, query models.DemandQuery) ([]models.DemandHotspot, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"timestamp": bson.M{"$gte": query.From, "$lt": query.To},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"geohash": bson.M{"$substrCP": bson.A{"$geohash", 0, query.Precision}},
+				"hour":    bson.M{"$hour": bson.M{"date": "$timestamp", "timezone": query.Location.String()}},
+			},
+			"searches": bson.M{"$sum": "$weight"},
+			"unmet":    bson.M{"$sum": bson.M{"$cond": bson.A{"$found", 0, "$weight"}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"unmet": bson.M{"$gt": 0}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "unmet", Value: -1}, {Key: "_id.geohash", Value: 1}, {Key: "_id.hour", Value: 1}}}},
+		{{Key: "$limit", Value: query.Limit}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		ID struct {
+			Geohash string `bson:"geohash"`
+			Hour    int    `bson:"hour"`
+		} `bson:"_id"`
+		Searches float64 `bson:"searches"`
+		Unmet    float64 `bson:"unmet"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	hotspots := make([]models.DemandHotspot, 0, len(groups))
+	for _, group := range groups {
+		bounds, err := geo.DecodeGeohash(group.ID.Geohash)
+		if err != nil {
+			continue
+		}
+		latitude, longitude := bounds.Center()
+
+		hotspots = append(hotspots, models.DemandHotspot{
+			Geohash:    group.ID.Geohash,
+			Hour:       group.ID.Hour,
+			Searches:   group.Searches,
+			Unmet:      group.Unmet,
+			UnmetRatio: group.Unmet / group.Searches,
+			Center:     models.Coordinates{Latitude: latitude, Longitude: longitude},
+			Bounds:     bounds,
+		})
+	}
+
+	return hotspots, nil
+}
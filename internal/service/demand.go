@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// SearchEventRepository stores search events and aggregates them into demand hotspots and surge demand
+type SearchEventRepository interface {
+	SearchCounter
+	SaveSearchEvents(ctx context.Context, events []models.StoredSearchEvent) error
+	AggregateUnmetDemand(ctx context.Context, query models.DemandQuery) ([]models.DemandHotspot, error)
+}
+
+// ErrInvalidDemandQuery is returned when a demand query cannot be answered from the stored events
+var ErrInvalidDemandQuery = errors.New("invalid demand query")
+
+const demandBatchSize = 100 // Maximum events written per insert
+
+// DemandRecorder persists a sample of searches as demand signals. Searches are anonymised by
+// snapping them to the center of their geohash cell and written in batches in the background,
+// so recording never blocks a search; when the buffer is full events are dropped.
+type DemandRecorder struct {
+	repo          SearchEventRepository
+	sampleRate    float64
+	precision     int
+	flushInterval time.Duration
+
+	events  chan models.StoredSearchEvent
+	done    chan struct{}
+	mu      sync.Mutex // Guards random, dropped and closed, and sending on events against closing it
+	random  *rand.Rand
+	dropped int
+	closed  bool
+}
+
+// NewDemandRecorder creates a recorder keeping the given fraction of searches at the given geohash precision
+func NewDemandRecorder(repo SearchEventRepository, sampleRate float64, precision, bufferSize int, flushInterval time.Duration) *DemandRecorder {
+	return &DemandRecorder{
+		repo:          repo,
+		sampleRate:    sampleRate,
+		precision:     precision,
+		flushInterval: flushInterval,
+		events:        make(chan models.StoredSearchEvent, bufferSize),
+		done:          make(chan struct{}),
+		random:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// RecordSearch samples, anonymises and queues a search event
+func (r *DemandRecorder) RecordSearch(ctx context.Context, event models.SearchEvent) {
+	r.mu.Lock()
+	sampled := r.random.Float64() < r.sampleRate
+	r.mu.Unlock()
+	if !sampled {
+		return
+	}
+
+	hash := geo.EncodeGeohash(event.Latitude, event.Longitude, r.precision)
+	bounds, _ := geo.DecodeGeohash(hash)
+	latitude, longitude := bounds.Center()
+
+	stored := models.StoredSearchEvent{
+		Location:  models.Location{Type: "Point", Coordinates: []float64{longitude, latitude}},
+		Geohash:   hash,
+		Radius:    event.Radius,
+		Found:     event.Found,
+		Weight:    1 / r.sampleRate,
+		Timestamp: event.Timestamp.UTC().Truncate(time.Second),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		// Searches still finishing during shutdown are not recorded
+		return
+	}
+	select {
+	case r.events <- stored:
+	default:
+		r.dropped++
+	}
+}
+
+// Start writes queued events until Close is called
+func (r *DemandRecorder) Start() {
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.flushInterval)
+		defer ticker.Stop()
+
+		var batch []models.StoredSearchEvent
+		for {
+			select {
+			case event, ok := <-r.events:
+				if !ok {
+					r.flush(batch)
+					return
+				}
+				batch = append(batch, event)
+				if len(batch) >= demandBatchSize {
+					batch = r.flush(batch)
+				}
+			case <-ticker.C:
+				batch = r.flush(batch)
+			}
+		}
+	}()
+}
+
+// Close stops accepting events and waits until the queued events are written
+func (r *DemandRecorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.events)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush writes the batch and returns a new empty batch, as the saved one may still be referenced
+func (r *DemandRecorder) flush(batch []models.StoredSearchEvent) []models.StoredSearchEvent {
+	r.mu.Lock()
+	dropped := r.dropped
+	r.dropped = 0
+	r.mu.Unlock()
+	if dropped > 0 {
+		slog.Warn("Dropped search events, the demand recorder buffer is full", "dropped", dropped)
+	}
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := r.repo.SaveSearchEvents(ctx, batch); err != nil {
+		slog.ErrorContext(ctx, "Failed to save search events", "events", len(batch), "error", err)
+	}
+
+	return nil
+}
+
+// DemandService reports where searches end without a driver
+type DemandService struct {
+	repo            SearchEventRepository
+	storedPrecision int
+}
+
+// NewDemandService creates a demand service over events stored at the given geohash precision
+func NewDemandService(repo SearchEventRepository, storedPrecision int) DemandService {
+	return DemandService{repo: repo, storedPrecision: storedPrecision}
+}
+
+// UnmetDemand returns the cells and hours of the day with the most searches that found no driver
+func (s *DemandService) UnmetDemand(ctx context.Context, query models.DemandQuery) ([]models.DemandHotspot, error) {
+	if query.Precision > s.storedPrecision {
+		return nil, fmt.Errorf("%w: precision must be at most %d", ErrInvalidDemandQuery, s.storedPrecision)
+	}
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidDemandQuery)
+	}
+
+	hotspots, err := s.repo.AggregateUnmetDemand(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate search events: %w", err)
+	}
+
+	return hotspots, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// StubSearchEventRepository keeps saved search events in memory
+type StubSearchEventRepository struct {
+	mu       sync.Mutex
+	saved    []models.StoredSearchEvent
+	hotspots []models.DemandHotspot
+	query    models.DemandQuery
+}
+
+func (s *StubSearchEventRepository) SaveSearchEvents(ctx context.Context, events []models.StoredSearchEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = append(s.saved, events...)
+	return nil
+}
+
+func (s *StubSearchEventRepository) CountSearches(ctx context.Context, bounds geo.Bounds, precision int, since time.Time) (map[string]float64, error) {
+	return nil, nil
+}
+
+func (s *StubSearchEventRepository) AggregateUnmetDemand(ctx context.Context, query models.DemandQuery) ([]models.DemandHotspot, error) {
+	s.query = query
+	return s.hotspots, nil
+}
+
+func TestDemandRecorder(t *testing.T) {
+	repo := &StubSearchEventRepository{}
+	recorder := NewDemandRecorder(repo, 1, 7, 10, time.Hour)
+	recorder.Start()
+
+	timestamp := time.Date(2024, 1, 1, 12, 30, 15, 500, time.UTC)
+	recorder.RecordSearch(context.Background(), models.SearchEvent{Latitude: 41.0082, Longitude: 28.9784, Radius: 500, Found: false, Timestamp: timestamp})
+	recorder.RecordSearch(context.Background(), models.SearchEvent{Latitude: 41.0082, Longitude: 28.9784, Radius: 500, Found: true, Timestamp: timestamp})
+
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(repo.saved) != 2 {
+		t.Fatalf("expected 2 saved events, got %d", len(repo.saved))
+	}
+
+	event := repo.saved[0]
+	hash := geo.EncodeGeohash(41.0082, 28.9784, 7)
+	bounds, _ := geo.DecodeGeohash(hash)
+	latitude, longitude := bounds.Center()
+	if event.Geohash != hash || event.Location.Coordinates[0] != longitude || event.Location.Coordinates[1] != latitude {
+		t.Errorf("expected the search to be snapped to the center of %s, got %+v", hash, event)
+	}
+	if event.Weight != 1 || event.Found || event.Radius != 500 {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if !event.Timestamp.Equal(timestamp.Truncate(time.Second)) {
+		t.Errorf("expected the timestamp truncated to the second, got %v", event.Timestamp)
+	}
+}
+
+func TestDemandRecorder_Sampling(t *testing.T) {
+	repo := &StubSearchEventRepository{}
+	recorder := NewDemandRecorder(repo, 0.25, 7, 1000, time.Hour)
+	recorder.Start()
+
+	for i := 0; i < 400; i++ {
+		recorder.RecordSearch(context.Background(), models.SearchEvent{Latitude: 41, Longitude: 29, Timestamp: time.Now()})
+	}
+	recorder.Close(context.Background())
+
+	// With 400 searches the sample is within 60..140 with overwhelming probability
+	if len(repo.saved) < 60 || len(repo.saved) > 140 {
+		t.Errorf("expected about 100 sampled events, got %d", len(repo.saved))
+	}
+	for _, event := range repo.saved {
+		if event.Weight != 4 {
+			t.Fatalf("expected each sample to stand for 4 searches, got %v", event.Weight)
+		}
+	}
+}
+
+func TestDemandRecorder_DropsWhenFull(t *testing.T) {
+	repo := &StubSearchEventRepository{}
+	recorder := NewDemandRecorder(repo, 1, 7, 2, time.Hour)
+
+	// Not started yet, so nothing drains the buffer
+	for i := 0; i < 5; i++ {
+		recorder.RecordSearch(context.Background(), models.SearchEvent{Latitude: 41, Longitude: 29, Timestamp: time.Now()})
+	}
+
+	recorder.Start()
+	recorder.Close(context.Background())
+
+	if len(repo.saved) != 2 {
+		t.Errorf("expected the events beyond the buffer to be dropped, got %d saved", len(repo.saved))
+	}
+}
+
+func TestDemandRecorder_RecordAfterClose(t *testing.T) {
+	repo := &StubSearchEventRepository{}
+	recorder := NewDemandRecorder(repo, 1, 7, 10, time.Hour)
+	recorder.Start()
+
+	// Searches finishing while the server shuts down race with closing the recorder
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder.RecordSearch(context.Background(), models.SearchEvent{Latitude: 41, Longitude: 29, Timestamp: time.Now()})
+		}()
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	wg.Wait()
+
+	recorder.RecordSearch(context.Background(), models.SearchEvent{Latitude: 41, Longitude: 29, Timestamp: time.Now()})
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Errorf("expected closing twice to succeed, got %v", err)
+	}
+}
+
+func TestUnmetDemand(t *testing.T) {
+	to := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	query := models.DemandQuery{From: to.Add(-24 * time.Hour), To: to, Precision: 6, Location: time.UTC, Limit: 10}
+
+	repo := &StubSearchEventRepository{hotspots: []models.DemandHotspot{{Geohash: "sxk973", Hour: 8, Searches: 40, Unmet: 30, UnmetRatio: 0.75}}}
+	demandService := NewDemandService(repo, 7)
+
+	hotspots, err := demandService.UnmetDemand(context.Background(), query)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(hotspots) != 1 || hotspots[0].Geohash != "sxk973" || repo.query != query {
+		t.Errorf("unexpected hotspots %+v for query %+v", hotspots, repo.query)
+	}
+
+	tooFine := query
+	tooFine.Precision = 8
+	if _, err := demandService.UnmetDemand(context.Background(), tooFine); !errors.Is(err, ErrInvalidDemandQuery) {
+		t.Errorf("expected ErrInvalidDemandQuery for a precision finer than stored, got %v", err)
+	}
+
+	reversed := query
+	reversed.From, reversed.To = query.To, query.From
+	if _, err := demandService.UnmetDemand(context.Background(), reversed); !errors.Is(err, ErrInvalidDemandQuery) {
+		t.Errorf("expected ErrInvalidDemandQuery for a reversed period, got %v", err)
+	}
+}
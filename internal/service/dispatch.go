@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+var (
+	// ErrRideNotFound is returned when a ride request does not exist
+	ErrRideNotFound = repository.ErrRideNotFound
+	// ErrNoActiveOffer is returned when the driver has no pending offer for the ride request
+	ErrNoActiveOffer = errors.New("driver has no pending offer for this ride request")
+)
+
+const rideRetention = time.Hour // How long finished ride requests stay queryable
+
+// RideRepository stores ride requests. UpdateRide stores a ride request only if it was not updated
+// since it was read, and returns repository.ErrRideConflict otherwise.
+type RideRepository interface {
+	CreateRide(ctx context.Context, ride *models.RideRequest) error
+	GetRide(ctx context.Context, id string) (*models.RideRequest, error)
+	UpdateRide(ctx context.Context, ride *models.RideRequest) error
+}
+
+// DispatchService offers ride requests to the nearest available drivers one at a time. Ride
+// requests live in the storage backend, so any replica can serve them. Offers expire without a
+// timer: an offer past its expiry is marked expired and the next driver is offered the ride, as
+// of the expiry, whenever the ride request is read.
+type DispatchService struct {
+	drivers      DriverRepository
+	reservations ReservationRepository
+	rides        RideRepository
+	offerTimeout time.Duration
+	maxOffers    int
+	now          func() time.Time
+}
+
+func NewDispatchService(drivers DriverRepository, reservations ReservationRepository, rides RideRepository, offerTimeout time.Duration, maxOffers int) *DispatchService {
+	return &DispatchService{
+		drivers:      drivers,
+		reservations: reservations,
+		rides:        rides,
+		offerTimeout: offerTimeout,
+		maxOffers:    maxOffers,
+		now:          time.Now,
+	}
+}
+
+// CreateRide creates a ride request at the pickup point and offers it to the nearest available driver
+func (s *DispatchService) CreateRide(ctx context.Context, latitude, longitude float64, radius int) (*models.RideRequest, error) {
+	drivers, err := s.drivers.FindNearestDrivers(ctx, latitude, longitude, radius, s.maxOffers)
+	if err != nil {
+		return nil, wrapFindError(err, radius)
+	}
+
+	candidates := make([]models.Candidate, len(drivers))
+	for i, driver := range drivers {
+		candidates[i] = models.Candidate{DriverID: driver.ID, Distance: driver.Distance}
+	}
+
+	now := s.now()
+	ride := &models.RideRequest{
+		ID:         primitive.NewObjectID().Hex(),
+		Pickup:     models.Coordinates{Latitude: latitude, Longitude: longitude},
+		Radius:     radius,
+		Status:     models.RideStatusOffering,
+		Offers:     []models.Offer{},
+		Candidates: candidates,
+		CreatedAt:  now,
+		// Every candidate has responded or timed out by then, so the ride request is finished
+		ExpiresAt: now.Add(time.Duration(len(candidates))*s.offerTimeout + rideRetention),
+	}
+	s.offerNext(ride, now)
+
+	if err := s.rides.CreateRide(ctx, ride); err != nil {
+		return nil, fmt.Errorf("failed to store ride request: %w", err)
+	}
+	return ride, nil
+}
+
+// GetRide returns the current state of a ride request
+func (s *DispatchService) GetRide(ctx context.Context, id string) (*models.RideRequest, error) {
+	return s.update(ctx, id, func(ride *models.RideRequest, now time.Time) (bool, error) {
+		return false, nil
+	})
+}
+
+// AcceptOffer reserves the driver for the ride, under the reservation ID the accepted ride request
+// carries. If the driver was reserved elsewhere in the meantime the offer is marked unavailable,
+// the next driver is offered and ErrDriverUnavailable is returned.
+func (s *DispatchService) AcceptOffer(ctx context.Context, id string, driverID primitive.ObjectID) (*models.RideRequest, error) {
+	reservationID := newReservationID()
+	reserved := false
+	ride, err := s.update(ctx, id, func(ride *models.RideRequest, now time.Time) (bool, error) {
+		offer := pendingOffer(ride, driverID)
+		if offer == nil {
+			return false, ErrNoActiveOffer
+		}
+
+		// Only reserved once, if the ride request was updated concurrently this runs again
+		if !reserved {
+			if err := s.reservations.ReserveDriver(ctx, driverID, reservationID); err != nil {
+				if !errors.Is(err, repository.ErrDriverUnavailable) {
+					return false, fmt.Errorf("failed to reserve driver %s: %w", driverID.Hex(), err)
+				}
+				offer.Status = models.OfferStatusUnavailable
+				s.offerNext(ride, now)
+				return true, fmt.Errorf("failed to reserve driver %s: %w", driverID.Hex(), err)
+			}
+			reserved = true
+		}
+
+		offer.Status = models.OfferStatusAccepted
+		ride.Status = models.RideStatusAccepted
+		ride.DriverID = &driverID
+		ride.ReservationID = reservationID
+		ride.UpdatedAt = now
+		return true, nil
+	})
+	if err != nil && reserved {
+		// The offer expired before the acceptance could be stored, so nobody holds the driver
+		if err := s.reservations.ReleaseDriver(context.WithoutCancel(ctx), driverID, reservationID); err != nil {
+			slog.ErrorContext(ctx, "Failed to release driver of an unstored acceptance", "ride_id", id, "driver_id", driverID.Hex(), "error", err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ride, nil
+}
+
+// DeclineOffer records the driver's refusal and offers the ride to the next driver
+func (s *DispatchService) DeclineOffer(ctx context.Context, id string, driverID primitive.ObjectID) (*models.RideRequest, error) {
+	ride, err := s.update(ctx, id, func(ride *models.RideRequest, now time.Time) (bool, error) {
+		offer := pendingOffer(ride, driverID)
+		if offer == nil {
+			return false, ErrNoActiveOffer
+		}
+
+		offer.Status = models.OfferStatusDeclined
+		s.offerNext(ride, now)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ride, nil
+}
+
+// update reads the ride request, expires its overdue offers and applies change, which reports
+// whether it changed the ride request. The changes are stored unless the ride request was updated
+// concurrently, in which case it is read and changed again. The error of change is returned after
+// storing, so that e.g. an offer marked unavailable is kept.
+func (s *DispatchService) update(ctx context.Context, id string, change func(ride *models.RideRequest, now time.Time) (bool, error)) (*models.RideRequest, error) {
+	for {
+		ride, err := s.rides.GetRide(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		now := s.now()
+		expired := s.expireOffers(ctx, ride, now)
+		changed, changeErr := change(ride, now)
+		if !expired && !changed {
+			return ride, changeErr
+		}
+
+		err = s.rides.UpdateRide(ctx, ride)
+		if errors.Is(err, repository.ErrRideConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to store ride request %s: %w", id, err)
+		}
+		return ride, changeErr
+	}
+}
+
+// expireOffers marks the offers past their expiry expired, offering the ride to the next driver
+// as of each expiry, and reports whether any expired
+func (s *DispatchService) expireOffers(ctx context.Context, ride *models.RideRequest, now time.Time) bool {
+	expired := false
+	for ride.Status == models.RideStatusOffering && len(ride.Offers) > 0 {
+		offer := &ride.Offers[len(ride.Offers)-1]
+		if offer.Status != models.OfferStatusPending || now.Before(offer.ExpiresAt) {
+			break
+		}
+
+		slog.InfoContext(ctx, "Ride offer expired", "ride_id", ride.ID, "driver_id", offer.DriverID.Hex())
+		offer.Status = models.OfferStatusExpired
+		s.offerNext(ride, offer.ExpiresAt)
+		expired = true
+	}
+	return expired
+}
+
+// offerNext offers the ride to the next candidate at the given time or marks it unfulfilled
+func (s *DispatchService) offerNext(ride *models.RideRequest, at time.Time) {
+	ride.UpdatedAt = at
+
+	if len(ride.Candidates) == 0 {
+		ride.Status = models.RideStatusUnfulfilled
+		return
+	}
+
+	candidate := ride.Candidates[0]
+	ride.Candidates = ride.Candidates[1:]
+	ride.Offers = append(ride.Offers, models.Offer{
+		DriverID:  candidate.DriverID,
+		Distance:  candidate.Distance,
+		Status:    models.OfferStatusPending,
+		OfferedAt: at,
+		ExpiresAt: at.Add(s.offerTimeout),
+	})
+}
+
+// pendingOffer returns the current offer if it is pending for the given driver
+func pendingOffer(ride *models.RideRequest, driverID primitive.ObjectID) *models.Offer {
+	if ride.Status != models.RideStatusOffering || len(ride.Offers) == 0 {
+		return nil
+	}
+
+	offer := &ride.Offers[len(ride.Offers)-1]
+	if offer.DriverID != driverID || offer.Status != models.OfferStatusPending {
+		return nil
+	}
+	return offer
+}
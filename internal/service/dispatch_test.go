@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+func TestDispatchService_OfferFlow(t *testing.T) {
+	drivers := testDrivers()
+
+	tests := []struct {
+		name            string
+		setupMock       func(mockRepo *MockDriverRepository)
+		respond         func(t *testing.T, service *DispatchService, id string)
+		expectedStatus  string
+		expectedDriver  int // Index into drivers, -1 for none
+		expectedOffers  []string
+		expectedErr     error
+		expectedRespErr bool
+		waitForExpiry   bool
+	}{
+		{
+			name: "First Driver Accepts",
+			setupMock: func(mockRepo *MockDriverRepository) {
+				mockRepo.On("ReserveDriver", mock.Anything, drivers[0].ID, mock.AnythingOfType("string")).Return(nil).Once()
+			},
+			respond: func(t *testing.T, service *DispatchService, id string) {
+				ride, err := service.AcceptOffer(context.Background(), id, drivers[0].ID)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if ride.ReservationID == "" {
+					t.Error("expected the accepted ride request to carry the reservation ID")
+				}
+			},
+			expectedStatus: models.RideStatusAccepted,
+			expectedDriver: 0,
+			expectedOffers: []string{models.OfferStatusAccepted},
+		},
+		{
+			name: "Decline Moves To Next Driver",
+			setupMock: func(mockRepo *MockDriverRepository) {
+				mockRepo.On("ReserveDriver", mock.Anything, drivers[1].ID, mock.AnythingOfType("string")).Return(nil).Once()
+			},
+			respond: func(t *testing.T, service *DispatchService, id string) {
+				if _, err := service.DeclineOffer(context.Background(), id, drivers[0].ID); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if _, err := service.AcceptOffer(context.Background(), id, drivers[1].ID); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			},
+			expectedStatus: models.RideStatusAccepted,
+			expectedDriver: 1,
+			expectedOffers: []string{models.OfferStatusDeclined, models.OfferStatusAccepted},
+		},
+		{
+			name: "Reserved Elsewhere Moves To Next Driver",
+			setupMock: func(mockRepo *MockDriverRepository) {
+				mockRepo.On("ReserveDriver", mock.Anything, drivers[0].ID, mock.AnythingOfType("string")).Return(repository.ErrDriverUnavailable).Once()
+			},
+			respond: func(t *testing.T, service *DispatchService, id string) {
+				_, err := service.AcceptOffer(context.Background(), id, drivers[0].ID)
+				if !errors.Is(err, repository.ErrDriverUnavailable) {
+					t.Fatalf("expected ErrDriverUnavailable, got: %v", err)
+				}
+			},
+			expectedStatus: models.RideStatusOffering,
+			expectedDriver: -1,
+			expectedOffers: []string{models.OfferStatusUnavailable, models.OfferStatusPending},
+		},
+		{
+			name:      "Offers Expire Until Unfulfilled",
+			setupMock: func(mockRepo *MockDriverRepository) {},
+			respond: func(t *testing.T, service *DispatchService, id string) {
+				_, err := service.AcceptOffer(context.Background(), id, drivers[1].ID)
+				if !errors.Is(err, ErrNoActiveOffer) {
+					t.Fatalf("expected ErrNoActiveOffer, got: %v", err)
+				}
+			},
+			waitForExpiry:  true,
+			expectedStatus: models.RideStatusUnfulfilled,
+			expectedDriver: -1,
+			expectedOffers: []string{models.OfferStatusExpired, models.OfferStatusExpired, models.OfferStatusExpired},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockDriverRepository{}
+			mockRepo.On("FindNearestDrivers", mock.Anything, 41.0, 29.0, 5000, 3).Return(drivers, nil).Once()
+			tt.setupMock(mockRepo)
+
+			timeout := time.Minute
+			if tt.waitForExpiry {
+				timeout = 10 * time.Millisecond
+			}
+			service := NewDispatchService(mockRepo, mockRepo, repository.NewMemoryRideRepository(), timeout, 3)
+
+			ride, err := service.CreateRide(context.Background(), 41.0, 29.0, 5000)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ride.Status != models.RideStatusOffering || len(ride.Offers) != 1 || ride.Offers[0].DriverID != drivers[0].ID {
+				t.Fatalf("expected ride offered to the nearest driver, got %+v", ride)
+			}
+
+			tt.respond(t, service, ride.ID)
+
+			deadline := time.Now().Add(time.Second)
+			for tt.waitForExpiry && time.Now().Before(deadline) {
+				ride, _ = service.GetRide(context.Background(), ride.ID)
+				if ride.Status == tt.expectedStatus {
+					break
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			ride, err = service.GetRide(context.Background(), ride.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ride.Status != tt.expectedStatus {
+				t.Errorf("expected status %s, got %s", tt.expectedStatus, ride.Status)
+			}
+			if tt.expectedDriver >= 0 && (ride.DriverID == nil || *ride.DriverID != drivers[tt.expectedDriver].ID) {
+				t.Errorf("expected driver %s, got %v", drivers[tt.expectedDriver].ID.Hex(), ride.DriverID)
+			}
+			if tt.expectedDriver < 0 && ride.DriverID != nil {
+				t.Errorf("expected no driver, got %s", ride.DriverID.Hex())
+			}
+			if len(ride.Offers) != len(tt.expectedOffers) {
+				t.Fatalf("expected %d offers, got %d", len(tt.expectedOffers), len(ride.Offers))
+			}
+			for i, status := range tt.expectedOffers {
+				if ride.Offers[i].Status != status {
+					t.Errorf("offer %d: expected status %s, got %s", i, status, ride.Offers[i].Status)
+				}
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDispatchService_Errors(t *testing.T) {
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("FindNearestDrivers", mock.Anything, 41.0, 29.0, 5000, 3).Return(nil, repository.ErrDriverNotFound).Once()
+	service := NewDispatchService(mockRepo, mockRepo, repository.NewMemoryRideRepository(), time.Minute, 3)
+
+	if _, err := service.CreateRide(context.Background(), 41.0, 29.0, 5000); !errors.Is(err, repository.ErrDriverNotFound) {
+		t.Errorf("expected ErrDriverNotFound, got: %v", err)
+	}
+	if _, err := service.GetRide(context.Background(), "missing"); !errors.Is(err, ErrRideNotFound) {
+		t.Errorf("expected ErrRideNotFound, got: %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDispatchService_SharedAcrossReplicas(t *testing.T) {
+	drivers := testDrivers()
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("FindNearestDrivers", mock.Anything, 41.0, 29.0, 5000, 3).Return(drivers, nil).Once()
+	mockRepo.On("ReserveDriver", mock.Anything, drivers[2].ID, mock.AnythingOfType("string")).Return(nil).Once()
+
+	// Two replicas sharing the storage backend, with a clock the test advances
+	rides := repository.NewMemoryRideRepository()
+	now := time.Now()
+	clock := func() time.Time { return now }
+	creator := NewDispatchService(mockRepo, mockRepo, rides, time.Minute, 3)
+	creator.now = clock
+	other := NewDispatchService(mockRepo, mockRepo, rides, time.Minute, 3)
+	other.now = clock
+
+	ride, err := creator.CreateRide(context.Background(), 41.0, 29.0, 5000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := other.DeclineOffer(context.Background(), ride.ID, drivers[0].ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The second offer expires without anybody watching, the next driver is offered the ride as of then
+	now = now.Add(90 * time.Second)
+	ride, err = other.GetRide(context.Background(), ride.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ride.Offers) != 3 || ride.Offers[1].Status != models.OfferStatusExpired || ride.Offers[2].DriverID != drivers[2].ID {
+		t.Fatalf("expected the third driver to be offered the ride, got %+v", ride.Offers)
+	}
+	if want := ride.CreatedAt.Add(time.Minute); !ride.Offers[2].OfferedAt.Equal(want) {
+		t.Errorf("expected the offer at %v, got %v", want, ride.Offers[2].OfferedAt)
+	}
+
+	if _, err := creator.AcceptOffer(context.Background(), ride.ID, drivers[2].ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ride, err = other.GetRide(context.Background(), ride.ID)
+	if err != nil || ride.Status != models.RideStatusAccepted || *ride.DriverID != drivers[2].ID {
+		t.Errorf("expected the ride accepted by the third driver, got %+v, %v", ride, err)
+	}
+	mockRepo.AssertExpectations(t)
+}
@@ -5,8 +5,15 @@ import (
 	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"bitaksi-go-driver/internal/models"
 	"bitaksi-go-driver/internal/repository"
@@ -20,28 +27,85 @@ const (
 type DriverRepository interface {
 	SaveDrivers(ctx context.Context, locations []models.DriverWithDistance) error
 	FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error)
-	EnsureIndex(ctx context.Context) error
+	FindNearestDrivers(ctx context.Context, latitude, longitude float64, radius, limit int) ([]models.DriverWithDistance, error)
+	UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error
+	ListDrivers(ctx context.Context) ([]models.DriverWithDistance, error)
+}
+
+// SearchRecorder is notified of every nearest-driver search that completed without an internal error
+type SearchRecorder interface {
+	RecordSearch(ctx context.Context, event models.SearchEvent)
 }
 
 type DriverService struct {
-	repo DriverRepository
+	repo          DriverRepository
+	eta           ETAProvider
+	etaCandidates int
+	recorders     []SearchRecorder
+	searchCache   *SearchCache
+}
+
+// Option configures optional DriverService behaviour
+type Option func(*DriverService)
+
+// WithETAProvider re-ranks the given number of straight-line nearest drivers by estimated travel time
+func WithETAProvider(provider ETAProvider, candidates int) Option {
+	return func(s *DriverService) {
+		s.eta = provider
+		s.etaCandidates = candidates
+	}
+}
+
+// WithSearchRecorder reports searches to the recorder, e.g. as a demand signal
+func WithSearchRecorder(recorder SearchRecorder) Option {
+	return func(s *DriverService) {
+		s.recorders = append(s.recorders, recorder)
+	}
 }
 
-func NewDriverService(repo DriverRepository) DriverService {
-	return DriverService{repo: repo}
+// WithSearchCache answers repeated searches from nearly the same point from the cache
+func WithSearchCache(cache *SearchCache) Option {
+	return func(s *DriverService) {
+		s.searchCache = cache
+	}
+}
+
+func NewDriverService(repo DriverRepository, opts ...Option) DriverService {
+	s := DriverService{repo: repo}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
 }
 
+// ImportLocations imports the bundled sample drivers
 func (s *DriverService) ImportLocations(ctx context.Context) error {
-	file, err := os.Open(filePath)
+	_, err := s.ImportLocationsFromFile(ctx, filePath)
+	return err
+}
+
+// ImportLocationsFromFile imports drivers from a CSV file with a header and latitude, longitude rows.
+// An optional third column holds the driver ID, so files written by ExportLocations keep their IDs.
+// New drivers are available, drivers that already exist are moved and keep their status, so an
+// export can be imported again into the database it came from.
+func (s *DriverService) ImportLocationsFromFile(ctx context.Context, path string) (count int, err error) {
+	ctx, span := startSpan(ctx, "DriverService.ImportLocations", attribute.String("import.file", path))
+	defer func() {
+		span.SetAttributes(attribute.Int("import.rows", count))
+		endSpan(span, err)
+	}()
+
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to open CSV file at %s: %w", filePath, err)
+		return 0, fmt.Errorf("failed to open CSV file at %s: %w", path, err)
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
 	if err != nil {
-		return fmt.Errorf("failed to read CSV file: %w", err)
+		return 0, fmt.Errorf("failed to read CSV file: %w", err)
 	}
 
 	var locations []models.DriverWithDistance
@@ -49,42 +113,175 @@ func (s *DriverService) ImportLocations(ctx context.Context) error {
 		if i == 0 {
 			continue // Skip header
 		}
+		if len(record) < 2 {
+			return 0, fmt.Errorf("invalid data format in CSV at row %d", i+1)
+		}
 
 		latitude, err1 := strconv.ParseFloat(record[0], 64)
 		longitude, err2 := strconv.ParseFloat(record[1], 64)
 		if err1 != nil || err2 != nil {
-			return fmt.Errorf("invalid data format in CSV at row %d", i+1)
+			return 0, fmt.Errorf("invalid data format in CSV at row %d", i+1)
 		}
 
-		locations = append(locations, models.DriverWithDistance{
+		location := models.DriverWithDistance{
 			Location: models.Location{
 				Type:        "Point",
 				Coordinates: []float64{longitude, latitude},
 			},
-		})
+		}
+		if len(record) > 2 && record[2] != "" {
+			if location.ID, err = primitive.ObjectIDFromHex(record[2]); err != nil {
+				return 0, fmt.Errorf("invalid driver ID in CSV at row %d", i+1)
+			}
+		}
+		locations = append(locations, location)
 	}
 
 	if err := s.repo.SaveDrivers(ctx, locations); err != nil {
-		return fmt.Errorf("failed to save drivers to repository: %w", err)
+		return 0, fmt.Errorf("failed to save drivers to repository: %w", err)
 	}
 
-	return nil
+	return len(locations), nil
 }
 
-func (s *DriverService) FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
-	// Ensure the geospatial index exists
-	if err := s.repo.EnsureIndex(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ensure index: %w", err)
+// ExportLocations writes the location of every driver as a CSV file that ImportLocationsFromFile
+// reads back. Reservations are not exported, as they only hold in the database they were made in.
+// A stored driver without a longitude and latitude fails the export before anything is written.
+func (s *DriverService) ExportLocations(ctx context.Context, w io.Writer) (int, error) {
+	drivers, err := s.repo.ListDrivers(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list drivers: %w", err)
+	}
+	for _, driver := range drivers {
+		if len(driver.Location.Coordinates) != 2 {
+			return 0, fmt.Errorf("driver %s has invalid coordinates %v", driver.ID.Hex(), driver.Location.Coordinates)
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Latitude", "Longitude", "ID"})
+	for _, driver := range drivers {
+		writer.Write([]string{
+			strconv.FormatFloat(driver.Location.Coordinates[1], 'f', -1, 64),
+			strconv.FormatFloat(driver.Location.Coordinates[0], 'f', -1, 64),
+			driver.ID.Hex(),
+		})
 	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return 0, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return len(drivers), nil
+}
+
+func (s *DriverService) FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (_ *models.DriverWithDistance, err error) {
+	ctx, span := startSpan(ctx, "DriverService.FindNearestDriver",
+		attribute.Float64("search.latitude", latitude),
+		attribute.Float64("search.longitude", longitude),
+		attribute.Int("search.radius", radius),
+	)
+	defer func() { endSpan(span, err) }()
 
 	// Perform the geospatial search
-	driver, err := s.repo.FindNearestDriver(ctx, latitude, longitude, radius)
+	driver, err := s.findNearestDriver(ctx, latitude, longitude, radius)
+	span.SetAttributes(attribute.Bool("search.found", err == nil))
+
+	if err == nil || errors.Is(err, repository.ErrDriverNotFound) {
+		s.recordSearch(ctx, models.SearchEvent{
+			Latitude:  latitude,
+			Longitude: longitude,
+			Radius:    radius,
+			Found:     err == nil,
+			Timestamp: time.Now(),
+		})
+	}
+
 	if err != nil {
-		if errors.Is(err, repository.ErrDriverNotFound) {
-			return nil, fmt.Errorf("no drivers found within the radius of %d meters: %w", radius, err)
-		}
-		return nil, fmt.Errorf("failed to find nearest driver: %w", err)
+		return nil, wrapFindError(err, radius)
 	}
 
 	return driver, nil
 }
+
+// AddDrivers saves new available drivers, keeping the IDs set by the caller
+func (s *DriverService) AddDrivers(ctx context.Context, drivers []models.DriverWithDistance) error {
+	if err := s.repo.SaveDrivers(ctx, drivers); err != nil {
+		return fmt.Errorf("failed to save drivers to repository: %w", err)
+	}
+	return nil
+}
+
+// UpdateDriverLocation moves a driver to a new position
+func (s *DriverService) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) (err error) {
+	ctx, span := startSpan(ctx, "DriverService.UpdateDriverLocation", attribute.String("driver.id", id.Hex()))
+	defer func() { endSpan(span, err) }()
+
+	if err := s.repo.UpdateDriverLocation(ctx, id, latitude, longitude); err != nil {
+		return fmt.Errorf("failed to update location of driver %s: %w", id.Hex(), err)
+	}
+	if s.searchCache != nil {
+		s.searchCache.DriverMoved(id, latitude, longitude)
+	}
+	return nil
+}
+
+// findNearestDriver answers the search from the search cache if possible
+func (s *DriverService) findNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
+	if s.searchCache != nil {
+		driver, ok := s.searchCache.Get(latitude, longitude, radius)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("search.cache_hit", ok))
+		if ok {
+			if driver == nil {
+				return nil, repository.ErrDriverNotFound
+			}
+			return driver, nil
+		}
+	}
+
+	var driver *models.DriverWithDistance
+	var err error
+	if s.eta != nil && s.etaCandidates > 1 {
+		driver, err = s.findNearestDriverByETA(ctx, latitude, longitude, radius)
+	} else {
+		driver, err = s.repo.FindNearestDriver(ctx, latitude, longitude, radius)
+	}
+
+	if s.searchCache != nil && (err == nil || errors.Is(err, repository.ErrDriverNotFound)) {
+		s.searchCache.Put(latitude, longitude, radius, driver)
+	}
+	return driver, err
+}
+
+func (s *DriverService) recordSearch(ctx context.Context, event models.SearchEvent) {
+	for _, recorder := range s.recorders {
+		recorder.RecordSearch(ctx, event)
+	}
+}
+
+// findNearestDriverByETA picks the driver with the shortest estimated travel time among the
+// straight-line nearest candidates, falling back to the straight-line nearest if estimation fails.
+func (s *DriverService) findNearestDriverByETA(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
+	candidates, err := s.repo.FindNearestDrivers(ctx, latitude, longitude, radius, s.etaCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	pickup := models.Coordinates{Latitude: latitude, Longitude: longitude}
+	etaCtx, span := startSpan(ctx, "DriverService.RankByETA", attribute.Int("eta.candidates", len(candidates)))
+	ranked, err := rankByETA(etaCtx, s.eta, pickup, candidates)
+	endSpan(span, err)
+	if err != nil {
+		slog.WarnContext(ctx, "ETA estimation failed, using straight-line distance", "error", err)
+		return &candidates[0], nil
+	}
+
+	return &ranked[0], nil
+}
+
+func wrapFindError(err error, radius int) error {
+	if errors.Is(err, repository.ErrDriverNotFound) {
+		return fmt.Errorf("no drivers found within the radius of %d meters: %w", radius, err)
+	}
+	return fmt.Errorf("failed to find nearest driver: %w", err)
+}
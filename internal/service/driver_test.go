@@ -4,10 +4,15 @@ import (
 	"context"
 	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"bitaksi-go-driver/internal/geo"
 	"bitaksi-go-driver/internal/models"
 	"bitaksi-go-driver/internal/repository"
 )
@@ -22,19 +27,66 @@ func (m *MockDriverRepository) SaveDrivers(ctx context.Context, locations []mode
 	return args.Error(0)
 }
 
-func (m *MockDriverRepository) EnsureIndex(ctx context.Context) error {
+func (m *MockDriverRepository) FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
+	args := m.Called(ctx, latitude, longitude, radius)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DriverWithDistance), args.Error(1)
+}
+
+func (m *MockDriverRepository) FindNearestDrivers(ctx context.Context, latitude, longitude float64, radius, limit int) ([]models.DriverWithDistance, error) {
+	args := m.Called(ctx, latitude, longitude, radius, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DriverWithDistance), args.Error(1)
+}
+
+func (m *MockDriverRepository) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	args := m.Called(ctx, id, latitude, longitude)
+	return args.Error(0)
+}
+
+func (m *MockDriverRepository) ListDrivers(ctx context.Context) ([]models.DriverWithDistance, error) {
 	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DriverWithDistance), args.Error(1)
+}
+
+func (m *MockDriverRepository) ReserveDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	args := m.Called(ctx, id, reservationID)
 	return args.Error(0)
 }
 
-func (m *MockDriverRepository) FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
-	args := m.Called(ctx, latitude, longitude, radius)
+func (m *MockDriverRepository) ClaimNearestDriver(ctx context.Context, latitude, longitude float64, radius int, ttl time.Duration, reservationID string) (*models.DriverWithDistance, error) {
+	args := m.Called(ctx, latitude, longitude, radius, ttl, reservationID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.DriverWithDistance), args.Error(1)
 }
 
+func (m *MockDriverRepository) ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	args := m.Called(ctx, id, reservationID)
+	return args.Error(0)
+}
+
+func (m *MockDriverRepository) ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	args := m.Called(ctx, id, reservationID)
+	return args.Error(0)
+}
+
+func (m *MockDriverRepository) AggregateDriverCells(ctx context.Context, bounds geo.Bounds, precision int) ([]models.HeatmapCell, error) {
+	args := m.Called(ctx, bounds, precision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.HeatmapCell), args.Error(1)
+}
+
 func createTestCSVFile(t *testing.T, content string) string {
 	t.Helper()
 
@@ -136,7 +188,6 @@ func TestFindNearestDriver(t *testing.T) {
 		{
 			name: "Successful Find",
 			setupMock: func() {
-				mockRepo.On("EnsureIndex", mock.Anything).Return(nil).Once()
 				mockRepo.On("FindNearestDriver", mock.Anything, 40.748817, -73.985428, 5000).
 					Return(&models.DriverWithDistance{
 						Location: models.Location{
@@ -154,7 +205,6 @@ func TestFindNearestDriver(t *testing.T) {
 		{
 			name: "No Drivers Found",
 			setupMock: func() {
-				mockRepo.On("EnsureIndex", mock.Anything).Return(nil).Once()
 				mockRepo.On("FindNearestDriver", mock.Anything, 40.748817, -73.985428, 5000).
 					Return(nil, repository.ErrDriverNotFound).Once()
 			},
@@ -164,9 +214,10 @@ func TestFindNearestDriver(t *testing.T) {
 			expectedErr: true,
 		},
 		{
-			name: "EnsureIndex Fails",
+			name: "Repository Fails",
 			setupMock: func() {
-				mockRepo.On("EnsureIndex", mock.Anything).Return(errors.New("index creation failed")).Once()
+				mockRepo.On("FindNearestDriver", mock.Anything, 40.748817, -73.985428, 5000).
+					Return(nil, errors.New("connection lost")).Once()
 			},
 			latitude:    40.748817,
 			longitude:   -73.985428,
@@ -186,3 +237,92 @@ func TestFindNearestDriver(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateDriverLocation(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("UpdateDriverLocation", mock.Anything, id, 41.01, 29.02).Return(nil).Once()
+	mockRepo.On("UpdateDriverLocation", mock.Anything, id, 0.0, 0.0).Return(repository.ErrUnknownDriver).Once()
+
+	driverService := NewDriverService(mockRepo)
+
+	if err := driverService.UpdateDriverLocation(context.Background(), id, 41.01, 29.02); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := driverService.UpdateDriverLocation(context.Background(), id, 0, 0); !errors.Is(err, repository.ErrUnknownDriver) {
+		t.Errorf("expected ErrUnknownDriver, got %v", err)
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestExportImportLocations(t *testing.T) {
+	ctx := context.Background()
+	source := repository.NewMemoryDriverRepository()
+	if err := source.SaveDrivers(ctx, []models.DriverWithDistance{
+		{Location: models.Location{Type: "Point", Coordinates: []float64{29.0390297, 40.94289771}}},
+		{Location: models.Location{Type: "Point", Coordinates: []float64{-73.985428, 40.748817}}},
+	}); err != nil {
+		t.Fatalf("failed to save drivers: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "drivers.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	sourceService := NewDriverService(source)
+	if count, err := sourceService.ExportLocations(ctx, file); err != nil || count != 2 {
+		t.Fatalf("expected 2 exported drivers, got %d, %v", count, err)
+	}
+	file.Close()
+
+	target := repository.NewMemoryDriverRepository()
+	targetService := NewDriverService(target)
+	if count, err := targetService.ImportLocationsFromFile(ctx, path); err != nil || count != 2 {
+		t.Fatalf("expected 2 imported drivers, got %d, %v", count, err)
+	}
+
+	exported, _ := source.ListDrivers(ctx)
+	imported, _ := target.ListDrivers(ctx)
+	if len(imported) != len(exported) {
+		t.Fatalf("expected %d imported drivers, got %d", len(exported), len(imported))
+	}
+	for i := range exported {
+		if imported[i].ID != exported[i].ID || imported[i].Location.Coordinates[0] != exported[i].Location.Coordinates[0] ||
+			imported[i].Location.Coordinates[1] != exported[i].Location.Coordinates[1] {
+			t.Errorf("driver %d: expected %+v, got %+v", i, exported[i], imported[i])
+		}
+	}
+
+	// Importing the export back into its source keeps the drivers and their reservations
+	if err := source.ReserveDriver(ctx, exported[0].ID, "r1"); err != nil {
+		t.Fatalf("failed to reserve driver: %v", err)
+	}
+	if count, err := sourceService.ImportLocationsFromFile(ctx, path); err != nil || count != 2 {
+		t.Fatalf("expected 2 imported drivers, got %d, %v", count, err)
+	}
+	reimported, _ := source.ListDrivers(ctx)
+	if len(reimported) != 2 || reimported[0].Status != models.DriverStatusReserved || reimported[1].Status != models.DriverStatusAvailable {
+		t.Errorf("expected the drivers to keep their status, got %+v", reimported)
+	}
+}
+
+func TestExportLocations_InvalidCoordinates(t *testing.T) {
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("ListDrivers", mock.Anything).Return([]models.DriverWithDistance{
+		{ID: primitive.NewObjectID(), Location: models.Location{Type: "Point", Coordinates: []float64{29, 41}}},
+		{ID: primitive.NewObjectID(), Location: models.Location{Type: "Point", Coordinates: []float64{29}}},
+	}, nil).Once()
+
+	driverService := NewDriverService(mockRepo)
+	var out strings.Builder
+	if _, err := driverService.ExportLocations(context.Background(), &out); err == nil {
+		t.Errorf("expected an error for a driver without a latitude")
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected nothing to be written, got %q", out.String())
+	}
+	mockRepo.AssertExpectations(t)
+}
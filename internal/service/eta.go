@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"bitaksi-go-driver/internal/models"
+)
+
+// ETAProvider estimates how long each candidate driver needs to reach the pickup point.
+// The returned slice must have the same length and order as drivers; a negative
+// duration marks a driver the provider cannot route to the pickup point.
+type ETAProvider interface {
+	EstimateETAs(ctx context.Context, pickup models.Coordinates, drivers []models.DriverWithDistance) ([]time.Duration, error)
+}
+
+// SpeedProfile is the expected average road speed for the hours in [StartHour, EndHour).
+type SpeedProfile struct {
+	StartHour int
+	EndHour   int
+	SpeedKmh  float64
+}
+
+// DefaultSpeedProfiles roughly follow Istanbul traffic over a working day.
+var DefaultSpeedProfiles = []SpeedProfile{
+	{StartHour: 0, EndHour: 7, SpeedKmh: 45},
+	{StartHour: 7, EndHour: 10, SpeedKmh: 18},
+	{StartHour: 10, EndHour: 16, SpeedKmh: 28},
+	{StartHour: 16, EndHour: 20, SpeedKmh: 15},
+	{StartHour: 20, EndHour: 24, SpeedKmh: 35},
+}
+
+const (
+	defaultDetourFactor        = 1.4 // Road distance is usually ~40% longer than straight-line distance
+	defaultSpeedKmh            = 30
+	defaultStalenessWeight     = 0.5 // ETA seconds added per second since the last location update
+	defaultMaxStalenessPenalty = 5 * time.Minute
+)
+
+// HeuristicETAProvider derives ETAs from the straight-line distance, a detour factor
+// and the speed profile for the current time of day. It needs no external service.
+// The longer ago a driver last reported their location, the less likely they are still
+// there, so the time since adds a penalty weighted by StalenessWeight and capped at
+// MaxStalenessPenalty. The penalty is what lets a recently seen driver outrank a nearer
+// one gone quiet; drivers without a known last update get none.
+type HeuristicETAProvider struct {
+	Profiles            []SpeedProfile
+	DetourFactor        float64
+	StalenessWeight     float64
+	MaxStalenessPenalty time.Duration
+	Now                 func() time.Time
+}
+
+// NewHeuristicETAProvider returns a heuristic provider using the default speed profiles.
+func NewHeuristicETAProvider() *HeuristicETAProvider {
+	return &HeuristicETAProvider{
+		Profiles:            DefaultSpeedProfiles,
+		DetourFactor:        defaultDetourFactor,
+		StalenessWeight:     defaultStalenessWeight,
+		MaxStalenessPenalty: defaultMaxStalenessPenalty,
+		Now:                 time.Now,
+	}
+}
+
+func (p *HeuristicETAProvider) EstimateETAs(ctx context.Context, pickup models.Coordinates, drivers []models.DriverWithDistance) ([]time.Duration, error) {
+	now := p.Now()
+	speed := p.speedAt(now)
+	metersPerSecond := speed * 1000 / 3600
+
+	etas := make([]time.Duration, len(drivers))
+	for i, driver := range drivers {
+		seconds := driver.Distance * p.DetourFactor / metersPerSecond
+		etas[i] = time.Duration(seconds*float64(time.Second)) + p.stalenessPenalty(driver.LastSeen, now)
+	}
+
+	return etas, nil
+}
+
+// stalenessPenalty returns the time added to the ETA of a driver last seen at the given time
+func (p *HeuristicETAProvider) stalenessPenalty(lastSeen *time.Time, now time.Time) time.Duration {
+	if lastSeen == nil || !lastSeen.Before(now) {
+		return 0
+	}
+	penalty := time.Duration(float64(now.Sub(*lastSeen)) * p.StalenessWeight)
+	return min(penalty, p.MaxStalenessPenalty)
+}
+
+// speedAt returns the profile speed for the hour of t, falling back to a default speed.
+func (p *HeuristicETAProvider) speedAt(t time.Time) float64 {
+	hour := t.Hour()
+	for _, profile := range p.Profiles {
+		if hour >= profile.StartHour && hour < profile.EndHour && profile.SpeedKmh > 0 {
+			return profile.SpeedKmh
+		}
+	}
+	return defaultSpeedKmh
+}
+
+// rankByETA orders drivers by their estimated travel time and records it on each driver.
+// Drivers the provider cannot route to (negative ETA) are moved to the end without an ETA.
+func rankByETA(ctx context.Context, provider ETAProvider, pickup models.Coordinates, drivers []models.DriverWithDistance) ([]models.DriverWithDistance, error) {
+	etas, err := provider.EstimateETAs(ctx, pickup, drivers)
+	if err != nil {
+		return nil, err
+	}
+	if len(etas) != len(drivers) {
+		return nil, fmt.Errorf("eta provider returned %d estimates for %d drivers", len(etas), len(drivers))
+	}
+
+	order := make([]int, len(drivers))
+	for i := range order {
+		order[i] = i
+		if etas[i] < 0 {
+			etas[i] = math.MaxInt64
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return etas[order[a]] < etas[order[b]]
+	})
+
+	ranked := make([]models.DriverWithDistance, 0, len(drivers))
+	for _, i := range order {
+		driver := drivers[i]
+		if etas[i] != math.MaxInt64 {
+			driver.ETASeconds = etas[i].Seconds()
+		}
+		ranked = append(ranked, driver)
+	}
+
+	return ranked, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+)
+
+// StubETAProvider returns predefined estimates
+type StubETAProvider struct {
+	etas []time.Duration
+	err  error
+}
+
+func (s *StubETAProvider) EstimateETAs(ctx context.Context, pickup models.Coordinates, drivers []models.DriverWithDistance) ([]time.Duration, error) {
+	return s.etas, s.err
+}
+
+func testDrivers() []models.DriverWithDistance {
+	return []models.DriverWithDistance{
+		{ID: primitive.NewObjectID(), Location: models.Location{Type: "Point", Coordinates: []float64{29.01, 41.01}}, Distance: 1000},
+		{ID: primitive.NewObjectID(), Location: models.Location{Type: "Point", Coordinates: []float64{29.02, 41.02}}, Distance: 2000},
+		{ID: primitive.NewObjectID(), Location: models.Location{Type: "Point", Coordinates: []float64{29.03, 41.03}}, Distance: 3000},
+	}
+}
+
+func TestHeuristicETAProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		hour     int
+		distance float64
+		expected time.Duration
+	}{
+		{name: "Night", hour: 3, distance: 1000, expected: 112 * time.Second},        // 1400m at 45 km/h
+		{name: "Morning Rush", hour: 8, distance: 1000, expected: 280 * time.Second}, // 1400m at 18 km/h
+		{name: "Evening Rush", hour: 17, distance: 500, expected: 168 * time.Second}, // 700m at 15 km/h
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewHeuristicETAProvider()
+			provider.Now = func() time.Time {
+				return time.Date(2024, 1, 1, tt.hour, 30, 0, 0, time.UTC)
+			}
+
+			etas, err := provider.EstimateETAs(context.Background(), models.Coordinates{}, []models.DriverWithDistance{{Distance: tt.distance}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if etas[0].Round(time.Second) != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, etas[0])
+			}
+		})
+	}
+}
+
+func TestHeuristicETAProvider_Staleness(t *testing.T) {
+	now := time.Date(2024, 1, 1, 3, 30, 0, 0, time.UTC)
+	provider := NewHeuristicETAProvider()
+	provider.Now = func() time.Time { return now }
+
+	seenAt := func(ago time.Duration) *time.Time {
+		at := now.Add(-ago)
+		return &at
+	}
+	drivers := []models.DriverWithDistance{
+		{Distance: 500, LastSeen: seenAt(10 * time.Minute)}, // Gone quiet, penalised by at most 5 minutes
+		{Distance: 1000, LastSeen: seenAt(10 * time.Second)},
+		{Distance: 1000, LastSeen: seenAt(-time.Second)}, // Clocks may disagree
+		{Distance: 1000},
+	}
+
+	etas, err := provider.EstimateETAs(context.Background(), models.Coordinates{}, drivers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []time.Duration{356 * time.Second, 117 * time.Second, 112 * time.Second, 112 * time.Second}
+	for i := range expected {
+		if etas[i].Round(time.Second) != expected[i] {
+			t.Errorf("driver %d: expected %v, got %v", i, expected[i], etas[i])
+		}
+	}
+
+	ranked, err := rankByETA(context.Background(), provider, models.Coordinates{}, drivers[:2])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ranked[0].Distance != 1000 {
+		t.Errorf("expected the recently seen driver to outrank the nearer one gone quiet, got %+v", ranked)
+	}
+}
+
+func TestOSRMETAProvider(t *testing.T) {
+	drivers := testDrivers()
+
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		expected    []time.Duration
+		expectedErr bool
+	}{
+		{
+			name:     "Successful Table",
+			status:   http.StatusOK,
+			body:     `{"code":"Ok","durations":[[600.5],[120],[null]]}`,
+			expected: []time.Duration{600500 * time.Millisecond, 120 * time.Second, -1},
+		},
+		{
+			name:        "Routing Error",
+			status:      http.StatusBadRequest,
+			body:        `{"code":"InvalidQuery","message":"Query string malformed"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "Row Count Mismatch",
+			status:      http.StatusOK,
+			body:        `{"code":"Ok","durations":[[600]]}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestedPath, requestedQuery string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestedPath = r.URL.Path
+				requestedQuery = r.URL.RawQuery
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewOSRMETAProvider(server.URL+"/", time.Second)
+			etas, err := provider.EstimateETAs(context.Background(), models.Coordinates{Latitude: 41, Longitude: 29}, drivers)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("expected error: %v, got: %v", tt.expectedErr, err)
+			}
+
+			expectedPath := "/table/v1/driving/29.000000,41.000000;29.010000,41.010000;29.020000,41.020000;29.030000,41.030000"
+			if requestedPath != expectedPath {
+				t.Errorf("expected path %q, got %q", expectedPath, requestedPath)
+			}
+			if !strings.Contains(requestedQuery, "sources=1;2;3") || !strings.Contains(requestedQuery, "destinations=0") {
+				t.Errorf("unexpected query %q", requestedQuery)
+			}
+
+			for i := range tt.expected {
+				if etas[i] != tt.expected[i] {
+					t.Errorf("driver %d: expected %v, got %v", i, tt.expected[i], etas[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFindNearestDriver_ETARanking(t *testing.T) {
+	drivers := testDrivers()
+
+	tests := []struct {
+		name       string
+		provider   *StubETAProvider
+		expectedID primitive.ObjectID
+		expectedTT float64
+	}{
+		{
+			name:       "Farther Driver Arrives First",
+			provider:   &StubETAProvider{etas: []time.Duration{40 * time.Minute, 5 * time.Minute, 10 * time.Minute}},
+			expectedID: drivers[1].ID,
+			expectedTT: 300,
+		},
+		{
+			name:       "Unreachable Drivers Ranked Last",
+			provider:   &StubETAProvider{etas: []time.Duration{-1, -1, 10 * time.Minute}},
+			expectedID: drivers[2].ID,
+			expectedTT: 600,
+		},
+		{
+			name:       "Provider Failure Falls Back To Distance",
+			provider:   &StubETAProvider{err: errors.New("routing server unavailable")},
+			expectedID: drivers[0].ID,
+			expectedTT: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockDriverRepository{}
+			mockRepo.On("FindNearestDrivers", mock.Anything, 41.0, 29.0, 5000, 3).Return(drivers, nil).Once()
+
+			service := NewDriverService(mockRepo, WithETAProvider(tt.provider, 3))
+			driver, err := service.FindNearestDriver(context.Background(), 41.0, 29.0, 5000)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if driver.ID != tt.expectedID {
+				t.Errorf("expected driver %s, got %s", tt.expectedID.Hex(), driver.ID.Hex())
+			}
+			if driver.ETASeconds != tt.expectedTT {
+				t.Errorf("expected ETA %v, got %v", tt.expectedTT, driver.ETASeconds)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// HeatmapRepository aggregates driver locations into grid cells
+type HeatmapRepository interface {
+	AggregateDriverCells(ctx context.Context, bounds geo.Bounds, precision int) ([]models.HeatmapCell, error)
+}
+
+// HeatmapService reports where drivers are concentrated
+type HeatmapService struct {
+	repo HeatmapRepository
+}
+
+func NewHeatmapService(repo HeatmapRepository) HeatmapService {
+	return HeatmapService{repo: repo}
+}
+
+// DriverHeatmap counts drivers per geohash cell of the given precision within the bounding box,
+// busiest cells first
+func (s *HeatmapService) DriverHeatmap(ctx context.Context, bounds geo.Bounds, precision int) (*models.Heatmap, error) {
+	cells, err := s.repo.AggregateDriverCells(ctx, bounds, precision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate driver locations: %w", err)
+	}
+
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Count != cells[j].Count {
+			return cells[i].Count > cells[j].Count
+		}
+		return cells[i].Geohash < cells[j].Geohash
+	})
+
+	heatmap := &models.Heatmap{Precision: precision, Bounds: bounds, Cells: cells}
+	for _, cell := range cells {
+		heatmap.Total += cell.Count
+	}
+
+	return heatmap, nil
+}
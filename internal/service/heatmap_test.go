@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+func TestDriverHeatmap(t *testing.T) {
+	bounds := geo.Bounds{MinLat: 40.8, MinLon: 28.6, MaxLat: 41.3, MaxLon: 29.4}
+
+	tests := []struct {
+		name          string
+		mockCells     []models.HeatmapCell
+		mockError     error
+		expectedOrder []string
+		expectedTotal int
+		expectedErr   bool
+	}{
+		{
+			name: "Busiest Cells First",
+			mockCells: []models.HeatmapCell{
+				{Geohash: "sxk9", Count: 2, Available: 1},
+				{Geohash: "sxkd", Count: 7, Available: 7},
+				{Geohash: "sxk3", Count: 2, Available: 2},
+			},
+			expectedOrder: []string{"sxkd", "sxk3", "sxk9"},
+			expectedTotal: 11,
+		},
+		{
+			name:          "No Drivers",
+			mockCells:     []models.HeatmapCell{},
+			expectedOrder: []string{},
+		},
+		{
+			name:        "Aggregation Fails",
+			mockError:   errors.New("aggregation failed"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockDriverRepository{}
+			mockRepo.On("AggregateDriverCells", mock.Anything, bounds, 4).Return(tt.mockCells, tt.mockError).Once()
+			service := NewHeatmapService(mockRepo)
+
+			heatmap, err := service.DriverHeatmap(context.Background(), bounds, 4)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("expected error: %v, got: %v", tt.expectedErr, err)
+			}
+			mockRepo.AssertExpectations(t)
+			if tt.expectedErr {
+				return
+			}
+
+			if heatmap.Total != tt.expectedTotal {
+				t.Errorf("expected total %d, got %d", tt.expectedTotal, heatmap.Total)
+			}
+			for i, hash := range tt.expectedOrder {
+				if heatmap.Cells[i].Geohash != hash {
+					t.Errorf("cell %d: expected %s, got %s", i, hash, heatmap.Cells[i].Geohash)
+				}
+			}
+		})
+	}
+}
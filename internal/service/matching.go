@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"bitaksi-go-driver/internal/matching"
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+const matchingConcurrency = 8 // Parallel candidate lookups per batch
+
+// ErrDuplicatePickup is returned when two pickups of a batch have the same ID, so the pairs could not tell them apart
+var ErrDuplicatePickup = errors.New("duplicate pickup ID")
+
+// MatchingService assigns batches of pickups to drivers so that the total pickup
+// distance is minimal, instead of greedily giving every rider their nearest driver.
+type MatchingService struct {
+	repo       DriverRepository
+	candidates int
+}
+
+// NewMatchingService creates a matching service that considers the given number of nearest drivers per pickup
+func NewMatchingService(repo DriverRepository, candidates int) MatchingService {
+	return MatchingService{repo: repo, candidates: candidates}
+}
+
+// MatchRiders pairs pickups with available drivers within maxDistance meters. Pickups without
+// an ID are identified by their position in the batch, which must not be the ID of another
+// pickup. Drivers are not reserved.
+func (s *MatchingService) MatchRiders(ctx context.Context, pickups []models.Pickup, maxDistance int) (*models.MatchResult, error) {
+	ids := make(map[string]struct{}, len(pickups))
+	for i := range pickups {
+		if pickups[i].ID == "" {
+			pickups[i].ID = strconv.Itoa(i)
+		}
+		if _, ok := ids[pickups[i].ID]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicatePickup, pickups[i].ID)
+		}
+		ids[pickups[i].ID] = struct{}{}
+	}
+
+	candidates, err := s.findCandidates(ctx, pickups, maxDistance)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the pickup x driver cost matrix over every driver that is a candidate for some pickup
+	var drivers []models.DriverWithDistance
+	columns := make(map[string]int)
+	for _, list := range candidates {
+		for _, driver := range list {
+			if _, ok := columns[driver.ID.Hex()]; !ok {
+				columns[driver.ID.Hex()] = len(drivers)
+				drivers = append(drivers, driver)
+			}
+		}
+	}
+
+	cost := make([][]float64, len(pickups))
+	for i, list := range candidates {
+		cost[i] = make([]float64, len(drivers))
+		for j := range cost[i] {
+			cost[i][j] = math.Inf(1)
+		}
+		for _, driver := range list {
+			cost[i][columns[driver.ID.Hex()]] = driver.Distance
+		}
+	}
+
+	result := &models.MatchResult{Pairs: []models.MatchPair{}, Unmatched: []string{}}
+	for i, j := range matching.Assign(cost) {
+		if j < 0 {
+			result.Unmatched = append(result.Unmatched, pickups[i].ID)
+			continue
+		}
+
+		driver := drivers[j]
+		driver.Distance = cost[i][j]
+		result.Pairs = append(result.Pairs, models.MatchPair{PickupID: pickups[i].ID, Driver: driver})
+		result.TotalDistance += driver.Distance
+	}
+
+	return result, nil
+}
+
+// findCandidates looks up the nearest available drivers of every pickup concurrently
+func (s *MatchingService) findCandidates(ctx context.Context, pickups []models.Pickup, maxDistance int) ([][]models.DriverWithDistance, error) {
+	candidates := make([][]models.DriverWithDistance, len(pickups))
+	errs := make([]error, len(pickups))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, matchingConcurrency)
+	for i, pickup := range pickups {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, pickup models.Pickup) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			drivers, err := s.repo.FindNearestDrivers(ctx, pickup.Latitude, pickup.Longitude, maxDistance, s.candidates)
+			if err != nil && !errors.Is(err, repository.ErrDriverNotFound) {
+				errs[i] = fmt.Errorf("failed to find candidates for pickup %s: %w", pickup.ID, err)
+				return
+			}
+			candidates[i] = drivers
+		}(i, pickup)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+func TestMatchRiders(t *testing.T) {
+	drivers := testDrivers()
+	withDistance := func(driver models.DriverWithDistance, distance float64) models.DriverWithDistance {
+		driver.Distance = distance
+		return driver
+	}
+
+	mockRepo := &MockDriverRepository{}
+	// Greedy nearest-first would give pickup "a" driver 0 and leave pickup "b" without a driver
+	mockRepo.On("FindNearestDrivers", mock.Anything, 41.0, 29.0, 3000, 5).
+		Return([]models.DriverWithDistance{withDistance(drivers[0], 100), withDistance(drivers[1], 200)}, nil).Once()
+	mockRepo.On("FindNearestDrivers", mock.Anything, 41.1, 29.1, 3000, 5).
+		Return([]models.DriverWithDistance{withDistance(drivers[0], 300)}, nil).Once()
+	mockRepo.On("FindNearestDrivers", mock.Anything, 42.0, 30.0, 3000, 5).
+		Return(nil, repository.ErrDriverNotFound).Once()
+
+	service := NewMatchingService(mockRepo, 5)
+	result, err := service.MatchRiders(context.Background(), []models.Pickup{
+		{ID: "a", Latitude: 41.0, Longitude: 29.0},
+		{ID: "b", Latitude: 41.1, Longitude: 29.1},
+		{Latitude: 42.0, Longitude: 30.0},
+	}, 3000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]models.DriverWithDistance{
+		"a": withDistance(drivers[1], 200),
+		"b": withDistance(drivers[0], 300),
+	}
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("expected %d pairs, got %+v", len(expected), result.Pairs)
+	}
+	for _, pair := range result.Pairs {
+		if pair.Driver.ID != expected[pair.PickupID].ID || pair.Driver.Distance != expected[pair.PickupID].Distance {
+			t.Errorf("pickup %s: expected driver %s at %v, got %s at %v", pair.PickupID,
+				expected[pair.PickupID].ID.Hex(), expected[pair.PickupID].Distance, pair.Driver.ID.Hex(), pair.Driver.Distance)
+		}
+	}
+	if len(result.Unmatched) != 1 || result.Unmatched[0] != "2" {
+		t.Errorf("expected pickup 2 to be unmatched, got %v", result.Unmatched)
+	}
+	if result.TotalDistance != 500 {
+		t.Errorf("expected total distance 500, got %v", result.TotalDistance)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMatchRiders_DuplicatePickups(t *testing.T) {
+	service := NewMatchingService(&MockDriverRepository{}, 5)
+	batches := [][]models.Pickup{
+		{{ID: "a", Latitude: 41.0, Longitude: 29.0}, {ID: "a", Latitude: 41.1, Longitude: 29.1}},
+		// The second pickup is identified by its position, which the first uses as its ID
+		{{ID: "1", Latitude: 41.0, Longitude: 29.0}, {Latitude: 41.1, Longitude: 29.1}},
+	}
+
+	for _, pickups := range batches {
+		if _, err := service.MatchRiders(context.Background(), pickups, 3000); !errors.Is(err, ErrDuplicatePickup) {
+			t.Errorf("expected ErrDuplicatePickup for %+v, got %v", pickups, err)
+		}
+	}
+}
+
+func TestMatchRiders_RepositoryError(t *testing.T) {
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("FindNearestDrivers", mock.Anything, 41.0, 29.0, 3000, 5).Return(nil, errors.New("connection reset")).Once()
+
+	service := NewMatchingService(mockRepo, 5)
+	if _, err := service.MatchRiders(context.Background(), []models.Pickup{{ID: "a", Latitude: 41.0, Longitude: 29.0}}, 3000); err == nil {
+		t.Error("expected an error but got nil")
+	}
+	mockRepo.AssertExpectations(t)
+}
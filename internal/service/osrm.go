@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitaksi-go-driver/internal/models"
+)
+
+// OSRMETAProvider asks an OSRM-compatible routing server for road travel times
+// using its table service (https://project-osrm.org/docs/v5.24.0/api/#table-service).
+type OSRMETAProvider struct {
+	baseURL string
+	profile string
+	client  *http.Client
+}
+
+type osrmTableResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Durations [][]*float64 `json:"durations"`
+}
+
+// NewOSRMETAProvider creates a provider for the routing server at baseURL using the driving profile.
+func NewOSRMETAProvider(baseURL string, timeout time.Duration) *OSRMETAProvider {
+	return &OSRMETAProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		profile: "driving",
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *OSRMETAProvider) EstimateETAs(ctx context.Context, pickup models.Coordinates, drivers []models.DriverWithDistance) ([]time.Duration, error) {
+	if len(drivers) == 0 {
+		return nil, nil
+	}
+
+	// The pickup point is the only destination (index 0), every driver is a source
+	coordinates := []string{fmt.Sprintf("%f,%f", pickup.Longitude, pickup.Latitude)}
+	sources := make([]string, 0, len(drivers))
+	for i, driver := range drivers {
+		if len(driver.Location.Coordinates) != 2 {
+			return nil, fmt.Errorf("driver %s has invalid coordinates", driver.ID.Hex())
+		}
+		coordinates = append(coordinates, fmt.Sprintf("%f,%f", driver.Location.Coordinates[0], driver.Location.Coordinates[1]))
+		sources = append(sources, fmt.Sprint(i+1))
+	}
+
+	url := fmt.Sprintf("%s/table/v1/%s/%s?sources=%s&destinations=0&annotations=duration",
+		p.baseURL, p.profile, strings.Join(coordinates, ";"), strings.Join(sources, ";"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create routing request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call routing server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var table osrmTableResponse
+	if err := json.NewDecoder(resp.Body).Decode(&table); err != nil {
+		return nil, fmt.Errorf("failed to decode routing response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || table.Code != "Ok" {
+		return nil, fmt.Errorf("routing server returned %d %s: %s", resp.StatusCode, table.Code, table.Message)
+	}
+	if len(table.Durations) != len(drivers) {
+		return nil, fmt.Errorf("routing server returned %d rows for %d drivers", len(table.Durations), len(drivers))
+	}
+
+	etas := make([]time.Duration, len(drivers))
+	for i, row := range table.Durations {
+		// A null duration means the pickup point is unreachable from this driver
+		if len(row) == 0 || row[0] == nil {
+			etas[i] = -1
+			continue
+		}
+		etas[i] = time.Duration(*row[0] * float64(time.Second))
+	}
+
+	return etas, nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+)
+
+// ReservationRepository atomically reserves and releases drivers. A reservation is made under a
+// reservation ID, and only confirmed or released when the same ID is given.
+type ReservationRepository interface {
+	ReserveDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error
+	ClaimNearestDriver(ctx context.Context, latitude, longitude float64, radius int, ttl time.Duration, reservationID string) (*models.DriverWithDistance, error)
+	ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) error
+	ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error
+}
+
+// ReservationService claims the nearest available driver for a rider. A claim expires after
+// the reservation TTL unless it is confirmed, after which the driver is available again. The
+// claimed driver carries the ID of the reservation, which only its holder knows and which is
+// needed to confirm or release it.
+type ReservationService struct {
+	repo ReservationRepository
+	ttl  time.Duration
+}
+
+func NewReservationService(repo ReservationRepository, ttl time.Duration) ReservationService {
+	return ReservationService{repo: repo, ttl: ttl}
+}
+
+func (s *ReservationService) ClaimNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (*models.DriverWithDistance, error) {
+	driver, err := s.repo.ClaimNearestDriver(ctx, latitude, longitude, radius, s.ttl, newReservationID())
+	if err != nil {
+		return nil, wrapFindError(err, radius)
+	}
+
+	return driver, nil
+}
+
+func (s *ReservationService) ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	if err := s.repo.ConfirmReservation(ctx, id, reservationID); err != nil {
+		return fmt.Errorf("failed to confirm reservation of driver %s: %w", id.Hex(), err)
+	}
+	return nil
+}
+
+func (s *ReservationService) ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	if err := s.repo.ReleaseDriver(ctx, id, reservationID); err != nil {
+		return fmt.Errorf("failed to release driver %s: %w", id.Hex(), err)
+	}
+	return nil
+}
+
+// newReservationID returns a random, unguessable reservation ID
+func newReservationID() string {
+	return uuid.NewString()
+}
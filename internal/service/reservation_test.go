@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+func TestClaimNearestDriver(t *testing.T) {
+	reservedUntil := time.Now().Add(30 * time.Second)
+
+	tests := []struct {
+		name        string
+		mockDriver  *models.DriverWithDistance
+		mockError   error
+		expectedErr error
+	}{
+		{
+			name:       "Successful Claim",
+			mockDriver: &models.DriverWithDistance{ID: primitive.NewObjectID(), Status: models.DriverStatusReserved, ReservedUntil: &reservedUntil},
+		},
+		{
+			name:        "No Drivers Found",
+			mockError:   repository.ErrDriverNotFound,
+			expectedErr: repository.ErrDriverNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockDriverRepository{}
+			mockRepo.On("ClaimNearestDriver", mock.Anything, 41.0, 29.0, 5000, 30*time.Second, mock.AnythingOfType("string")).Return(tt.mockDriver, tt.mockError).Once()
+			service := NewReservationService(mockRepo, 30*time.Second)
+
+			driver, err := service.ClaimNearestDriver(context.Background(), 41.0, 29.0, 5000)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got: %v", tt.expectedErr, err)
+			}
+			if driver != tt.mockDriver {
+				t.Errorf("expected driver %v, got %v", tt.mockDriver, driver)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConfirmAndReleaseReservation(t *testing.T) {
+	id := primitive.NewObjectID()
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("ConfirmReservation", mock.Anything, id, "r1").Return(repository.ErrReservationNotFound).Once()
+	mockRepo.On("ReleaseDriver", mock.Anything, id, "r1").Return(nil).Once()
+	service := NewReservationService(mockRepo, 30*time.Second)
+
+	if err := service.ConfirmReservation(context.Background(), id, "r1"); !errors.Is(err, repository.ErrReservationNotFound) {
+		t.Errorf("expected ErrReservationNotFound, got: %v", err)
+	}
+	if err := service.ReleaseDriver(context.Background(), id, "r1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	mockRepo.AssertExpectations(t)
+}
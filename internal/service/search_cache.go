@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// SearchCacheStats counts the lookups and invalidations of a SearchCache
+type SearchCacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Invalidations uint64 `json:"invalidations"`
+	Entries       int    `json:"entries"`
+}
+
+// searchKey identifies searches answered alike: searches from the same geohash cell with the same radius
+type searchKey struct {
+	cell   string
+	radius int
+}
+
+type searchEntry struct {
+	driver  *models.DriverWithDistance // nil if no driver was found
+	expires time.Time
+}
+
+// SearchCache briefly remembers nearest-driver results, so that riders searching from within a
+// few meters of each other at the same time share one repository lookup. Results are dropped
+// after the TTL, or earlier when the found driver moves or is reserved, a driver moves within
+// the radius of the search cell, or a driver is released while the search found none. Only moves
+// made through the same DriverService and reservations made through Reservations are seen, the
+// TTL bounds staleness otherwise.
+type SearchCache struct {
+	ttl        time.Duration
+	precision  int
+	maxEntries int
+	now        func() time.Time
+
+	mu        sync.Mutex
+	entries   map[searchKey]searchEntry
+	byDriver  map[primitive.ObjectID]map[searchKey]struct{}
+	byCell    map[string]map[searchKey]struct{}
+	notFound  map[searchKey]struct{} // searches that found no driver
+	maxRadius int                    // the largest radius cached so far
+	stats     SearchCacheStats
+}
+
+// NewSearchCache creates a cache of at most maxEntries results, quantising searches to geohash
+// cells of the given precision
+func NewSearchCache(ttl time.Duration, precision, maxEntries int) *SearchCache {
+	return &SearchCache{
+		ttl:        ttl,
+		precision:  precision,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[searchKey]searchEntry),
+		byDriver:   make(map[primitive.ObjectID]map[searchKey]struct{}),
+		byCell:     make(map[string]map[searchKey]struct{}),
+		notFound:   make(map[searchKey]struct{}),
+	}
+}
+
+// Get returns the cached result of the search. The driver is nil if the search found no driver.
+func (c *SearchCache) Get(latitude, longitude float64, radius int) (driver *models.DriverWithDistance, ok bool) {
+	key := c.key(latitude, longitude, radius)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expires) {
+		c.stats.Misses++
+		return nil, false
+	}
+	if entry.driver == nil {
+		c.stats.Hits++
+		return nil, true
+	}
+
+	// The result was computed for another point of the cell, so the distance is the caller's own. Near the
+	// edge of the radius the driver may be out of the caller's range, who must then search for themselves.
+	found := *entry.driver
+	found.Distance = geo.Distance(latitude, longitude, found.Location.Coordinates[1], found.Location.Coordinates[0])
+	if found.Distance > float64(radius) {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	return &found, true
+}
+
+// Put caches the result of the search, a nil driver meaning no driver was found
+func (c *SearchCache) Put(latitude, longitude float64, radius int, driver *models.DriverWithDistance) {
+	key := c.key(latitude, longitude, radius)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.removeExpired()
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+
+	c.remove(key)
+	entry := searchEntry{expires: c.now().Add(c.ttl)}
+	if driver != nil {
+		cached := *driver
+		entry.driver = &cached
+		addToIndex(c.byDriver, driver.ID, key)
+	} else {
+		c.notFound[key] = struct{}{}
+	}
+	c.entries[key] = entry
+	addToIndex(c.byCell, key.cell, key)
+	c.maxRadius = max(c.maxRadius, radius)
+}
+
+// DriverMoved drops the results that found the driver and those searched from cells within their
+// radius of where the driver moved to, for which the driver may now be the nearest
+func (c *SearchCache) DriverMoved(id primitive.ObjectID, latitude, longitude float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.byDriver[id] {
+		c.remove(key)
+		c.stats.Invalidations++
+	}
+	c.forEachCellWithin(latitude, longitude, float64(c.maxRadius), func(cell string, keys map[searchKey]struct{}) {
+		bounds, err := geo.DecodeGeohash(cell)
+		if err != nil {
+			return
+		}
+
+		// Every point of the cell is at most the distance to its center plus the distance from the
+		// center to its farthest corner away, whose southern and northern corners differ in width
+		centerLat, centerLon := bounds.Center()
+		halfDiagonal := math.Max(
+			geo.Distance(centerLat, centerLon, bounds.MinLat, bounds.MinLon),
+			geo.Distance(centerLat, centerLon, bounds.MaxLat, bounds.MinLon),
+		)
+		distance := geo.Distance(latitude, longitude, centerLat, centerLon) - halfDiagonal
+
+		for key := range keys {
+			if distance <= float64(key.radius) {
+				c.remove(key)
+				c.stats.Invalidations++
+			}
+		}
+	})
+}
+
+// DriverReleased drops the results that found no driver, as the released driver may now be found.
+// Results that found another driver stay until the TTL, as the location of the released driver is
+// unknown here.
+func (c *SearchCache) DriverReleased() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.notFound {
+		c.remove(key)
+		c.stats.Invalidations++
+	}
+}
+
+// DriverReserved drops the results that found the driver, as it can no longer be offered
+func (c *SearchCache) DriverReserved(id primitive.ObjectID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.byDriver[id] {
+		c.remove(key)
+		c.stats.Invalidations++
+	}
+}
+
+// Reservations returns the repository with the drivers reserved through it dropped from the cache
+func (c *SearchCache) Reservations(repo ReservationRepository) ReservationRepository {
+	return cachedReservations{ReservationRepository: repo, cache: c}
+}
+
+// cachedReservations tells a SearchCache about the drivers reserved through a repository
+type cachedReservations struct {
+	ReservationRepository
+	cache *SearchCache
+}
+
+func (r cachedReservations) ReserveDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	if err := r.ReservationRepository.ReserveDriver(ctx, id, reservationID); err != nil {
+		return err
+	}
+	r.cache.DriverReserved(id)
+	return nil
+}
+
+func (r cachedReservations) ClaimNearestDriver(ctx context.Context, latitude, longitude float64, radius int, ttl time.Duration, reservationID string) (*models.DriverWithDistance, error) {
+	driver, err := r.ReservationRepository.ClaimNearestDriver(ctx, latitude, longitude, radius, ttl, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.DriverReserved(driver.ID)
+	return driver, nil
+}
+
+func (r cachedReservations) ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) error {
+	if err := r.ReservationRepository.ReleaseDriver(ctx, id, reservationID); err != nil {
+		return err
+	}
+	r.cache.DriverReleased()
+	return nil
+}
+
+// Stats returns the lookup counts since the cache was created
+func (c *SearchCache) Stats() SearchCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+func (c *SearchCache) key(latitude, longitude float64, radius int) searchKey {
+	return searchKey{cell: geo.EncodeGeohash(latitude, longitude, c.precision), radius: radius}
+}
+
+func (c *SearchCache) removeExpired() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			c.remove(key)
+		}
+	}
+}
+
+// remove drops the entry and its index references. The caller must hold c.mu.
+func (c *SearchCache) remove(key searchKey) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+
+	delete(c.entries, key)
+	removeFromIndex(c.byCell, key.cell, key)
+	if entry.driver != nil {
+		removeFromIndex(c.byDriver, entry.driver.ID, key)
+	} else {
+		delete(c.notFound, key)
+	}
+}
+
+// forEachCellWithin calls visit for every cached cell that may hold points within radius meters of
+// the given point, with the keys cached for it. When the circle covers more cells than are cached,
+// the cached cells are checked against the covered ranges instead of enumerating the ranges. The
+// caller must hold c.mu, visit may remove keys.
+func (c *SearchCache) forEachCellWithin(latitude, longitude, radius float64, visit func(cell string, keys map[searchKey]struct{})) {
+	_, lonWidth := geo.GeohashCellSize(c.precision)
+	_, maxColumn := geo.GeohashCellIndex(90, 180, c.precision)
+	columns := maxColumn + 1
+
+	// Latitude span of the circle, reaching over a pole covers every longitude. The spans are
+	// widened by a tiny margin so rounding never excludes a cell touching the circle.
+	const margin = 1e-9
+	angular := radius/geo.EarthRadius*180/math.Pi + margin
+	minLat, maxLat := latitude-angular, latitude+angular
+	allLongitudes := minLat <= -90 || maxLat >= 90
+
+	var lonSpan float64
+	if !allLongitudes {
+		ratio := math.Sin(radius/geo.EarthRadius) / math.Cos(latitude*math.Pi/180)
+		if ratio >= 1 {
+			allLongitudes = true
+		} else {
+			lonSpan = math.Asin(ratio)*180/math.Pi + margin
+		}
+	}
+
+	minRow, _ := geo.GeohashCellIndex(math.Max(minLat, -90), 0, c.precision)
+	maxRow, _ := geo.GeohashCellIndex(math.Min(maxLat, 90), 0, c.precision)
+
+	var firstColumn, columnCount int64
+	if allLongitudes {
+		columnCount = columns
+	} else {
+		firstColumn = int64(math.Floor((longitude - lonSpan + 180) / lonWidth))
+		lastColumn := int64(math.Floor((longitude + lonSpan + 180) / lonWidth))
+		columnCount = min(lastColumn-firstColumn+1, columns)
+	}
+	firstColumn = (firstColumn%columns + columns) % columns
+
+	if (maxRow-minRow+1)*columnCount > int64(len(c.byCell)) {
+		for cell, keys := range c.byCell {
+			bounds, err := geo.DecodeGeohash(cell)
+			if err != nil {
+				continue
+			}
+			centerLat, centerLon := bounds.Center()
+			row, column := geo.GeohashCellIndex(centerLat, centerLon, c.precision)
+			offset := ((column-firstColumn)%columns + columns) % columns
+			if row >= minRow && row <= maxRow && offset < columnCount {
+				visit(cell, keys)
+			}
+		}
+		return
+	}
+
+	for row := minRow; row <= maxRow; row++ {
+		for offset := int64(0); offset < columnCount; offset++ {
+			cell := geo.GeohashFromCellIndex(row, (firstColumn+offset)%columns, c.precision)
+			if keys, ok := c.byCell[cell]; ok {
+				visit(cell, keys)
+			}
+		}
+	}
+}
+
+func addToIndex[K comparable](index map[K]map[searchKey]struct{}, k K, key searchKey) {
+	keys, ok := index[k]
+	if !ok {
+		keys = make(map[searchKey]struct{})
+		index[k] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+func removeFromIndex[K comparable](index map[K]map[searchKey]struct{}, k K, key searchKey) {
+	delete(index[k], key)
+	if len(index[k]) == 0 {
+		delete(index, k)
+	}
+}
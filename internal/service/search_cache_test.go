@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+func newTestSearchCache(now *time.Time) *SearchCache {
+	cache := NewSearchCache(500*time.Millisecond, 8, 100)
+	cache.now = func() time.Time { return *now }
+	return cache
+}
+
+func TestSearchCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestSearchCache(&now)
+	driver := &models.DriverWithDistance{
+		ID:       primitive.NewObjectID(),
+		Location: models.Location{Type: "Point", Coordinates: []float64{29.001, 41.001}},
+	}
+
+	if _, ok := cache.Get(41, 29, 1000); ok {
+		t.Fatalf("expected a miss on an empty cache")
+	}
+	cache.Put(41, 29, 1000, driver)
+
+	// A search a few meters away shares the result, with its own distance
+	cached, ok := cache.Get(41.00001, 29.00001, 1000)
+	if !ok || cached.ID != driver.ID {
+		t.Fatalf("expected a hit, got %+v, %v", cached, ok)
+	}
+	if cached.Distance < 130 || cached.Distance > 140 {
+		t.Errorf("expected the distance from the second search point, got %v", cached.Distance)
+	}
+	if _, ok := cache.Get(41, 29, 2000); ok {
+		t.Errorf("expected a miss for another radius")
+	}
+	if _, ok := cache.Get(41.01, 29, 1000); ok {
+		t.Errorf("expected a miss for another cell")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if _, ok := cache.Get(41, 29, 1000); ok {
+		t.Errorf("expected the result to expire")
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 4 || stats.Entries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSearchCache_OutOfRadius(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestSearchCache(&now)
+	// About 90m north of the search point at the north of the cell, within the 100m radius
+	driver := &models.DriverWithDistance{
+		ID:       primitive.NewObjectID(),
+		Location: models.Location{Type: "Point", Coordinates: []float64{29, 41.0009}},
+	}
+	cache.Put(41.0001, 29, 100, driver)
+
+	// A search from the south of the same cell is about 105m from the driver
+	if cached, ok := cache.Get(40.99996, 29, 100); ok {
+		t.Errorf("expected a miss for a driver out of the radius, got %+v", cached)
+	}
+	if cached, ok := cache.Get(41.0001, 29, 100); !ok || cached.ID != driver.ID {
+		t.Errorf("expected a hit within the radius, got %+v, %v", cached, ok)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSearchCache_DriverMoved(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestSearchCache(&now)
+	driver := &models.DriverWithDistance{
+		ID:       primitive.NewObjectID(),
+		Location: models.Location{Type: "Point", Coordinates: []float64{29.001, 41.001}},
+	}
+
+	cache.Put(41, 29, 1000, driver)
+	cache.Put(41.1, 29.1, 1000, nil)
+	cache.Put(41.2, 29.2, 1000, nil)
+
+	// Moving the found driver drops their results
+	cache.DriverMoved(driver.ID, 41.3, 29.3)
+	if _, ok := cache.Get(41, 29, 1000); ok {
+		t.Errorf("expected the result of the moved driver to be dropped")
+	}
+
+	// A driver moving into a cell drops its results, including searches that found no driver
+	cache.DriverMoved(primitive.NewObjectID(), 41.1, 29.1)
+	if _, ok := cache.Get(41.1, 29.1, 1000); ok {
+		t.Errorf("expected the result of the cell to be dropped")
+	}
+	if driver, ok := cache.Get(41.2, 29.2, 1000); !ok || driver != nil {
+		t.Errorf("expected the unrelated result to stay cached, got %+v, %v", driver, ok)
+	}
+
+	if stats := cache.Stats(); stats.Invalidations != 2 || stats.Entries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSearchCache_DriverMovedNearby(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestSearchCache(&now)
+
+	cache.Put(41, 29, 1000, nil)
+	cache.Put(41, 29, 100, nil)
+	cache.Put(41.02, 29, 1000, nil)
+	cache.Put(0, 179.9999, 1000, nil)
+
+	// About 500m north, within the radius of the first search only
+	cache.DriverMoved(primitive.NewObjectID(), 41.0045, 29)
+	if _, ok := cache.Get(41, 29, 1000); ok {
+		t.Errorf("expected the result of the neighbouring cell to be dropped")
+	}
+	if _, ok := cache.Get(41, 29, 100); !ok {
+		t.Errorf("expected the result with a smaller radius to stay cached")
+	}
+	if _, ok := cache.Get(41.02, 29, 1000); !ok {
+		t.Errorf("expected the result of a distant cell to stay cached")
+	}
+
+	// Across the antimeridian
+	cache.DriverMoved(primitive.NewObjectID(), 0, -179.9999)
+	if _, ok := cache.Get(0, 179.9999, 1000); ok {
+		t.Errorf("expected the result across the antimeridian to be dropped")
+	}
+
+	if stats := cache.Stats(); stats.Invalidations != 2 || stats.Entries != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSearchCache_DriverMovedScansCachedCells(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestSearchCache(&now)
+
+	// The radius covers far more cells than are cached, so the cached cells are checked instead
+	cache.Put(41, 29, 50000, nil)
+	cache.Put(42, 29, 50000, nil)
+
+	cache.DriverMoved(primitive.NewObjectID(), 41.3, 29.3)
+	if _, ok := cache.Get(41, 29, 50000); ok {
+		t.Errorf("expected the result within the radius to be dropped")
+	}
+	if _, ok := cache.Get(42, 29, 50000); !ok {
+		t.Errorf("expected the result out of the radius to stay cached")
+	}
+}
+
+func TestSearchCache_Reservations(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestSearchCache(&now)
+	claimed := &models.DriverWithDistance{
+		ID:       primitive.NewObjectID(),
+		Location: models.Location{Type: "Point", Coordinates: []float64{29.001, 41.001}},
+	}
+	accepted := &models.DriverWithDistance{
+		ID:       primitive.NewObjectID(),
+		Location: models.Location{Type: "Point", Coordinates: []float64{29.101, 41.101}},
+	}
+	cache.Put(41, 29, 1000, claimed)
+	cache.Put(41.1, 29.1, 1000, accepted)
+	cache.Put(41.2, 29.2, 1000, nil)
+
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("ClaimNearestDriver", mock.Anything, 41.0, 29.0, 1000, time.Minute, "r1").Return(claimed, nil).Once()
+	mockRepo.On("ReserveDriver", mock.Anything, accepted.ID, "r2").Return(nil).Once()
+	mockRepo.On("ReleaseDriver", mock.Anything, claimed.ID, "r1").Return(nil).Once()
+	reservations := cache.Reservations(mockRepo)
+
+	// Searches must not return drivers once they are reserved, whichever way
+	if _, err := reservations.ClaimNearestDriver(context.Background(), 41, 29, 1000, time.Minute, "r1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := cache.Get(41, 29, 1000); ok {
+		t.Errorf("expected the result of the claimed driver to be dropped")
+	}
+	if err := reservations.ReserveDriver(context.Background(), accepted.ID, "r2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := cache.Get(41.1, 29.1, 1000); ok {
+		t.Errorf("expected the result of the reserved driver to be dropped")
+	}
+	if driver, ok := cache.Get(41.2, 29.2, 1000); !ok || driver != nil {
+		t.Errorf("expected the unrelated result to stay cached, got %+v, %v", driver, ok)
+	}
+
+	// A released driver may be found by searches that found none
+	cache.Put(41.3, 29.3, 1000, &models.DriverWithDistance{
+		ID:       primitive.NewObjectID(),
+		Location: models.Location{Type: "Point", Coordinates: []float64{29.301, 41.301}},
+	})
+	if err := reservations.ReleaseDriver(context.Background(), claimed.ID, "r1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := cache.Get(41.2, 29.2, 1000); ok {
+		t.Errorf("expected the result that found no driver to be dropped")
+	}
+	if _, ok := cache.Get(41.3, 29.3, 1000); !ok {
+		t.Errorf("expected the result that found a driver to stay cached")
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSearchCache_MaxEntries(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewSearchCache(time.Second, 8, 2)
+	cache.now = func() time.Time { return now }
+
+	cache.Put(41, 29, 1000, nil)
+	cache.Put(41.1, 29, 1000, nil)
+	cache.Put(41.2, 29, 1000, nil)
+	if _, ok := cache.Get(41.2, 29, 1000); ok {
+		t.Errorf("expected the full cache to skip new results")
+	}
+
+	// Expired results make room
+	now = now.Add(time.Second)
+	cache.Put(41.2, 29, 1000, nil)
+	if _, ok := cache.Get(41.2, 29, 1000); !ok {
+		t.Errorf("expected the result to be cached once expired ones are removed")
+	}
+}
+
+func TestFindNearestDriver_SearchCache(t *testing.T) {
+	id := primitive.NewObjectID()
+	driver := &models.DriverWithDistance{
+		ID:       id,
+		Location: models.Location{Type: "Point", Coordinates: []float64{29, 41}},
+	}
+
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("FindNearestDriver", mock.Anything, 41.0, 29.0, 1000).Return(driver, nil).Twice()
+	mockRepo.On("FindNearestDriver", mock.Anything, 42.0, 29.0, 1000).Return(nil, repository.ErrDriverNotFound).Once()
+	mockRepo.On("UpdateDriverLocation", mock.Anything, id, 41.001, 29.0).Return(nil).Once()
+
+	driverService := NewDriverService(mockRepo, WithSearchCache(NewSearchCache(time.Minute, 8, 100)))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := driverService.FindNearestDriver(ctx, 41, 29, 1000); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := driverService.FindNearestDriver(ctx, 42, 29, 1000); !errors.Is(err, repository.ErrDriverNotFound) {
+			t.Fatalf("expected ErrDriverNotFound, got %v", err)
+		}
+	}
+
+	// The move invalidates the cached result, so the next search reaches the repository again
+	if err := driverService.UpdateDriverLocation(ctx, id, 41.001, 29); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := driverService.FindNearestDriver(ctx, 41, 29, 1000); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	mockRepo.AssertExpectations(t)
+}
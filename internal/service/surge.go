@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// SurgeCurvePoint maps a demand per available driver ratio to a price multiplier
+type SurgeCurvePoint struct {
+	Ratio      float64
+	Multiplier float64
+}
+
+// SurgePolicy turns supply and demand into a multiplier. The multiplier is interpolated
+// linearly between curve points, which are sorted by ratio, clamped to
+// [MinMultiplier, MaxMultiplier] and rounded to Step.
+type SurgePolicy struct {
+	Curve         []SurgeCurvePoint
+	MinMultiplier float64
+	MaxMultiplier float64
+	Step          float64
+}
+
+// Multiplier returns the multiplier for the given demand per available driver ratio
+func (p SurgePolicy) Multiplier(ratio float64) float64 {
+	multiplier := p.MinMultiplier
+
+	switch {
+	case len(p.Curve) == 0:
+	case ratio <= p.Curve[0].Ratio:
+		multiplier = p.Curve[0].Multiplier
+	case ratio >= p.Curve[len(p.Curve)-1].Ratio:
+		multiplier = p.Curve[len(p.Curve)-1].Multiplier
+	default:
+		for i := 1; i < len(p.Curve); i++ {
+			lower, upper := p.Curve[i-1], p.Curve[i]
+			if ratio <= upper.Ratio {
+				fraction := (ratio - lower.Ratio) / (upper.Ratio - lower.Ratio)
+				multiplier = lower.Multiplier + fraction*(upper.Multiplier-lower.Multiplier)
+				break
+			}
+		}
+	}
+
+	if p.Step > 0 {
+		// The second rounding drops floating point noise such as 1.2000000000000002
+		multiplier = math.Round(math.Round(multiplier/p.Step)*p.Step*1e9) / 1e9
+	}
+	return math.Max(p.MinMultiplier, math.Min(p.MaxMultiplier, multiplier))
+}
+
+// SearchCounter counts the searches stored by the demand recorders of every replica
+type SearchCounter interface {
+	// CountSearches returns the weighted number of searches per geohash cell of the given
+	// precision made within the bounding box since the given time
+	CountSearches(ctx context.Context, bounds geo.Bounds, precision int, since time.Time) (map[string]float64, error)
+}
+
+// SurgeOption configures a SurgeService
+type SurgeOption func(*SurgeService)
+
+// WithStoredDemand counts the searches stored by the demand recorders of all replicas instead of
+// those this replica received, so that every replica returns the same multiplier. Stored searches
+// are sampled and written in batches, so demand is estimated from the sample and lags by up to the
+// flush interval of the recorders.
+func WithStoredDemand(searches SearchCounter) SurgeOption {
+	return func(s *SurgeService) {
+		s.searches = searches
+	}
+}
+
+// WithCellTTL reuses the supply and demand counted for a cell during the TTL, so that repeated
+// lookups of a cell cost a single count
+func WithCellTTL(ttl time.Duration) SurgeOption {
+	return func(s *SurgeService) {
+		s.cellTTL = ttl
+	}
+}
+
+// SurgeService computes how scarce drivers are per geohash cell. Supply is the number of
+// available drivers in the cell, demand the number of searches from the cell within the
+// sliding window. Demand is counted from the searches stored by the demand recorders with
+// WithStoredDemand; otherwise the service counts the searches this replica receives as a
+// SearchRecorder of the DriverService, which only suits a single replica.
+type SurgeService struct {
+	repo      HeatmapRepository
+	searches  SearchCounter
+	demand    *SlidingWindowCounter
+	window    time.Duration
+	precision int
+	policy    SurgePolicy
+	now       func() time.Time
+
+	cellTTL   time.Duration
+	mu        sync.Mutex // Guards cells and nextSweep
+	cells     map[string]surgeCount
+	nextSweep time.Time
+}
+
+// surgeCount is the supply and demand of a cell, reused until it expires
+type surgeCount struct {
+	supply  int
+	demand  int
+	expires time.Time
+}
+
+// NewSurgeService creates a surge service over geohash cells of the given precision, counting
+// searches over the window split into the given number of buckets
+func NewSurgeService(repo HeatmapRepository, precision int, window time.Duration, buckets int, policy SurgePolicy, options ...SurgeOption) *SurgeService {
+	policy.Curve = append([]SurgeCurvePoint(nil), policy.Curve...)
+	sort.Slice(policy.Curve, func(i, j int) bool {
+		return policy.Curve[i].Ratio < policy.Curve[j].Ratio
+	})
+
+	s := &SurgeService{
+		repo:      repo,
+		demand:    NewSlidingWindowCounter(window, buckets),
+		window:    window,
+		precision: precision,
+		policy:    policy,
+		now:       time.Now,
+		cells:     make(map[string]surgeCount),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// RecordSearch counts a search as demand in the cell it was made from
+func (s *SurgeService) RecordSearch(ctx context.Context, event models.SearchEvent) {
+	s.demand.Add(geo.EncodeGeohash(event.Latitude, event.Longitude, s.precision), event.Timestamp)
+}
+
+// SurgeAt returns the surge of the cell containing the point
+func (s *SurgeService) SurgeAt(ctx context.Context, latitude, longitude float64) (*models.SurgeCell, error) {
+	latIndex, lonIndex := geo.GeohashCellIndex(latitude, longitude, s.precision)
+	hash := geo.GeohashFromCellIndex(latIndex, lonIndex, s.precision)
+	bounds := geo.GeohashCellBounds(latIndex, lonIndex, s.precision)
+	now := s.now()
+
+	count, ok := s.cachedCount(hash, now)
+	if !ok {
+		cells, err := s.repo.AggregateDriverCells(ctx, bounds, s.precision)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count available drivers: %w", err)
+		}
+		for _, cell := range cells {
+			if cell.Geohash == hash {
+				count.supply = cell.Available
+			}
+		}
+
+		if s.searches == nil {
+			count.demand = s.demand.Count(hash, now)
+		} else {
+			demand, err := s.storedDemand(ctx, bounds, now)
+			if err != nil {
+				return nil, err
+			}
+			count.demand = demand[hash]
+		}
+		s.cacheCount(hash, count, now)
+	}
+
+	cell := s.surgeCell(hash, count.supply, count.demand)
+	return &cell, nil
+}
+
+// SurgeMap returns the surge of every cell within the bounding box that has drivers or demand
+func (s *SurgeService) SurgeMap(ctx context.Context, bounds geo.Bounds) (*models.SurgeMap, error) {
+	cells, err := s.repo.AggregateDriverCells(ctx, bounds, s.precision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count available drivers: %w", err)
+	}
+
+	supply := make(map[string]int, len(cells))
+	for _, cell := range cells {
+		supply[cell.Geohash] = cell.Available
+	}
+
+	var demand map[string]int
+	if s.searches == nil {
+		demand = make(map[string]int)
+		for hash, count := range s.demand.Counts(s.now()) {
+			cellBounds, err := geo.DecodeGeohash(hash)
+			if err != nil {
+				continue
+			}
+			if latitude, longitude := cellBounds.Center(); bounds.Contains(latitude, longitude) {
+				demand[hash] = count
+			}
+		}
+	} else if demand, err = s.storedDemand(ctx, bounds, s.now()); err != nil {
+		return nil, err
+	}
+	for hash := range demand {
+		if _, ok := supply[hash]; !ok {
+			supply[hash] = 0
+		}
+	}
+
+	surgeMap := &models.SurgeMap{Precision: s.precision, Window: s.window.String(), Cells: []models.SurgeCell{}}
+	for hash, available := range supply {
+		surgeMap.Cells = append(surgeMap.Cells, s.surgeCell(hash, available, demand[hash]))
+	}
+
+	sort.Slice(surgeMap.Cells, func(i, j int) bool {
+		if surgeMap.Cells[i].Multiplier != surgeMap.Cells[j].Multiplier {
+			return surgeMap.Cells[i].Multiplier > surgeMap.Cells[j].Multiplier
+		}
+		return surgeMap.Cells[i].Geohash < surgeMap.Cells[j].Geohash
+	})
+
+	return surgeMap, nil
+}
+
+// storedDemand returns the stored searches per cell within the bounding box over the window
+func (s *SurgeService) storedDemand(ctx context.Context, bounds geo.Bounds, now time.Time) (map[string]int, error) {
+	counts, err := s.searches.CountSearches(ctx, bounds, s.precision, now.Add(-s.window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count searches: %w", err)
+	}
+
+	demand := make(map[string]int, len(counts))
+	for hash, count := range counts {
+		demand[hash] = int(math.Round(count))
+	}
+	return demand, nil
+}
+
+// cachedCount returns the supply and demand of the cell counted within the cell TTL
+func (s *SurgeService) cachedCount(hash string, now time.Time) (surgeCount, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count, ok := s.cells[hash]
+	if !ok || !now.Before(count.expires) {
+		return surgeCount{}, false
+	}
+	return count, true
+}
+
+// cacheCount keeps the supply and demand of the cell for the cell TTL, dropping expired cells at most once per TTL
+func (s *SurgeService) cacheCount(hash string, count surgeCount, now time.Time) {
+	if s.cellTTL <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !now.Before(s.nextSweep) {
+		for cached, expired := range s.cells {
+			if !now.Before(expired.expires) {
+				delete(s.cells, cached)
+			}
+		}
+		s.nextSweep = now.Add(s.cellTTL)
+	}
+	count.expires = now.Add(s.cellTTL)
+	s.cells[hash] = count
+}
+
+func (s *SurgeService) surgeCell(hash string, supply, demand int) models.SurgeCell {
+	// Without any available driver every search counts as unserved demand
+	ratio := float64(demand) / math.Max(float64(supply), 1)
+
+	bounds, _ := geo.DecodeGeohash(hash)
+	latitude, longitude := bounds.Center()
+
+	return models.SurgeCell{
+		Geohash:    hash,
+		Supply:     supply,
+		Demand:     demand,
+		Ratio:      ratio,
+		Multiplier: s.policy.Multiplier(ratio),
+		Center:     models.Coordinates{Latitude: latitude, Longitude: longitude},
+		Bounds:     bounds,
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+)
+
+var testSurgePolicy = SurgePolicy{
+	Curve: []SurgeCurvePoint{
+		{Ratio: 1, Multiplier: 1},
+		{Ratio: 2, Multiplier: 1.5},
+		{Ratio: 4, Multiplier: 2.5},
+	},
+	MinMultiplier: 1,
+	MaxMultiplier: 2,
+	Step:          0.1,
+}
+
+func TestSurgePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		ratio    float64
+		expected float64
+	}{
+		{name: "No Demand", ratio: 0, expected: 1},
+		{name: "Balanced", ratio: 1, expected: 1},
+		{name: "Interpolated", ratio: 1.5, expected: 1.3}, // 1.25 rounded to the 0.1 step
+		{name: "Curve Point", ratio: 2, expected: 1.5},
+		{name: "Capped", ratio: 3.5, expected: 2},
+		{name: "Beyond Curve", ratio: 10, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if multiplier := testSurgePolicy.Multiplier(tt.ratio); multiplier != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, multiplier)
+			}
+		})
+	}
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	counter := NewSlidingWindowCounter(time.Minute, 6)
+
+	counter.Add("a", start)
+	counter.Add("a", start.Add(15*time.Second))
+	counter.Add("b", start.Add(30*time.Second))
+
+	if count := counter.Count("a", start.Add(30*time.Second)); count != 2 {
+		t.Errorf("expected 2 events in the window, got %d", count)
+	}
+	if count := counter.Count("a", start.Add(65*time.Second)); count != 1 {
+		t.Errorf("expected the first event to leave the window, got %d", count)
+	}
+	if counts := counter.Counts(start.Add(80 * time.Second)); len(counts) != 1 || counts["b"] != 1 {
+		t.Errorf("expected only b in the window, got %v", counts)
+	}
+	if count := counter.Count("a", start.Add(10*time.Minute)); count != 0 {
+		t.Errorf("expected an empty window, got %d", count)
+	}
+}
+
+func TestSlidingWindowCounter_ClampsBuckets(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		window  time.Duration
+		buckets int
+	}{
+		{"no buckets", time.Minute, 0},
+		{"negative buckets", time.Minute, -1},
+		{"buckets narrower than a nanosecond", 10 * time.Nanosecond, 100},
+		{"no window", 0, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := NewSlidingWindowCounter(tt.window, tt.buckets)
+			counter.Add("a", start)
+			if count := counter.Count("a", start); count != 1 {
+				t.Errorf("expected 1 event in the window, got %d", count)
+			}
+		})
+	}
+}
+
+func TestSurgeAt(t *testing.T) {
+	now := time.Now()
+	latitude, longitude := 41.0082, 28.9784
+	hash := geo.EncodeGeohash(latitude, longitude, 6)
+	bounds, _ := geo.DecodeGeohash(hash)
+
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("AggregateDriverCells", mock.Anything, bounds, 6).
+		Return([]models.HeatmapCell{{Geohash: hash, Count: 3, Available: 2}}, nil).Once()
+
+	service := NewSurgeService(mockRepo, 6, 5*time.Minute, 10, testSurgePolicy)
+	service.now = func() time.Time { return now }
+	for i := 0; i < 4; i++ {
+		service.RecordSearch(context.Background(), models.SearchEvent{Latitude: latitude, Longitude: longitude, Timestamp: now})
+	}
+	// Searches outside the window or in other cells do not count
+	service.RecordSearch(context.Background(), models.SearchEvent{Latitude: latitude, Longitude: longitude, Timestamp: now.Add(-time.Hour)})
+	service.RecordSearch(context.Background(), models.SearchEvent{Latitude: 40, Longitude: 29, Timestamp: now})
+
+	cell, err := service.SurgeAt(context.Background(), latitude, longitude)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cell.Geohash != hash || cell.Supply != 2 || cell.Demand != 4 || cell.Ratio != 2 || cell.Multiplier != 1.5 {
+		t.Errorf("unexpected surge cell %+v", cell)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+// searchCounterStub returns fixed search counts and records the queries
+type searchCounterStub struct {
+	counts map[string]float64
+	since  []time.Time
+}
+
+func (s *searchCounterStub) CountSearches(ctx context.Context, bounds geo.Bounds, precision int, since time.Time) (map[string]float64, error) {
+	s.since = append(s.since, since)
+	return s.counts, nil
+}
+
+func TestSurgeAt_StoredDemand(t *testing.T) {
+	now := time.Now()
+	latitude, longitude := 41.0082, 28.9784
+	hash := geo.EncodeGeohash(latitude, longitude, 6)
+	bounds, _ := geo.DecodeGeohash(hash)
+
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("AggregateDriverCells", mock.Anything, bounds, 6).
+		Return([]models.HeatmapCell{{Geohash: hash, Count: 3, Available: 2}}, nil).Once()
+	searches := &searchCounterStub{counts: map[string]float64{hash: 40, "sxk9": 10}}
+
+	service := NewSurgeService(mockRepo, 6, 5*time.Minute, 10, testSurgePolicy, WithStoredDemand(searches), WithCellTTL(5*time.Second))
+	service.now = func() time.Time { return now }
+	// Searches of this replica do not count, the stored ones of every replica do
+	service.RecordSearch(context.Background(), models.SearchEvent{Latitude: latitude, Longitude: longitude, Timestamp: now})
+
+	for i := 0; i < 2; i++ {
+		cell, err := service.SurgeAt(context.Background(), latitude, longitude)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cell.Supply != 2 || cell.Demand != 40 || cell.Multiplier != 2 {
+			t.Errorf("unexpected surge cell %+v", cell)
+		}
+	}
+	if len(searches.since) != 1 || !searches.since[0].Equal(now.Add(-5*time.Minute)) {
+		t.Errorf("expected one count over the window within the cell TTL, got %v", searches.since)
+	}
+
+	// Once the TTL passed the cell is counted again
+	mockRepo.On("AggregateDriverCells", mock.Anything, bounds, 6).
+		Return([]models.HeatmapCell{{Geohash: hash, Count: 3, Available: 2}}, nil).Once()
+	now = now.Add(5 * time.Second)
+	if _, err := service.SurgeAt(context.Background(), latitude, longitude); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(searches.since) != 2 {
+		t.Errorf("expected the cell to be counted again after the TTL, got %d counts", len(searches.since))
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFindNearestDriver_RecordsSearches(t *testing.T) {
+	recorder := &surgeRecorderStub{}
+	mockRepo := &MockDriverRepository{}
+	mockRepo.On("FindNearestDriver", mock.Anything, 41.0, 29.0, 5000).Return(&models.DriverWithDistance{}, nil).Once()
+	mockRepo.On("FindNearestDriver", mock.Anything, 42.0, 30.0, 5000).Return(nil, repository.ErrDriverNotFound).Once()
+
+	service := NewDriverService(mockRepo, WithSearchRecorder(recorder))
+	service.FindNearestDriver(context.Background(), 41.0, 29.0, 5000)
+	service.FindNearestDriver(context.Background(), 42.0, 30.0, 5000)
+
+	if len(recorder.events) != 2 || !recorder.events[0].Found || recorder.events[1].Found || recorder.events[1].Latitude != 42.0 {
+		t.Errorf("unexpected recorded searches %+v", recorder.events)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+type surgeRecorderStub struct {
+	events []models.SearchEvent
+}
+
+func (s *surgeRecorderStub) RecordSearch(ctx context.Context, event models.SearchEvent) {
+	s.events = append(s.events, event)
+}
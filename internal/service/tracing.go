@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"bitaksi-go-driver/internal/repository"
+)
+
+// startSpan starts a span of the service. The tracer is looked up on every call, so that it follows
+// changes of the global provider.
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer("bitaksi-go-driver/internal/service").Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// endSpan ends the span, marking it failed if err is an error other than a missing driver or reservation
+func endSpan(span trace.Span, err error) {
+	if err != nil && !repository.IsNotFound(err) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
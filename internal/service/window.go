@@ -0,0 +1,111 @@
+package service
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// SlidingWindowCounter counts events per key over a sliding time window. The window is split
+// into buckets, so counts are accurate to one bucket width and memory stays constant per key.
+type SlidingWindowCounter struct {
+	mu          sync.Mutex
+	bucketWidth time.Duration
+	buckets     int
+	counts      map[string][]windowBucket
+	adds        int
+}
+
+type windowBucket struct {
+	epoch int64 // Bucket number since the Unix epoch, identifies stale buckets
+	count int
+}
+
+// pruneInterval is the number of additions after which keys without recent events are dropped
+const pruneInterval = 10000
+
+// NewSlidingWindowCounter creates a counter over the window split into the given number of
+// buckets. The buckets are clamped to between one and one per nanosecond of the window, and a
+// window that is not positive counts over a single nanosecond.
+func NewSlidingWindowCounter(window time.Duration, buckets int) *SlidingWindowCounter {
+	if window < 1 {
+		window = 1
+	}
+	buckets = max(1, min(buckets, int(min(window, time.Duration(math.MaxInt)))))
+
+	return &SlidingWindowCounter{
+		bucketWidth: window / time.Duration(buckets),
+		buckets:     buckets,
+		counts:      make(map[string][]windowBucket),
+	}
+}
+
+// Add records an event for the key at the given time
+func (c *SlidingWindowCounter) Add(key string, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ring, ok := c.counts[key]
+	if !ok {
+		ring = make([]windowBucket, c.buckets)
+		c.counts[key] = ring
+	}
+
+	epoch := at.UnixNano() / int64(c.bucketWidth)
+	bucket := &ring[epoch%int64(c.buckets)]
+	switch {
+	case bucket.epoch > epoch:
+		// The slot already holds a newer bucket, so the event is older than the window
+		return
+	case bucket.epoch < epoch:
+		*bucket = windowBucket{epoch: epoch}
+	}
+	bucket.count++
+
+	c.adds++
+	if c.adds%pruneInterval == 0 {
+		c.prune(epoch)
+	}
+}
+
+// Count returns the number of events for the key within the window ending at the given time
+func (c *SlidingWindowCounter) Count(key string, at time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.count(c.counts[key], at.UnixNano()/int64(c.bucketWidth))
+}
+
+// Counts returns the non-zero counts of all keys within the window ending at the given time
+func (c *SlidingWindowCounter) Counts(at time.Time) map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	epoch := at.UnixNano() / int64(c.bucketWidth)
+	counts := make(map[string]int)
+	for key, ring := range c.counts {
+		if count := c.count(ring, epoch); count > 0 {
+			counts[key] = count
+		}
+	}
+	return counts
+}
+
+func (c *SlidingWindowCounter) count(ring []windowBucket, epoch int64) int {
+	total := 0
+	for _, bucket := range ring {
+		if bucket.epoch > epoch-int64(c.buckets) && bucket.epoch <= epoch {
+			total += bucket.count
+		}
+	}
+	return total
+}
+
+// prune drops keys without events in the current window. The caller holds c.mu.
+func (c *SlidingWindowCounter) prune(epoch int64) {
+	for key, ring := range c.counts {
+		if c.count(ring, epoch) == 0 {
+			delete(c.counts, key)
+		}
+	}
+}
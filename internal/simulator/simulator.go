@@ -0,0 +1,240 @@
+// Package simulator generates a synthetic fleet of drivers and moves them around at realistic
+// speeds, for load tests and demos. Paths only depend on the seed, so runs are reproducible.
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// Movement modes
+const (
+	// ModeRandomWalk drives in a slowly changing direction, bouncing off the edges of the bounds
+	ModeRandomWalk = "random-walk"
+	// ModeWaypoints drives straight to a random point of the bounds, then picks the next one
+	ModeWaypoints = "waypoints"
+)
+
+const (
+	minSpeedKmh = 5  // Slowest a moving driver goes, e.g. in a traffic jam
+	maxSpeedKmh = 90 // Fastest a driver goes on urban highways
+	seedBatch   = 1000
+
+	// Standard deviations of the changes between steps
+	headingChange = 15.0 // degrees
+	speedChange   = 0.1  // fraction of the speed
+)
+
+// ErrInvalidOptions is returned when the simulation cannot run with the given options
+var ErrInvalidOptions = errors.New("invalid simulation options")
+
+// DriverUpdater receives the simulated drivers, e.g. a DriverService
+type DriverUpdater interface {
+	AddDrivers(ctx context.Context, drivers []models.DriverWithDistance) error
+	UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error
+}
+
+// Options configure a simulation
+type Options struct {
+	Drivers  int
+	Bounds   geo.Bounds // Drivers start and stay within these bounds, which must not cross the antimeridian
+	Mode     string
+	SpeedKmh float64 // Average speed, each driver varies around it
+	Seed     int64
+}
+
+type simulatedDriver struct {
+	id                  primitive.ObjectID
+	latitude, longitude float64
+	speed               float64 // meters per second
+	heading             float64 // degrees clockwise from north
+	targetLatitude      float64
+	targetLongitude     float64
+}
+
+// Simulator moves a synthetic fleet and pushes every position through the updater
+type Simulator struct {
+	updater DriverUpdater
+	options Options
+	random  *rand.Rand
+	drivers []*simulatedDriver
+}
+
+// New creates a simulation of the options, pushing drivers through the updater
+func New(updater DriverUpdater, options Options) (*Simulator, error) {
+	bounds := options.Bounds
+	switch {
+	case options.Drivers <= 0:
+		return nil, fmt.Errorf("%w: the number of drivers must be positive", ErrInvalidOptions)
+	case bounds.MinLat >= bounds.MaxLat || bounds.MinLon >= bounds.MaxLon ||
+		bounds.MinLat < -90 || bounds.MaxLat > 90 || bounds.MinLon < -180 || bounds.MaxLon > 180:
+		return nil, fmt.Errorf("%w: the bounds must be a non-empty rectangle not crossing the antimeridian", ErrInvalidOptions)
+	case options.Mode != ModeRandomWalk && options.Mode != ModeWaypoints:
+		return nil, fmt.Errorf("%w: the mode must be %s or %s", ErrInvalidOptions, ModeRandomWalk, ModeWaypoints)
+	case options.SpeedKmh < minSpeedKmh || options.SpeedKmh > maxSpeedKmh:
+		return nil, fmt.Errorf("%w: the speed must be between %d and %d km/h", ErrInvalidOptions, minSpeedKmh, maxSpeedKmh)
+	}
+
+	return &Simulator{
+		updater: updater,
+		options: options,
+		random:  rand.New(rand.NewSource(options.Seed)),
+	}, nil
+}
+
+// Seed places the drivers at random points of the bounds and adds them through the updater
+func (s *Simulator) Seed(ctx context.Context) error {
+	s.drivers = make([]*simulatedDriver, s.options.Drivers)
+	batch := make([]models.DriverWithDistance, 0, seedBatch)
+
+	for i := range s.drivers {
+		latitude, longitude := s.randomPoint()
+		driver := &simulatedDriver{
+			id:        primitive.NewObjectID(),
+			latitude:  latitude,
+			longitude: longitude,
+			speed:     s.clampSpeed(s.options.SpeedKmh / 3.6 * (1 + 0.25*s.random.NormFloat64())),
+			heading:   s.random.Float64() * 360,
+		}
+		driver.targetLatitude, driver.targetLongitude = s.randomPoint()
+		s.drivers[i] = driver
+
+		batch = append(batch, models.DriverWithDistance{
+			ID:       driver.id,
+			Location: models.Location{Type: "Point", Coordinates: []float64{longitude, latitude}},
+		})
+		if len(batch) == seedBatch || i == len(s.drivers)-1 {
+			if err := s.updater.AddDrivers(ctx, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	return nil
+}
+
+// Step moves every driver for the elapsed time and pushes their new positions
+func (s *Simulator) Step(ctx context.Context, elapsed time.Duration) error {
+	for _, driver := range s.drivers {
+		switch s.options.Mode {
+		case ModeRandomWalk:
+			s.walk(driver, elapsed)
+		case ModeWaypoints:
+			s.driveToWaypoint(driver, elapsed)
+		}
+
+		if err := s.updater.UpdateDriverLocation(ctx, driver.id, driver.latitude, driver.longitude); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run seeds the fleet and moves it every tick until the duration has passed, or until the context
+// is done if the duration is zero
+func (s *Simulator) Run(ctx context.Context, tick, duration time.Duration) error {
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	if err := s.Seed(ctx); err != nil {
+		return fmt.Errorf("failed to seed drivers: %w", err)
+	}
+	slog.InfoContext(ctx, "Simulating drivers", "drivers", len(s.drivers), "tick", tick)
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		started := time.Now()
+		if err := s.Step(ctx, tick); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to move drivers: %w", err)
+		}
+		if took := time.Since(started); took > tick {
+			slog.WarnContext(ctx, "Moving the drivers took longer than the tick", "took", took, "tick", tick)
+		}
+	}
+}
+
+// Drivers returns the current positions of the simulated drivers
+func (s *Simulator) Drivers() []models.DriverWithDistance {
+	drivers := make([]models.DriverWithDistance, len(s.drivers))
+	for i, driver := range s.drivers {
+		drivers[i] = models.DriverWithDistance{
+			ID:       driver.id,
+			Location: models.Location{Type: "Point", Coordinates: []float64{driver.longitude, driver.latitude}},
+		}
+	}
+	return drivers
+}
+
+// walk turns the driver a little and moves them, reflecting their heading off the edges of the bounds
+func (s *Simulator) walk(driver *simulatedDriver, elapsed time.Duration) {
+	driver.heading = math.Mod(driver.heading+headingChange*s.random.NormFloat64()+360, 360)
+	driver.speed = s.clampSpeed(driver.speed * (1 + speedChange*s.random.NormFloat64()))
+
+	bounds := s.options.Bounds
+	distance := driver.speed * elapsed.Seconds()
+	latitude, longitude := geo.Destination(driver.latitude, driver.longitude, driver.heading, distance)
+	if latitude < bounds.MinLat || latitude > bounds.MaxLat {
+		driver.heading = math.Mod(540-driver.heading, 360)
+	}
+	if longitude < bounds.MinLon || longitude > bounds.MaxLon {
+		driver.heading = math.Mod(360-driver.heading, 360)
+	}
+	if !bounds.Contains(latitude, longitude) {
+		latitude, longitude = geo.Destination(driver.latitude, driver.longitude, driver.heading, distance)
+	}
+
+	// A distance longer than the bounds can still overshoot after reflecting
+	driver.latitude = math.Max(bounds.MinLat, math.Min(bounds.MaxLat, latitude))
+	driver.longitude = math.Max(bounds.MinLon, math.Min(bounds.MaxLon, longitude))
+}
+
+// driveToWaypoint moves the driver towards their waypoint, picking the next one once it is reached
+func (s *Simulator) driveToWaypoint(driver *simulatedDriver, elapsed time.Duration) {
+	distance := driver.speed * elapsed.Seconds()
+	remaining := geo.Distance(driver.latitude, driver.longitude, driver.targetLatitude, driver.targetLongitude)
+
+	if distance >= remaining {
+		driver.latitude, driver.longitude = driver.targetLatitude, driver.targetLongitude
+		driver.targetLatitude, driver.targetLongitude = s.randomPoint()
+		driver.speed = s.clampSpeed(s.options.SpeedKmh / 3.6 * (1 + 0.25*s.random.NormFloat64()))
+		return
+	}
+
+	driver.heading = geo.Bearing(driver.latitude, driver.longitude, driver.targetLatitude, driver.targetLongitude)
+	driver.latitude, driver.longitude = geo.Destination(driver.latitude, driver.longitude, driver.heading, distance)
+}
+
+// randomPoint returns a random point of the bounds
+func (s *Simulator) randomPoint() (latitude, longitude float64) {
+	return s.options.Bounds.RandomPoint(s.random)
+}
+
+// clampSpeed keeps a speed in meters per second within realistic city driving speeds
+func (s *Simulator) clampSpeed(speed float64) float64 {
+	return math.Max(minSpeedKmh/3.6, math.Min(maxSpeedKmh/3.6, speed))
+}
@@ -0,0 +1,177 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+)
+
+// updaterStub records the drivers pushed by the simulator
+type updaterStub struct {
+	mu        sync.Mutex
+	added     int
+	positions map[primitive.ObjectID][2]float64
+	updates   int
+	err       error
+}
+
+func (u *updaterStub) AddDrivers(ctx context.Context, drivers []models.DriverWithDistance) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.positions == nil {
+		u.positions = make(map[primitive.ObjectID][2]float64)
+	}
+	for _, driver := range drivers {
+		u.positions[driver.ID] = [2]float64{driver.Location.Coordinates[1], driver.Location.Coordinates[0]}
+	}
+	u.added += len(drivers)
+	return nil
+}
+
+func (u *updaterStub) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.positions[id] = [2]float64{latitude, longitude}
+	u.updates++
+	return u.err
+}
+
+var istanbul = geo.Bounds{MinLat: 40.95, MinLon: 28.9, MaxLat: 41.1, MaxLon: 29.1}
+
+func newSimulator(t *testing.T, updater DriverUpdater, mode string, seed int64) *Simulator {
+	t.Helper()
+	simulator, err := New(updater, Options{Drivers: 50, Bounds: istanbul, Mode: mode, SpeedKmh: 30, Seed: seed})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := simulator.Seed(context.Background()); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	return simulator
+}
+
+func positions(simulator *Simulator) [][2]float64 {
+	var result [][2]float64
+	for _, driver := range simulator.Drivers() {
+		result = append(result, [2]float64{driver.Location.Coordinates[1], driver.Location.Coordinates[0]})
+	}
+	return result
+}
+
+func TestSimulator_Deterministic(t *testing.T) {
+	for _, mode := range []string{ModeRandomWalk, ModeWaypoints} {
+		t.Run(mode, func(t *testing.T) {
+			first := newSimulator(t, &updaterStub{}, mode, 42)
+			second := newSimulator(t, &updaterStub{}, mode, 42)
+			other := newSimulator(t, &updaterStub{}, mode, 43)
+
+			for i := 0; i < 20; i++ {
+				for _, simulator := range []*Simulator{first, second, other} {
+					if err := simulator.Step(context.Background(), time.Second); err != nil {
+						t.Fatalf("Step failed: %v", err)
+					}
+				}
+			}
+
+			firstPositions, secondPositions := positions(first), positions(second)
+			for i := range firstPositions {
+				if firstPositions[i] != secondPositions[i] {
+					t.Fatalf("driver %d: expected the same seed to give the same path, got %v and %v", i, firstPositions[i], secondPositions[i])
+				}
+			}
+			if positions(other)[0] == firstPositions[0] {
+				t.Errorf("expected another seed to give another path")
+			}
+		})
+	}
+}
+
+func TestSimulator_MovesRealistically(t *testing.T) {
+	for _, mode := range []string{ModeRandomWalk, ModeWaypoints} {
+		t.Run(mode, func(t *testing.T) {
+			updater := &updaterStub{}
+			simulator := newSimulator(t, updater, mode, 7)
+			if updater.added != 50 {
+				t.Fatalf("expected 50 seeded drivers, got %d", updater.added)
+			}
+
+			moved := false
+			for step := 0; step < 600; step++ {
+				before := positions(simulator)
+				if err := simulator.Step(context.Background(), 5*time.Second); err != nil {
+					t.Fatalf("Step failed: %v", err)
+				}
+
+				for i, driver := range simulator.Drivers() {
+					latitude, longitude := driver.Location.Coordinates[1], driver.Location.Coordinates[0]
+					if !istanbul.Contains(latitude, longitude) {
+						t.Fatalf("step %d: driver %d left the bounds: (%v, %v)", step, i, latitude, longitude)
+					}
+
+					distance := geo.Distance(before[i][0], before[i][1], latitude, longitude)
+					if distance > maxSpeedKmh/3.6*5+0.01 {
+						t.Fatalf("step %d: driver %d moved %.1f meters in 5 seconds", step, i, distance)
+					}
+					moved = moved || distance > 0
+					if updater.positions[driver.ID] != [2]float64{latitude, longitude} {
+						t.Fatalf("step %d: driver %d was not pushed to the updater", step, i)
+					}
+				}
+			}
+			if !moved {
+				t.Errorf("expected the drivers to move")
+			}
+		})
+	}
+}
+
+func TestSimulator_Run(t *testing.T) {
+	updater := &updaterStub{}
+	simulator, err := New(updater, Options{Drivers: 3, Bounds: istanbul, Mode: ModeRandomWalk, SpeedKmh: 30, Seed: 1})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if err := simulator.Run(context.Background(), 5*time.Millisecond, 100*time.Millisecond); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if updater.added != 3 || updater.updates == 0 || updater.updates%3 != 0 {
+		t.Errorf("expected 3 seeded drivers moved every tick, got %d added and %d updates", updater.added, updater.updates)
+	}
+
+	failing := &updaterStub{err: errors.New("database unavailable")}
+	simulator, _ = New(failing, Options{Drivers: 3, Bounds: istanbul, Mode: ModeWaypoints, SpeedKmh: 30, Seed: 1})
+	if err := simulator.Run(context.Background(), time.Millisecond, time.Second); err == nil {
+		t.Errorf("expected failed updates to stop the simulation")
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+	}{
+		{name: "No Drivers", options: Options{Drivers: 0, Bounds: istanbul, Mode: ModeRandomWalk, SpeedKmh: 30}},
+		{name: "Empty Bounds", options: Options{Drivers: 1, Bounds: geo.Bounds{MinLat: 41, MinLon: 29, MaxLat: 41, MaxLon: 29}, Mode: ModeRandomWalk, SpeedKmh: 30}},
+		{name: "Across The Antimeridian", options: Options{Drivers: 1, Bounds: geo.Bounds{MinLat: 0, MinLon: 179, MaxLat: 1, MaxLon: -179}, Mode: ModeRandomWalk, SpeedKmh: 30}},
+		{name: "Unknown Mode", options: Options{Drivers: 1, Bounds: istanbul, Mode: "teleport", SpeedKmh: 30}},
+		{name: "Unrealistic Speed", options: Options{Drivers: 1, Bounds: istanbul, Mode: ModeWaypoints, SpeedKmh: 300}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(&updaterStub{}, tt.options); !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("expected ErrInvalidOptions, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"bitaksi-go-driver/internal/config"
+	"bitaksi-go-driver/internal/db"
+	"bitaksi-go-driver/internal/health"
+	"bitaksi-go-driver/internal/migrate"
+	"bitaksi-go-driver/internal/repository"
+	"bitaksi-go-driver/internal/service"
+)
+
+// Storage backends selectable with the storage.backend configuration key
+const (
+	BackendMongo    = "mongo"
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// DriverStore is the repository contract every storage backend implements for drivers
+type DriverStore interface {
+	service.DriverRepository
+	service.ReservationRepository
+	service.HeatmapRepository
+}
+
+// Repositories are the repositories of one storage backend
+type Repositories struct {
+	Drivers      DriverStore
+	SearchEvents service.SearchEventRepository
+	Rides        service.RideRepository
+	// Checks tell whether the backend can serve requests, for the readiness probe
+	Checks []health.Check
+
+	close func(ctx context.Context) error
+}
+
+// Close releases the connections of the backend
+func (r *Repositories) Close(ctx context.Context) error {
+	if r.close == nil {
+		return nil
+	}
+	return r.close(ctx)
+}
+
+// Option configures how Open connects to the storage backend
+type Option func(opts *openOptions)
+
+type openOptions struct {
+	mongoClientOptions []*options.ClientOptions
+}
+
+// WithPoolMonitor observes the MongoDB connection pool. It has no effect on the other backends.
+func WithPoolMonitor(monitor *event.PoolMonitor) Option {
+	return func(opts *openOptions) {
+		opts.mongoClientOptions = append(opts.mongoClientOptions, options.Client().SetPoolMonitor(monitor))
+	}
+}
+
+// WithCommandMonitor observes the MongoDB commands, e.g. to trace them. It has no effect on the other backends.
+func WithCommandMonitor(monitor *event.CommandMonitor) Option {
+	return func(opts *openOptions) {
+		opts.mongoClientOptions = append(opts.mongoClientOptions, options.Client().SetMonitor(monitor))
+	}
+}
+
+// Open connects to the storage backend selected in the configuration and returns its repositories
+func Open(ctx context.Context, cfg *config.Config, opts ...Option) (*Repositories, error) {
+	var o openOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	switch cfg.Storage.Backend {
+	case "", BackendMongo:
+		return openMongo(ctx, cfg, o.mongoClientOptions)
+	case BackendMemory:
+		slog.WarnContext(ctx, "Using in-memory storage, data is lost on restart")
+		return &Repositories{
+			Drivers:      repository.NewMemoryDriverRepository(),
+			SearchEvents: repository.NewMemorySearchEventRepository(),
+			Rides:        repository.NewMemoryRideRepository(),
+		}, nil
+	case BackendPostgres:
+		return openPostgres(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Storage.Backend)
+	}
+}
+
+// Migration commands accepted by Migrate
+const (
+	MigrateUp     = "up"
+	MigrateDown   = "down"
+	MigrateStatus = "status"
+)
+
+// Migrate runs a migration command against the storage backend selected in the configuration:
+// up applies the pending migrations, down reverts the given number of most recent ones and status
+// lists them. Only MongoDB has versioned migrations, the PostgreSQL schema is created by up.
+func Migrate(ctx context.Context, cfg *config.Config, command string, steps int, out io.Writer) error {
+	switch cfg.Storage.Backend {
+	case "", BackendMongo:
+		client, err := db.ConnectMongo(cfg)
+		if err != nil {
+			return err
+		}
+		defer client.Disconnect(ctx)
+
+		return runMongoMigration(ctx, mongoMigrator(cfg, client.Database(cfg.MongoDB.Database)), command, steps, out)
+	case BackendMemory:
+		fmt.Fprintf(out, "The %s backend has no schema to migrate\n", BackendMemory)
+		return nil
+	case BackendPostgres:
+		if command != MigrateUp {
+			return fmt.Errorf("the %s backend only supports %q", BackendPostgres, MigrateUp)
+		}
+
+		pool, err := db.ConnectPostgres(cfg)
+		if err != nil {
+			return err
+		}
+		defer pool.Close()
+
+		drivers := repository.NewPostgresDriverRepository(pool)
+		searchEvents := repository.NewPostgresSearchEventRepository(pool)
+		rides := repository.NewPostgresRideRepository(pool)
+		return migratePostgres(ctx, &drivers, &searchEvents, &rides)
+	default:
+		return fmt.Errorf("unknown storage backend: %s", cfg.Storage.Backend)
+	}
+}
+
+func runMongoMigration(ctx context.Context, migrator *migrate.Migrator, command string, steps int, out io.Writer) error {
+	switch command {
+	case MigrateUp:
+		versions, err := migrator.Up(ctx)
+		fmt.Fprintf(out, "Applied migrations: %v\n", versions)
+		return err
+	case MigrateDown:
+		versions, err := migrator.Down(ctx, steps)
+		fmt.Fprintf(out, "Reverted migrations: %v\n", versions)
+		return err
+	case MigrateStatus:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%3d  %-28s  %s\n", status.Version, applied, status.Description)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migration command: %s", command)
+	}
+}
+
+func mongoMigrator(cfg *config.Config, database *mongo.Database) *migrate.Migrator {
+	return migrate.NewMigrator(database, migrate.Migrations(cfg.Demand.Retention), migrate.RequiredIndexes)
+}
+
+// openMongo applies pending migrations if configured to, and refuses to start with an outdated schema
+func openMongo(ctx context.Context, cfg *config.Config, clientOptions []*options.ClientOptions) (*Repositories, error) {
+	client, err := db.ConnectMongo(cfg, clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	database := client.Database(cfg.MongoDB.Database)
+	migrator := mongoMigrator(cfg, database)
+	if cfg.Migrations.RunOnStartup {
+		if _, err := migrator.Up(ctx); err != nil {
+			client.Disconnect(ctx)
+			return nil, err
+		}
+	}
+	if err := migrator.Verify(ctx); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+
+	drivers := repository.NewDriverRepository(database, migrate.DriversCollection)
+	searchEvents := repository.NewSearchEventRepository(database, migrate.SearchEventsCollection)
+	rides := repository.NewRideRepository(database, migrate.RidesCollection)
+
+	return &Repositories{
+		Drivers:      &drivers,
+		SearchEvents: &searchEvents,
+		Rides:        &rides,
+		Checks: []health.Check{
+			{Name: "mongodb", Check: func(ctx context.Context) error {
+				return client.Ping(ctx, readpref.Primary())
+			}},
+			{Name: "geo_index", Check: func(ctx context.Context) error {
+				return requireIndex(migrate.HasIndex(ctx, database.Collection(migrate.DriversCollection), migrate.DriversLocationIndex))
+			}},
+		},
+		close: client.Disconnect,
+	}, nil
+}
+
+func openPostgres(ctx context.Context, cfg *config.Config) (*Repositories, error) {
+	pool, err := db.ConnectPostgres(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	drivers := repository.NewPostgresDriverRepository(pool)
+	searchEvents := repository.NewPostgresSearchEventRepository(pool)
+	rides := repository.NewPostgresRideRepository(pool)
+	if err := migratePostgres(ctx, &drivers, &searchEvents, &rides); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &Repositories{
+		Drivers:      &drivers,
+		SearchEvents: &searchEvents,
+		Rides:        &rides,
+		Checks: []health.Check{
+			{Name: "postgres", Check: pool.Ping},
+			{Name: "geo_index", Check: func(ctx context.Context) error {
+				return requireIndex(drivers.HasIndex(ctx))
+			}},
+		},
+		close: func(ctx context.Context) error {
+			pool.Close()
+			return nil
+		},
+	}, nil
+}
+
+// requireIndex fails the readiness check of an index missing, e.g. dropped by hand, as searches
+// would scan every driver without it
+func requireIndex(exists bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("the geospatial index of the drivers is missing")
+	}
+	return nil
+}
+
+// migratePostgres creates the tables and indexes. They are all created if missing, so it runs on every start.
+func migratePostgres(ctx context.Context, drivers *repository.PostgresDriverRepository, searchEvents *repository.PostgresSearchEventRepository, rides *repository.PostgresRideRepository) error {
+	if err := drivers.EnsureSchema(ctx); err != nil {
+		return fmt.Errorf("failed to create drivers table: %w", err)
+	}
+	if err := drivers.EnsureIndex(ctx); err != nil {
+		return fmt.Errorf("failed to create drivers index: %w", err)
+	}
+	if err := searchEvents.EnsureSchema(ctx); err != nil {
+		return fmt.Errorf("failed to create search_events table: %w", err)
+	}
+	if err := rides.EnsureSchema(ctx); err != nil {
+		return fmt.Errorf("failed to create rides table: %w", err)
+	}
+	return nil
+}
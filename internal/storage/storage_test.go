@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"bitaksi-go-driver/internal/config"
+	"bitaksi-go-driver/internal/migrate"
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+	"bitaksi-go-driver/internal/repository/repositorytest"
+)
+
+// Backends other than memory run when a database is available, see repositorytest.MongoURI and
+// repositorytest.PostgresPool
+func testBackends(t *testing.T) map[string]DriverStore {
+	backends := map[string]DriverStore{BackendMemory: repository.NewMemoryDriverRepository()}
+
+	if uri, ok := repositorytest.MongoURI(t); ok {
+		database := repositorytest.MongoDatabase(t, uri)
+		if _, err := migrate.NewMigrator(database, migrate.Migrations(time.Hour), migrate.RequiredIndexes).Up(context.Background()); err != nil {
+			t.Fatalf("failed to migrate: %v", err)
+		}
+		drivers := repository.NewDriverRepository(database, migrate.DriversCollection)
+		backends[BackendMongo] = &drivers
+	}
+
+	if pool, ok := repositorytest.PostgresPool(t); ok {
+		drivers := repository.NewPostgresDriverRepository(pool)
+		if err := drivers.EnsureSchema(context.Background()); err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+		if err := drivers.EnsureIndex(context.Background()); err != nil {
+			t.Fatalf("failed to create index: %v", err)
+		}
+		backends[BackendPostgres] = &drivers
+	}
+
+	return backends
+}
+
+func TestBackendsReturnIdenticalNearestDrivers(t *testing.T) {
+	ctx := context.Background()
+	random := rand.New(rand.NewSource(7))
+
+	var locations []models.DriverWithDistance
+	for i := 0; i < 500; i++ {
+		latitude, longitude := 41+random.Float64()*0.1, 28.95+random.Float64()*0.1
+		locations = append(locations, models.DriverWithDistance{Location: models.Location{Type: "Point", Coordinates: []float64{longitude, latitude}}})
+	}
+
+	backends := testBackends(t)
+	if len(backends) == 1 {
+		t.Log("Only the memory backend is available, set MONGO_TEST_URI, install mongod or set POSTGRES_TEST_URL to compare backends")
+	}
+
+	results := make(map[string][][]models.DriverWithDistance)
+	for name, backend := range backends {
+		if err := backend.SaveDrivers(ctx, locations); err != nil {
+			t.Fatalf("%s: failed to save drivers: %v", name, err)
+		}
+
+		searches := []struct {
+			latitude, longitude float64
+			radius, limit       int
+		}{
+			{41.05, 29, 500, 10},
+			{41.05, 29, 5000, 25},
+			{41.0, 28.95, 2000, 5},
+			{40, 28, 1000, 5},
+		}
+		for _, search := range searches {
+			drivers, err := backend.FindNearestDrivers(ctx, search.latitude, search.longitude, search.radius, search.limit)
+			if err != nil && !errors.Is(err, repository.ErrDriverNotFound) {
+				t.Fatalf("%s: search %+v failed: %v", name, search, err)
+			}
+			results[name] = append(results[name], drivers)
+		}
+	}
+
+	expected := results[BackendMemory]
+	for name, result := range results {
+		for i := range expected {
+			if len(result[i]) != len(expected[i]) {
+				t.Fatalf("%s: search %d returned %d drivers, memory returned %d", name, i, len(result[i]), len(expected[i]))
+			}
+			for j := range expected[i] {
+				got, want := result[i][j], expected[i][j]
+				// IDs are generated per backend, so drivers are compared by location and distance
+				if got.Location.Coordinates[0] != want.Location.Coordinates[0] || got.Location.Coordinates[1] != want.Location.Coordinates[1] ||
+					math.Abs(got.Distance-want.Distance) > 0.01 {
+					t.Errorf("%s: search %d driver %d is %+v, memory returned %+v", name, i, j, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Storage.Backend = BackendMemory
+
+	repos, err := Open(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := repos.Drivers.(*repository.MemoryDriverRepository); !ok {
+		t.Errorf("expected the memory repository, got %T", repos.Drivers)
+	}
+	if err := repos.Close(context.Background()); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	cfg.Storage.Backend = "cassandra"
+	if _, err := Open(context.Background(), cfg); err == nil {
+		t.Errorf("expected an error for an unknown backend")
+	}
+}
@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"bitaksi-go-driver/internal/geo"
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/storage"
+)
+
+// instrumentationName names the tracer of the repository spans
+const instrumentationName = "bitaksi-go-driver/internal/tracing"
+
+// Repository starts a span for every operation of a storage backend. MongoDB commands made by the
+// operation show up as its children when the client is monitored by otelmongo.
+type Repository struct {
+	store storage.DriverStore
+}
+
+func NewRepository(store storage.DriverStore) *Repository {
+	return &Repository{store: store}
+}
+
+func (r *Repository) start(ctx context.Context, operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	// The tracer is looked up on every call, so that it follows changes of the global provider
+	return otel.Tracer(instrumentationName).Start(ctx, "DriverRepository."+operation,
+		trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attributes...))
+}
+
+func searchAttributes(latitude, longitude float64, radius int) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Float64("search.latitude", latitude),
+		attribute.Float64("search.longitude", longitude),
+		attribute.Int("search.radius", radius),
+	}
+}
+
+func (r *Repository) SaveDrivers(ctx context.Context, locations []models.DriverWithDistance) (err error) {
+	ctx, span := r.start(ctx, "SaveDrivers", attribute.Int("drivers.count", len(locations)))
+	defer func() { end(span, err) }()
+	return r.store.SaveDrivers(ctx, locations)
+}
+
+func (r *Repository) FindNearestDriver(ctx context.Context, latitude, longitude float64, radius int) (_ *models.DriverWithDistance, err error) {
+	ctx, span := r.start(ctx, "FindNearestDriver", searchAttributes(latitude, longitude, radius)...)
+	defer func() { end(span, err) }()
+	return r.store.FindNearestDriver(ctx, latitude, longitude, radius)
+}
+
+func (r *Repository) FindNearestDrivers(ctx context.Context, latitude, longitude float64, radius, limit int) (_ []models.DriverWithDistance, err error) {
+	ctx, span := r.start(ctx, "FindNearestDrivers", append(searchAttributes(latitude, longitude, radius), attribute.Int("search.limit", limit))...)
+	defer func() { end(span, err) }()
+	return r.store.FindNearestDrivers(ctx, latitude, longitude, radius, limit)
+}
+
+func (r *Repository) UpdateDriverLocation(ctx context.Context, id primitive.ObjectID, latitude, longitude float64) (err error) {
+	ctx, span := r.start(ctx, "UpdateDriverLocation", attribute.String("driver.id", id.Hex()))
+	defer func() { end(span, err) }()
+	return r.store.UpdateDriverLocation(ctx, id, latitude, longitude)
+}
+
+func (r *Repository) ListDrivers(ctx context.Context) (_ []models.DriverWithDistance, err error) {
+	ctx, span := r.start(ctx, "ListDrivers")
+	defer func() { end(span, err) }()
+	return r.store.ListDrivers(ctx)
+}
+
+func (r *Repository) ReserveDriver(ctx context.Context, id primitive.ObjectID, reservationID string) (err error) {
+	ctx, span := r.start(ctx, "ReserveDriver", attribute.String("driver.id", id.Hex()))
+	defer func() { end(span, err) }()
+	return r.store.ReserveDriver(ctx, id, reservationID)
+}
+
+func (r *Repository) ClaimNearestDriver(ctx context.Context, latitude, longitude float64, radius int, ttl time.Duration, reservationID string) (_ *models.DriverWithDistance, err error) {
+	ctx, span := r.start(ctx, "ClaimNearestDriver", searchAttributes(latitude, longitude, radius)...)
+	defer func() { end(span, err) }()
+	return r.store.ClaimNearestDriver(ctx, latitude, longitude, radius, ttl, reservationID)
+}
+
+func (r *Repository) ConfirmReservation(ctx context.Context, id primitive.ObjectID, reservationID string) (err error) {
+	ctx, span := r.start(ctx, "ConfirmReservation", attribute.String("driver.id", id.Hex()))
+	defer func() { end(span, err) }()
+	return r.store.ConfirmReservation(ctx, id, reservationID)
+}
+
+func (r *Repository) ReleaseDriver(ctx context.Context, id primitive.ObjectID, reservationID string) (err error) {
+	ctx, span := r.start(ctx, "ReleaseDriver", attribute.String("driver.id", id.Hex()))
+	defer func() { end(span, err) }()
+	return r.store.ReleaseDriver(ctx, id, reservationID)
+}
+
+func (r *Repository) AggregateDriverCells(ctx context.Context, bounds geo.Bounds, precision int) (_ []models.HeatmapCell, err error) {
+	ctx, span := r.start(ctx, "AggregateDriverCells", attribute.Int("heatmap.precision", precision))
+	defer func() { end(span, err) }()
+	return r.store.AggregateDriverCells(ctx, bounds, precision)
+}
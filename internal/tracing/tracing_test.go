@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"bitaksi-go-driver/internal/api"
+	"bitaksi-go-driver/internal/config"
+	"bitaksi-go-driver/internal/models"
+	"bitaksi-go-driver/internal/repository"
+	"bitaksi-go-driver/internal/service"
+	"bitaksi-go-driver/internal/storage"
+)
+
+// recordSpans installs a tracer provider that records the ended spans until the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	previous := otel.GetTracerProvider()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSearchTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	cfg := &config.Config{}
+	cfg.Server.APIKey = "test-api-key"
+	cfg.Tracing.Exporter = ExporterNone
+	if _, err := Setup(context.Background(), cfg); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	store := repository.NewMemoryDriverRepository()
+	if err := store.SaveDrivers(context.Background(), []models.DriverWithDistance{
+		{Location: models.Location{Type: "Point", Coordinates: []float64{29, 41}}},
+	}); err != nil {
+		t.Fatalf("SaveDrivers failed: %v", err)
+	}
+	driverService := service.NewDriverService(NewRepository(store))
+	router := api.SetupRouter(&driverService, cfg, api.WithTracing("driver-service"))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	for _, query := range []string{"latitude=41&longitude=29&radius=1000", "latitude=0&longitude=0&radius=1000"} {
+		req := httptest.NewRequest(http.MethodGet, "/driver/api/v1/search?"+query, nil)
+		req.Header.Set("Authorization", "test-api-key")
+		req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 6 {
+		t.Fatalf("expected 3 spans per search, got %d", len(spans))
+	}
+
+	// Spans end innermost first: repository, service, then the server span of the route
+	for i, name := range []string{"DriverRepository.FindNearestDriver", "DriverService.FindNearestDriver", "/driver/api/v1/search"} {
+		span := spans[i]
+		if span.Name() != name {
+			t.Fatalf("span %d: expected %s, got %s", i, name, span.Name())
+		}
+		if span.SpanContext().TraceID().String() != traceID {
+			t.Errorf("%s: expected the trace of the traceparent header, got %s", name, span.SpanContext().TraceID())
+		}
+		if i > 0 && spans[i-1].Parent().SpanID() != span.SpanContext().SpanID() {
+			t.Errorf("expected %s to be the parent of %s", name, spans[i-1].Name())
+		}
+	}
+
+	// A search finding no driver is an expected outcome, not a failed span
+	for _, span := range spans[3:5] {
+		if span.Status().Code == codes.Error {
+			t.Errorf("%s: expected a search finding no driver not to fail the span", span.Name())
+		}
+	}
+}
+
+// failingStore fails to list drivers
+type failingStore struct {
+	storage.DriverStore
+}
+
+func (failingStore) ListDrivers(ctx context.Context) ([]models.DriverWithDistance, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func TestRepository_RecordsErrors(t *testing.T) {
+	recorder := recordSpans(t)
+
+	if _, err := NewRepository(failingStore{}).ListDrivers(context.Background()); err == nil {
+		t.Fatalf("expected ListDrivers to fail")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error || len(spans[0].Events()) == 0 {
+		t.Errorf("expected one failed span with the error recorded, got %+v", spans)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	cfg := &config.Config{}
+	cfg.Tracing.Exporter = ExporterStdout
+	cfg.Tracing.SampleRatio = 1
+	cfg.Tracing.ServiceName = "driver-service"
+
+	shutdown, err := Setup(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); !ok {
+		t.Errorf("expected an SDK tracer provider, got %T", otel.GetTracerProvider())
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown failed: %v", err)
+	}
+
+	cfg.Tracing.Exporter = "zipkin"
+	if _, err := Setup(context.Background(), cfg); err == nil {
+		t.Errorf("expected an unknown exporter to fail")
+	}
+}